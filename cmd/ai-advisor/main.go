@@ -24,11 +24,11 @@ func main() {
 		log.Fatalf("❌ Failed to initialize trading client: %v", err)
 	}
 
-	// Cleanup orphaned orders before analysis
-	fmt.Println("🧹 Cleaning up orphaned orders before AI analysis...")
+	// Reconcile positions with their stop loss and take profit orders before analysis
+	fmt.Println("🧹 Reconciling positions and orders before AI analysis...")
 	ctx := context.Background()
-	if err := client.CleanupOrphaneOrders(ctx); err != nil {
-		log.Printf("⚠️  Warning: Failed to cleanup orphaned orders: %v", err)
+	if _, err := trading.NewReconciler(client, trading.ReconcileConfigFromEnv()).Reconcile(ctx); err != nil {
+		log.Printf("⚠️  Warning: Failed to reconcile orders: %v", err)
 	}
 	fmt.Println()
 
@@ -63,7 +63,7 @@ func main() {
 	for i, symbol := range allPairs {
 		fmt.Printf("📊 [%d/%d] Scanning %s...", i+1, len(allPairs), symbol.Symbol)
 
-		signals, err := analyzer.AnalyzeSymbol(client.BinanceClient, symbol.Symbol)
+		signals, err := analyzer.AnalyzeSymbol(client, symbol.Symbol)
 		if err != nil {
 			fmt.Printf(" ❌ Error: %v\n", err)
 			continue
@@ -102,8 +102,6 @@ func main() {
 		} else {
 			fmt.Printf(" ⚪ No signals\n")
 		}
-
-		time.Sleep(100 * time.Millisecond) // Rate limiting
 	}
 
 	fmt.Printf("\n🎯 Found %d coins with BREAKOUT + RETEST signals\n", len(breakoutCoins))
@@ -143,7 +141,7 @@ type BreakoutInfo struct {
 
 func generateTradingAdvice(coin BreakoutInfo, client *trading.TradingClient, analyzer *analysis.TechnicalAnalyzer) string {
 	// Get recent price data for Fibonacci analysis
-	klines, err := analyzer.GetKlineData(client.BinanceClient, coin.Symbol, "1h", 100)
+	klines, err := analyzer.GetKlineData(client, coin.Symbol, "1h", 100)
 	if err != nil {
 		return fmt.Sprintf("❌ Error getting market data for %s", coin.Symbol)
 	}
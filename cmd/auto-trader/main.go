@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -50,12 +51,25 @@ type AIAnalysisResult struct {
 // CandleData represents candlestick data for AI analysis
 type CandleData = analysis.CandleData
 
+// strategyName tags the client order IDs of this bot's orders
+const strategyName = "autotrader"
+
 // AutoTrader represents the main trading bot
 type AutoTrader struct {
-	client     *trading.TradingClient
-	config     *config.AppConfig
-	minBalance float64  // Minimum USDT balance required for trading
-	symbols    []string // Symbols to trade
+	client      trading.Exchange
+	account     *trading.UserDataStream    // Live account state (nil when paper trading)
+	positions   *trading.PositionManager   // Moves stops to break-even and trails them
+	scaledExit  trading.ScaledExitConfig   // Multi-target take profits, when enabled
+	entries     *trading.LimitEntryManager // Limit entries at the retest level (nil for market entries)
+	sizer       *trading.PositionSizer     // Sizes positions from the stop distance and equity
+	leverage    *trading.LeveragePolicy    // Selects leverage from volatility and the stop distance
+	exposure    *trading.ExposureGate      // Limits open positions and notional across the portfolio
+	breaker     *trading.CircuitBreaker    // Halts new entries after the daily loss or drawdown limit
+	liquidation *trading.LiquidationGuard  // Keeps stops inside the liquidation price
+	journal     *trading.Journal           // Order and trade journal (nil when JOURNAL_DIR is unset)
+	config      *config.AppConfig
+	minBalance  float64  // Minimum USDT balance required for trading
+	symbols     []string // Symbols to trade
 }
 
 // NewAutoTrader creates a new auto trader instance
@@ -78,6 +92,92 @@ func NewAutoTrader() (*AutoTrader, error) {
 		return nil, fmt.Errorf("failed to create trading client: %w", err)
 	}
 
+	// Simulate fills on a paper exchange instead of sending real orders
+	var exchange trading.Exchange = client
+	var account *trading.UserDataStream
+	if trading.PaperTradingEnabled() {
+		paperConfig := trading.PaperConfigFromEnv()
+		exchange = trading.NewPaperExchange(client, paperConfig)
+		log.Printf("📝 Paper trading enabled (balance: %.2f USDT)", paperConfig.InitialBalance)
+	} else {
+		// Follow fills and positions in real time
+		account = trading.NewUserDataStream(client)
+		if err := account.Start(context.Background()); err != nil {
+			return nil, fmt.Errorf("failed to start user data stream: %w", err)
+		}
+	}
+
+	// Serve klines from the local candle store when configured
+	if dir := trading.KlineStoreDir(); dir != "" {
+		store, err := trading.NewKlineStore(dir, client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create kline store: %w", err)
+		}
+		exchange = trading.WithKlineSource(exchange, store)
+		log.Printf("💾 Kline store enabled (%s)", dir)
+	}
+
+	// Reject orders that would fail the symbol filters, margin or bracket checks before sending them
+	exchange = trading.WithOrderValidation(exchange)
+
+	// Record every order, fill and trade outcome when JOURNAL_DIR is set
+	journal, err := trading.JournalFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	if journal != nil {
+		exchange = trading.WithJournal(exchange, journal)
+		if account != nil {
+			journal.Attach(account)
+		}
+		log.Printf("📕 Journal enabled (%s)", os.Getenv("JOURNAL_DIR"))
+	}
+
+	// Load symbol filters for order rounding and keep them refreshed
+	if err := exchange.Symbols().Start(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load symbol filters: %w", err)
+	}
+
+	// Report orders a previous run left open; their entries are not resent
+	if tagged, err := trading.TaggedOpenOrders(context.Background(), exchange, strategyName); err != nil {
+		log.Printf("Warning: Could not look up open orders: %v", err)
+	} else if len(tagged) > 0 {
+		log.Printf("♻️  Found %d open orders from a previous run", len(tagged))
+		for tag, order := range tagged {
+			log.Printf("   %s %s (signal %s): %s order %d", tag.Symbol, tag.Leg, tag.SignalID, order.Type, order.OrderID)
+		}
+	}
+
+	// Cancel the sibling SL/TP once one closes the position and resize the
+	// stop after a partial take profit; paper trading has no stream to follow
+	oco := trading.NewOCOSupervisor(exchange)
+	if account != nil {
+		oco.Attach(account)
+	} else {
+		go oco.Run(context.Background())
+	}
+
+	// Move stops to break-even and trail them as configured by BREAKEVEN_R and TRAIL_MODE
+	positions := trading.NewPositionManager(exchange, trading.PositionManagerConfigFromEnv())
+	go positions.Run(context.Background())
+
+	// Rest entries at the retest level when LIMIT_ENTRY is set, attaching the
+	// bracket once they fill
+	var entries *trading.LimitEntryManager
+	if entryConfig := trading.LimitEntryConfigFromEnv(); entryConfig.Enabled {
+		entries = trading.NewLimitEntryManager(exchange, entryConfig)
+		if account != nil {
+			entries.Attach(account)
+		}
+		go entries.Run(context.Background())
+	}
+
+	// Halt new entries once the daily loss or drawdown limit is crossed
+	breaker, err := trading.NewCircuitBreaker(exchange, trading.CircuitBreakerConfigFromEnv())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create circuit breaker: %w", err)
+	}
+
 	// Default configuration
 	minBalance := 50.0 // Minimum $50 USDT to trade
 	if minBalanceStr := os.Getenv("MIN_BALANCE"); minBalanceStr != "" {
@@ -92,10 +192,20 @@ func NewAutoTrader() (*AutoTrader, error) {
 	}
 
 	return &AutoTrader{
-		client:     client,
-		config:     cfg,
-		minBalance: minBalance,
-		symbols:    symbols,
+		client:      exchange,
+		account:     account,
+		positions:   positions,
+		scaledExit:  trading.ScaledExitConfigFromEnv(),
+		entries:     entries,
+		sizer:       trading.NewPositionSizer(exchange, trading.SizingConfigFromEnv()),
+		leverage:    trading.NewLeveragePolicy(exchange, trading.LeverageConfigFromEnv()),
+		exposure:    trading.NewExposureGate(exchange, trading.ExposureConfigFromEnv()).WithLimitEntries(entries),
+		breaker:     breaker,
+		liquidation: trading.NewLiquidationGuard(exchange, trading.LiquidationConfigFromEnv()),
+		journal:     journal,
+		config:      cfg,
+		minBalance:  minBalance,
+		symbols:     symbols,
 	}, nil
 }
 
@@ -122,22 +232,8 @@ func (at *AutoTrader) checkBalance() (bool, float64, error) {
 	return hasEnough, usdtBalance, nil
 }
 
-// setupLeverageAndMargin ensures proper leverage and margin settings
-func (at *AutoTrader) setupLeverageAndMargin(symbol string) error {
-	// Check current leverage
-	currentLeverage, err := at.client.GetLeverage(symbol)
-	if err != nil {
-		return fmt.Errorf("failed to get leverage for %s: %w", symbol, err)
-	}
-
-	// Set leverage to 10x if not already set
-	if currentLeverage != 10 {
-		if err := at.client.ChangeLeverage(symbol, 10); err != nil {
-			return fmt.Errorf("failed to set leverage to 10x for %s: %w", symbol, err)
-		}
-		log.Printf("✅ Set leverage to 10x for %s", symbol)
-	}
-
+// setupMarginMode ensures isolated margin; leverage is set per entry by the leverage policy
+func (at *AutoTrader) setupMarginMode(symbol string) error {
 	// Check current margin mode
 	currentMode, err := at.client.GetMarginMode(symbol)
 	if err != nil {
@@ -297,30 +393,11 @@ Notes:
 	return &result, nil
 }
 
-// calculatePositionSize calculates position size based on balance and risk
-func (at *AutoTrader) calculatePositionSize(balance float64, price float64, riskPercent float64) float64 {
-	// Use fixed $15 margin for position
-	maxUSDT := 15.0
-
-	// Calculate position size considering leverage (10x)
-	leveragedAmount := maxUSDT * 10
-
-	// Apply risk management (max 5% risk per trade)
-	if riskPercent > 5.0 {
-		riskPercent = 5.0
-	}
-
-	riskAmount := balance * (riskPercent / 100)
-	positionValue := math.Min(leveragedAmount, riskAmount*10) // 10x leverage
-
-	quantity := positionValue / price
-
-	// Round to reasonable precision
-	return math.Floor(quantity*1000) / 1000
-}
+// openPosition opens a trading position with stop loss and take profit for
+// the analysis of candles
+func (at *AutoTrader) openPosition(symbol string, analysis *AIAnalysisResult, candles []CandleData) error {
+	ctx := context.Background()
 
-// openPosition opens a trading position with stop loss and take profit
-func (at *AutoTrader) openPosition(symbol string, analysis *AIAnalysisResult, balance float64) error {
 	// Get current price
 	ticker, err := at.client.GetTicker(symbol)
 	if err != nil {
@@ -332,11 +409,14 @@ func (at *AutoTrader) openPosition(symbol string, analysis *AIAnalysisResult, ba
 		return fmt.Errorf("failed to parse price: %w", err)
 	}
 
-	// Calculate position size
-	quantity := at.calculatePositionSize(balance, currentPrice, 3.0) // 3% risk per trade
-
-	if quantity <= 0 {
-		return fmt.Errorf("calculated position size too small")
+	// Rest a limit order at the retest level instead of entering at market
+	entryPrice, entryType := currentPrice, "MARKET"
+	if at.entries != nil {
+		if level, ok := retestLevel(symbol, candles, analysis.Action, currentPrice); ok {
+			entryPrice, entryType = level, "LIMIT"
+		} else {
+			log.Printf("⚠️  No retest level for %s, entering at market", symbol)
+		}
 	}
 
 	// Determine side
@@ -348,81 +428,221 @@ func (at *AutoTrader) openPosition(symbol string, analysis *AIAnalysisResult, ba
 	// Calculate stop loss and take profit prices
 	var stopPrice, takeProfitPrice float64
 	if analysis.Action == "LONG" {
-		stopPrice = currentPrice * (1 - analysis.StopLoss/100)
-		takeProfitPrice = currentPrice * (1 + analysis.TakeProfit/100)
+		stopPrice = entryPrice * (1 - analysis.StopLoss/100)
+		takeProfitPrice = entryPrice * (1 + analysis.TakeProfit/100)
 	} else {
-		stopPrice = currentPrice * (1 + analysis.StopLoss/100)
-		takeProfitPrice = currentPrice * (1 - analysis.TakeProfit/100)
+		stopPrice = entryPrice * (1 + analysis.StopLoss/100)
+		takeProfitPrice = entryPrice * (1 - analysis.TakeProfit/100)
 	}
 
-	log.Printf("🔥 Opening %s position for %s", analysis.Action, symbol)
-	log.Printf("   Price: $%.4f", currentPrice)
-	log.Printf("   Quantity: %.3f", quantity)
-	log.Printf("   Stop Loss: $%.4f (%.2f%%)", stopPrice, analysis.StopLoss)
-	log.Printf("   Take Profit: $%.4f (%.2f%%)", takeProfitPrice, analysis.TakeProfit)
-	log.Printf("   Confidence: %.1f%%", analysis.Confidence)
-	log.Printf("   Risk Level: %s", analysis.RiskLevel)
+	// Choose the leverage from volatility and the stop, within the bracket for the intended notional
+	sizeRequest := trading.SizeRequest{
+		Symbol:    symbol,
+		OrderType: entryType,
+		Entry:     entryPrice,
+		Stop:      stopPrice,
+	}
+	notional, err := at.sizer.RiskNotional(ctx, sizeRequest)
+	if err != nil {
+		return fmt.Errorf("failed to size position: %w", err)
+	}
+	leverage, err := at.leverage.Select(ctx, trading.LeverageRequest{Symbol: symbol, Entry: entryPrice, Stop: stopPrice, Notional: notional})
+	if err != nil {
+		return fmt.Errorf("failed to select leverage for %s: %w", symbol, err)
+	}
+
+	// Size the position so that the stop loss costs the configured share of equity
+	sizeRequest.Leverage = leverage.Leverage
+	size, err := at.sizer.Size(ctx, sizeRequest)
+	if err != nil {
+		return fmt.Errorf("failed to size position: %w", err)
+	}
 
-	// Open main position
-	order, err := at.client.CreateOrder(&trading.OrderRequest{
+	// Keep the stop well inside the liquidation price, lowering the leverage if needed
+	liquidation, err := at.liquidation.Check(ctx, trading.LiquidationRequest{
 		Symbol:   symbol,
-		Side:     side,
-		Type:     "MARKET",
-		Quantity: fmt.Sprintf("%.3f", quantity),
+		Side:     analysis.Action,
+		Entry:    entryPrice,
+		Stop:     stopPrice,
+		Quantity: size.Quantity,
+		Leverage: leverage.Leverage,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create market order: %w", err)
+		return err
+	}
+	if liquidation.Leverage != leverage.Leverage {
+		// Less leverage fits a smaller position in the available margin
+		sizeRequest.Leverage = liquidation.Leverage
+		if size, err = at.sizer.Size(ctx, sizeRequest); err != nil {
+			return fmt.Errorf("failed to size position: %w", err)
+		}
+	}
+	quantity := size.Quantity
+
+	// Check the portfolio limits against the open positions and resting entries
+	if err := at.exposure.Check(ctx, symbol, analysis.Action, size.Notional); err != nil {
+		return err
+	}
+
+	// Only changes the exchange leverage when it differs from the current one
+	if err := at.leverage.Apply(symbol, liquidation.Leverage); err != nil {
+		return err
+	}
+
+	// Round to the symbol's step and tick sizes
+	filters, err := at.client.Symbols().Get(ctx, symbol)
+	if err != nil {
+		return fmt.Errorf("failed to get symbol filters for %s: %w", symbol, err)
 	}
+	quantityStr := filters.FormatQuantity(quantity, entryType)
 
-	log.Printf("✅ Market order executed: %s", order.OrderID)
+	log.Printf("🔥 Opening %s position for %s", analysis.Action, symbol)
+	log.Printf("   Price: $%.4f", currentPrice)
+	if entryType == "LIMIT" {
+		log.Printf("   Limit Entry: $%.4f (retest level)", entryPrice)
+	}
+	log.Printf("   Quantity: %s (risking %.2f USDT, limited by %s)", quantityStr, size.Risk, size.LimitedBy)
+	log.Printf("   Stop Loss: $%.4f (%.2f%%), liquidation $%.4f at %dx", stopPrice, analysis.StopLoss, liquidation.LiquidationPrice, liquidation.Leverage)
+	log.Printf("   Take Profit: $%.4f (%.2f%%)", takeProfitPrice, analysis.TakeProfit)
+	log.Printf("   Confidence: %.1f%%", analysis.Confidence)
+	log.Printf("   Risk Level: %s", analysis.RiskLevel)
 
-	// Set stop loss order
 	stopSide := "SELL"
 	if analysis.Action == "SHORT" {
 		stopSide = "BUY"
 	}
 
-	stopOrder, err := at.client.CreateOrder(&trading.OrderRequest{
-		Symbol:        symbol,
-		Side:          stopSide,
-		Type:          "STOP_MARKET",
-		Quantity:      fmt.Sprintf("%.3f", quantity),
-		StopPrice:     fmt.Sprintf("%.4f", stopPrice),
-		ClosePosition: true,
-	})
-	if err != nil {
-		log.Printf("⚠️  Failed to set stop loss: %v", err)
-	} else {
-		log.Printf("✅ Stop loss order set: %s", stopOrder.OrderID)
+	// Tag each leg so a restart recognises orders already placed for this
+	// signal, identified by the candle it was analyzed on
+	tag := trading.ClientOrderTag{
+		Strategy: strategyName,
+		Symbol:   symbol,
+		SignalID: trading.SignalID(time.UnixMilli(candles[len(candles)-1].Timestamp)),
+	}
+	clientOrderID := func(leg trading.OrderLeg) string {
+		tag.Leg = leg
+		return tag.ClientOrderID()
+	}
+	at.journal.RecordSignal(tag, analysis, analysis.Reasoning)
+
+	// protection builds the stop loss and take profits for the filled quantity
+	protection := func(filled float64) (*trading.OrderRequest, []*trading.OrderRequest, error) {
+		stopLoss := &trading.OrderRequest{
+			Symbol:        symbol,
+			Side:          stopSide,
+			Type:          "STOP_MARKET",
+			StopPrice:     filters.FormatPrice(stopPrice),
+			ClosePosition: true,
+			ClientOrderID: clientOrderID(trading.LegStopLoss),
+		}
+		takeProfits := []*trading.OrderRequest{{
+			Symbol:        symbol,
+			Side:          stopSide,
+			Type:          "TAKE_PROFIT_MARKET",
+			StopPrice:     filters.FormatPrice(takeProfitPrice),
+			ClosePosition: true,
+			ClientOrderID: clientOrderID(trading.LegTakeProfit),
+		}}
+
+		// Scale out at Fibonacci extensions of the recent swing instead
+		if at.scaledExit.Enabled {
+			high, low := swingRange(candles, at.scaledExit.Lookback)
+			targets := at.scaledExit.FibonacciTargets(high, low, analysis.Action)
+			scaled, err := trading.ScaledTakeProfits(ctx, at.client, symbol, side, filled, entryPrice, targets, tag)
+			if err != nil {
+				log.Printf("⚠️  Using a single take profit for %s: %v", symbol, err)
+				return stopLoss, takeProfits, nil
+			}
+			for i, order := range scaled {
+				log.Printf("   Take Profit %d: $%s for %s", i+1, order.Price, order.Quantity)
+			}
+
+			// A quantity stop is resized to what is left after each target fills
+			stopLoss.ClosePosition = false
+			stopLoss.Quantity = filters.FormatQuantity(filled, "MARKET")
+			stopLoss.ReduceOnly = true
+			return stopLoss, scaled, nil
+		}
+		return stopLoss, takeProfits, nil
+	}
+
+	// The limit entry gets its stop loss and take profits once it fills
+	if entryType == "LIMIT" {
+		err := at.entries.Place(ctx, trading.PendingEntry{
+			Entry: &trading.OrderRequest{
+				Symbol:        symbol,
+				Side:          side,
+				Type:          "LIMIT",
+				Quantity:      quantityStr,
+				Price:         filters.FormatPrice(entryPrice),
+				ClientOrderID: clientOrderID(trading.LegEntry),
+			},
+			Invalidation: stopPrice,
+			Protection:   protection,
+			OnFilled: func(bracket *trading.Bracket) {
+				at.journal.RecordBracket(tag, bracket)
+				at.positions.ManageBracket(bracket, stopPrice)
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to place limit entry: %w", err)
+		}
+		log.Printf("💡 Reasoning: %s", analysis.Reasoning)
+		return nil
 	}
 
-	// Set take profit order
-	tpOrder, err := at.client.CreateOrder(&trading.OrderRequest{
-		Symbol:        symbol,
-		Side:          stopSide,
-		Type:          "TAKE_PROFIT_MARKET",
-		Quantity:      fmt.Sprintf("%.3f", quantity),
-		StopPrice:     fmt.Sprintf("%.4f", takeProfitPrice),
-		ClosePosition: true,
-	})
+	stopLoss, takeProfits, err := protection(quantity)
 	if err != nil {
-		log.Printf("⚠️  Failed to set take profit: %v", err)
-	} else {
-		log.Printf("✅ Take profit order set: %s", tpOrder.OrderID)
+		return err
+	}
+
+	// Open the position with its stop loss and take profits; it is flattened
+	// again if any protective order cannot be placed
+	bracket, err := trading.PlaceBracket(ctx, at.client,
+		&trading.OrderRequest{
+			Symbol:        symbol,
+			Side:          side,
+			Type:          "MARKET",
+			Quantity:      quantityStr,
+			ClientOrderID: clientOrderID(trading.LegEntry),
+		},
+		stopLoss, takeProfits...)
+	if err != nil {
+		return fmt.Errorf("failed to open bracket: %w", err)
 	}
 
+	log.Printf("✅ Market order executed: %d", bracket.EntryOrderID)
+	log.Printf("✅ Stop loss order set: %d", bracket.StopOrderID)
+	log.Printf("✅ Take profit orders set: %v", bracket.TakeProfitOrderIDs)
+	at.journal.RecordBracket(tag, bracket)
+	at.positions.ManageBracket(bracket, stopPrice)
+
 	log.Printf("💡 Reasoning: %s", analysis.Reasoning)
 
 	return nil
 }
 
+// swingRange returns the highest high and lowest low of the last lookback candles
+func swingRange(candles []CandleData, lookback int) (high, low float64) {
+	if lookback > len(candles) {
+		lookback = len(candles)
+	}
+	recent := candles[len(candles)-lookback:]
+	high, low = recent[0].High, recent[0].Low
+	for _, candle := range recent {
+		high = math.Max(high, candle.High)
+		low = math.Min(low, candle.Low)
+	}
+	return high, low
+}
+
 // processSymbol processes a single trading symbol
-func (at *AutoTrader) processSymbol(symbol string, balance float64) error {
+func (at *AutoTrader) processSymbol(symbol string) error {
 	log.Printf("📊 Analyzing %s...", symbol)
 
-	// Setup leverage and margin
-	if err := at.setupLeverageAndMargin(symbol); err != nil {
-		return fmt.Errorf("failed to setup leverage/margin for %s: %w", symbol, err)
+	// Setup margin mode
+	if err := at.setupMarginMode(symbol); err != nil {
+		return fmt.Errorf("failed to setup margin mode for %s: %w", symbol, err)
 	}
 
 	// Get market data
@@ -449,35 +669,17 @@ func (at *AutoTrader) processSymbol(symbol string, balance float64) error {
 	}
 
 	// Open position
-	if err := at.openPosition(symbol, analysis, balance); err != nil {
+	if err := at.openPosition(symbol, analysis, candles); err != nil {
 		return fmt.Errorf("failed to open position for %s: %w", symbol, err)
 	}
 
 	return nil
 }
 
-// waitUntilNextHour waits until the next hour (minute 1)
-func (at *AutoTrader) waitUntilNextHour() {
-	now := time.Now()
-	nextHour := now.Truncate(time.Hour).Add(time.Hour).Add(1 * time.Minute)
-	duration := nextHour.Sub(now)
-
-	log.Printf("⏰ Waiting until next hour: %s (%.0f minutes)",
-		nextHour.Format("15:04"), duration.Minutes())
-
-	time.Sleep(duration)
-}
-
-// scanForRetestSymbols scans for symbols with successful retest patterns
-func (at *AutoTrader) scanForRetestSymbols() ([]string, error) {
+// scanForRetestSymbols scans symbols for successful retest patterns
+func (at *AutoTrader) scanForRetestSymbols(symbols []string) ([]string, error) {
 	log.Printf("🔍 Scanning for symbols with successful retest patterns...")
 
-	// Get all USDT symbols
-	symbols, err := at.client.GetUSDTSymbols()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get USDT symbols: %w", err)
-	}
-
 	var retestSymbols []string
 	var totalScanned int
 	var successfulRetests int
@@ -515,9 +717,6 @@ func (at *AutoTrader) scanForRetestSymbols() ([]string, error) {
 				log.Printf("✅ Found %d successful retests so far...", successfulRetests)
 			}
 		}
-
-		// Small delay to avoid overwhelming the API
-		time.Sleep(100 * time.Millisecond)
 	}
 
 	log.Printf("📊 Scan completed:")
@@ -560,24 +759,8 @@ func (at *AutoTrader) hasSuccessfulRetest(symbol string, candles []CandleData) b
 	// Use technical analyzer to detect patterns
 	analyzer := analysis.NewTechnicalAnalyzer()
 
-	// Convert to Kline format for technical analysis
-	klines := make([]*analysis.Kline, len(candles))
-	for i, candle := range candles {
-		klines[i] = &analysis.Kline{
-			OpenTime:  candle.Timestamp,
-			Open:      candle.Open,
-			High:      candle.High,
-			Low:       candle.Low,
-			Close:     candle.Close,
-			Volume:    candle.Volume,
-			CloseTime: candle.Timestamp + 3600000,
-			IsGreen:   candle.Close > candle.Open,
-			IsRed:     candle.Close < candle.Open,
-		}
-	}
-
 	// Get breakout signals
-	signals := analyzer.DetectBreakouts(klines, symbol)
+	signals := analyzer.DetectBreakouts(toKlines(candles), symbol)
 
 	// Check for successful retest patterns
 	for _, signal := range signals {
@@ -595,60 +778,133 @@ func (at *AutoTrader) hasSuccessfulRetest(symbol string, candles []CandleData) b
 	return false
 }
 
+// retestLevel returns the channel level of the latest breakout or successful
+// retest in the direction of action, if it is on the entry side of price
+// (below for LONG, above for SHORT) so a limit there rests until retested
+func retestLevel(symbol string, candles []CandleData, action string, price float64) (float64, bool) {
+	signals := analysis.NewTechnicalAnalyzer().DetectBreakouts(toKlines(candles), symbol)
+	for i := len(signals) - 1; i >= 0; i-- {
+		signal := signals[i]
+		long := signal.Type == "UP_BREAKOUT" || (signal.Type == "RETEST_SUCCESS" && signal.Price > signal.ChannelLevel)
+		short := signal.Type == "DOWN_BREAKOUT" || (signal.Type == "RETEST_SUCCESS" && signal.Price < signal.ChannelLevel)
+		if action == "LONG" && long && signal.ChannelLevel < price {
+			return signal.ChannelLevel, true
+		}
+		if action == "SHORT" && short && signal.ChannelLevel > price {
+			return signal.ChannelLevel, true
+		}
+	}
+	return 0, false
+}
+
+// toKlines converts hourly candles to the Kline format of the technical analyzer
+func toKlines(candles []CandleData) []*analysis.Kline {
+	klines := make([]*analysis.Kline, len(candles))
+	for i, candle := range candles {
+		klines[i] = &analysis.Kline{
+			OpenTime:  candle.Timestamp,
+			Open:      candle.Open,
+			High:      candle.High,
+			Low:       candle.Low,
+			Close:     candle.Close,
+			Volume:    candle.Volume,
+			CloseTime: candle.Timestamp + 3600000,
+			IsGreen:   candle.Close > candle.Open,
+			IsRed:     candle.Close < candle.Open,
+		}
+	}
+	return klines
+}
+
 // run starts the main trading loop
 func (at *AutoTrader) run() {
 	log.Printf("🚀 Auto Trader Bot Started!")
 	log.Printf("� Will scan ALL USDT pairs for successful retest patterns")
 	log.Printf("💰 Minimum balance: $%.2f USDT", at.minBalance)
-	log.Printf("⚙️  Leverage: 10x, Margin: ISOLATED")
+	leverage := at.leverage.Config()
+	log.Printf("⚙️  Leverage: %d-%dx by volatility, Margin: ISOLATED", leverage.MinLeverage, leverage.MaxLeverage)
+
+	ctx := context.Background()
+
+	// Get all USDT symbols
+	symbols, err := at.client.GetUSDTSymbols()
+	if err != nil {
+		log.Fatalf("❌ Failed to get USDT symbols: %v", err)
+	}
+
+	// Keep hourly candles current over websocket instead of polling
+	stream, err := trading.NewKlineStream(at.client, symbols, "1h", 200)
+	if err != nil {
+		log.Fatalf("❌ Failed to create kline stream: %v", err)
+	}
+	if err := stream.Start(ctx); err != nil {
+		log.Fatalf("❌ Failed to start kline stream: %v", err)
+	}
+	at.client = trading.WithKlineSource(at.client, stream)
 
+	// Scan everything once, then each symbol as soon as its candle closes
+	closed := symbols
 	for {
-		startTime := time.Now()
-		log.Println("\n" + strings.Repeat("=", 60))
-		log.Printf("🔄 Starting trading cycle at %s", startTime.Format("2006-01-02 15:04:05"))
-		log.Println(strings.Repeat("=", 60))
+		at.runCycle(closed)
 
-		// Check balance first
-		hasEnoughBalance, balance, err := at.checkBalance()
+		closed, err = stream.NextBatch(ctx)
 		if err != nil {
-			log.Printf("❌ Failed to check balance: %v", err)
-		} else if !hasEnoughBalance {
-			log.Printf("💸 Insufficient balance: $%.2f USDT (minimum: $%.2f)", balance, at.minBalance)
-			log.Printf("⏭️  Skipping this cycle...")
-		} else {
-			log.Printf("💰 Available balance: $%.2f USDT", balance)
+			log.Fatalf("❌ Kline stream stopped: %v", err)
+		}
+	}
+}
 
-			// Scan for symbols with successful retest patterns
-			retestSymbols, err := at.scanForRetestSymbols()
-			if err != nil {
-				log.Printf("❌ Error scanning for retest symbols: %v", err)
-			} else if len(retestSymbols) == 0 {
-				log.Printf("🔍 No symbols found with successful retest patterns")
-			} else {
-				log.Printf("📈 Found %d symbols with successful retests", len(retestSymbols))
+// runCycle checks balance, scans symbols for retests and trades the AI-confirmed ones
+func (at *AutoTrader) runCycle(symbols []string) {
+	startTime := time.Now()
+	log.Println("\n" + strings.Repeat("=", 60))
+	log.Printf("🔄 Starting trading cycle at %s (%d symbols)", startTime.Format("2006-01-02 15:04:05"), len(symbols))
+	log.Println(strings.Repeat("=", 60))
 
-				// Analyze ALL symbols with successful retest (no limit)
-				log.Printf("🤖 Proceeding with AI analysis for ALL %d quality coins...", len(retestSymbols))
+	// Open nothing while the circuit breaker is tripped
+	if err := at.breaker.Check(context.Background()); err != nil {
+		log.Printf("🛑 %v", err)
+		log.Printf("⏭️  Skipping this cycle...")
+		return
+	}
 
-				// Process each symbol with retest pattern
-				for i, symbol := range retestSymbols {
-					log.Printf("\n🔍 [%d/%d] Analyzing %s with AI...", i+1, len(retestSymbols), symbol)
+	// Check balance first
+	hasEnoughBalance, balance, err := at.checkBalance()
+	if err != nil {
+		log.Printf("❌ Failed to check balance: %v", err)
+	} else if !hasEnoughBalance {
+		log.Printf("💸 Insufficient balance: $%.2f USDT (minimum: $%.2f)", balance, at.minBalance)
+		log.Printf("⏭️  Skipping this cycle...")
+	} else {
+		log.Printf("💰 Available balance: $%.2f USDT", balance)
+
+		// Scan for symbols with successful retest patterns
+		retestSymbols, err := at.scanForRetestSymbols(symbols)
+		if err != nil {
+			log.Printf("❌ Error scanning for retest symbols: %v", err)
+		} else if len(retestSymbols) == 0 {
+			log.Printf("🔍 No symbols found with successful retest patterns")
+		} else {
+			log.Printf("📈 Found %d symbols with successful retests", len(retestSymbols))
 
-					if err := at.processSymbol(symbol, balance); err != nil {
-						log.Printf("❌ Error processing %s: %v", symbol, err)
-					}
+			// Analyze ALL symbols with successful retest (no limit)
+			log.Printf("🤖 Proceeding with AI analysis for ALL %d quality coins...", len(retestSymbols))
 
-					// Small delay between symbols
-					time.Sleep(2 * time.Second)
+			// Process each symbol with retest pattern
+			for i, symbol := range retestSymbols {
+				log.Printf("\n🔍 [%d/%d] Analyzing %s with AI...", i+1, len(retestSymbols), symbol)
+
+				if err := at.processSymbol(symbol); err != nil {
+					log.Printf("❌ Error processing %s: %v", symbol, err)
 				}
+
+				// Small delay between symbols
+				time.Sleep(2 * time.Second)
 			}
 		}
-
-		log.Printf("\n✅ Trading cycle completed in %.1f seconds", time.Since(startTime).Seconds())
-
-		// Wait until next hour
-		at.waitUntilNextHour()
 	}
+
+	log.Printf("\n✅ Trading cycle completed in %.1f seconds", time.Since(startTime).Seconds())
 }
 
 func main() {
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"tread2/pkg/backtest"
+	"tread2/pkg/trading"
+)
+
+func main() {
+	strategyName := flag.String("strategy", "both", "Strategy to test: breakout, retest or both")
+	symbolList := flag.String("symbols", "BTCUSDT,ETHUSDT,BNBUSDT,SOLUSDT,XRPUSDT", "Comma-separated symbols")
+	interval := flag.String("interval", "1h", "Kline interval")
+	bars := flag.Int("bars", 1000, "Bars to download the first time a symbol is synced")
+	dataDir := flag.String("data", defaultDataDir(), "Kline store directory")
+	offline := flag.Bool("offline", false, "Use stored candles only, without syncing from Binance")
+	from := flag.String("from", "", "First day to test (YYYY-MM-DD, UTC)")
+	to := flag.String("to", "", "Last day to test (YYYY-MM-DD, UTC)")
+	showTrades := flag.Bool("trades", false, "Print every trade")
+	balance := flag.Float64("balance", 1000, "Starting balance per symbol (USDT)")
+	risk := flag.Float64("risk", trading.DefaultSizingConfig().RiskPercent, "Equity risked per trade if the stop is hit (percent)")
+	leverage := flag.Int("leverage", 3, "Leverage")
+	flag.Parse()
+
+	fmt.Println("🧪 Strategy Backtester")
+	fmt.Println("======================")
+
+	var strategies []backtest.Strategy
+	switch *strategyName {
+	case "breakout":
+		strategies = append(strategies, backtest.NewBreakoutStrategy())
+	case "retest":
+		strategies = append(strategies, backtest.NewRetestStrategy())
+	case "both":
+		strategies = append(strategies, backtest.NewBreakoutStrategy(), backtest.NewRetestStrategy())
+	default:
+		log.Fatalf("❌ Unknown strategy: %s", *strategyName)
+	}
+
+	symbols := strings.Split(strings.ToUpper(*symbolList), ",")
+	data, err := loadData(symbols, *interval, *bars, *dataDir, *offline, *from, *to)
+	if err != nil {
+		log.Fatalf("❌ Failed to load candles: %v", err)
+	}
+
+	config := backtest.DefaultConfig()
+	config.InitialBalance = *balance
+	config.Sizing.RiskPercent = *risk
+	config.Leverage = *leverage
+	engine := backtest.NewEngine(config)
+
+	for _, strategy := range strategies {
+		reports, aggregate, err := engine.RunAll(strategy, data)
+		if err != nil {
+			log.Fatalf("❌ Backtest failed: %v", err)
+		}
+
+		for _, report := range reports {
+			backtest.PrintReport(os.Stdout, report, *showTrades)
+		}
+		backtest.PrintReport(os.Stdout, aggregate, false)
+	}
+}
+
+// defaultDataDir returns KLINE_STORE_DIR or the local data directory
+func defaultDataDir() string {
+	if dir := trading.KlineStoreDir(); dir != "" {
+		return dir
+	}
+	return "data/klines"
+}
+
+// loadData syncs the kline store and reads the requested range for each symbol
+func loadData(symbols []string, interval string, bars int, dataDir string, offline bool, from, to string) (map[string][]trading.Candle, error) {
+	start, end, err := parseRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var source trading.KlineSource
+	if !offline {
+		client, err := trading.NewTradingClient()
+		if err != nil {
+			return nil, err
+		}
+		source = client
+	}
+
+	store, err := trading.NewKlineStore(dataDir, source)
+	if err != nil {
+		return nil, err
+	}
+	store.InitialBars = bars
+
+	data := make(map[string][]trading.Candle)
+	for _, symbol := range symbols {
+		added, err := store.Sync(symbol, interval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sync %s: %w", symbol, err)
+		}
+
+		candles, err := store.Range(symbol, interval, start, end)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("📥 %s: %d candles (%d new)\n", symbol, len(candles), added)
+
+		gaps, err := store.Gaps(symbol, interval)
+		if err != nil {
+			return nil, err
+		}
+		for _, gap := range gaps {
+			fmt.Printf("⚠️  %s: %d missing bars from %s\n", symbol, gap.Missing, time.UnixMilli(gap.From).UTC().Format("2006-01-02 15:04"))
+		}
+
+		data[symbol] = candles
+	}
+
+	return data, nil
+}
+
+// parseRange converts -from/-to dates into millisecond open times
+func parseRange(from, to string) (int64, int64, error) {
+	var start, end int64
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid -from date: %w", err)
+		}
+		start = t.UnixMilli()
+	}
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid -to date: %w", err)
+		}
+		end = t.Add(24*time.Hour).UnixMilli() - 1
+	}
+	return start, end, nil
+}
@@ -39,7 +39,7 @@ func main() {
 	fmt.Println()
 
 	// Analyze the symbol
-	signals, err := analyzer.AnalyzeSymbol(client.BinanceClient, symbol)
+	signals, err := analyzer.AnalyzeSymbol(client, symbol)
 	if err != nil {
 		log.Fatalf("❌ Failed to analyze %s: %v", symbol, err)
 	}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"tread2/pkg/trading"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	reset := flag.Bool("reset", false, "Clear the halt so the traders open positions again")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: Could not load .env file: %v", err)
+	}
+
+	fmt.Println("🛡️  Circuit Breaker")
+	fmt.Println("===================")
+
+	config := trading.CircuitBreakerConfigFromEnv()
+	client, err := trading.NewTradingClient()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize client: %v", err)
+	}
+	var exchange trading.Exchange = client
+	if trading.PaperTradingEnabled() {
+		exchange = trading.NewPaperExchange(client, trading.PaperConfigFromEnv())
+	}
+
+	breaker, err := trading.NewCircuitBreaker(exchange, config)
+	if err != nil {
+		log.Fatalf("❌ Failed to load circuit breaker: %v", err)
+	}
+
+	if *reset {
+		if err := breaker.Reset(); err != nil {
+			log.Fatalf("❌ Failed to reset circuit breaker: %v", err)
+		}
+		fmt.Println("✅ Circuit breaker reset, running traders resume on their next check unless a limit is still crossed")
+		return
+	}
+
+	state := breaker.State()
+	fmt.Printf("📄 State file: %s\n", config.StateFile)
+	if state.Halted {
+		fmt.Printf("🚨 HALTED since %s\n", state.HaltedAt.Format(time.RFC3339))
+		fmt.Printf("   Reason: %s\n", state.Reason)
+		if state.Flattened {
+			fmt.Println("   Positions were closed")
+		}
+		fmt.Println("   Run with -reset to resume trading")
+	} else {
+		fmt.Println("✅ Trading allowed")
+	}
+
+	snapshot, err := breaker.Measure(context.Background())
+	if err != nil {
+		log.Fatalf("❌ Failed to measure account: %v", err)
+	}
+	fmt.Printf("\n💰 Equity: %.2f USDT\n", snapshot.Equity)
+	fmt.Printf("📅 Today (UTC): %+.2f USDT from %.2f (limit %.2f%%, %.2f USDT)\n",
+		snapshot.DailyPnL, snapshot.DayStartEquity, config.DailyLossPercent, config.DailyLossLimit)
+	fmt.Printf("📉 Drawdown: %.2f%% from the %.2f USDT peak of the last %s (limit %.2f%%)\n",
+		snapshot.Drawdown, snapshot.PeakEquity, config.Window, config.DrawdownPercent)
+}
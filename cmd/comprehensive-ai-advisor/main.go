@@ -31,6 +31,12 @@ func main() {
 		log.Fatalf("❌ Failed to initialize trading client: %v", err)
 	}
 
+	// Read klines through the local candle store when KLINE_STORE_DIR is set
+	klineSource, err := trading.KlineSourceFromEnv(client)
+	if err != nil {
+		log.Fatalf("❌ Failed to open kline store: %v", err)
+	}
+
 	// Get all USDT pairs from exchange
 	fmt.Println("🔄 Fetching all USDT pairs from Binance...")
 	allPairs, err := client.GetUSDTPairs(context.Background())
@@ -53,7 +59,7 @@ func main() {
 		fmt.Printf("📊 [%d/%d] Scanning %s...", i+1, len(allPairs), symbol.Symbol)
 
 		// Get technical signals
-		signals, err := analyzer.AnalyzeSymbol(client.BinanceClient, symbol.Symbol)
+		signals, err := analyzer.AnalyzeSymbol(klineSource, symbol.Symbol)
 		if err != nil {
 			fmt.Printf(" ❌ Error: %v\n", err)
 			continue
@@ -62,7 +68,7 @@ func main() {
 		// Check if has any signal
 		if len(signals) > 0 {
 			// Get 200 candle data for AI analysis
-			candleData, err := analyzer.GetKlineData(client.BinanceClient, symbol.Symbol, "1h", 200)
+			candleData, err := analyzer.GetKlineData(klineSource, symbol.Symbol, "1h", 200)
 			if err != nil {
 				fmt.Printf(" ❌ Error getting candle data: %v\n", err)
 				continue
@@ -86,9 +92,6 @@ func main() {
 		} else {
 			fmt.Printf(" ⚪ No signals\n")
 		}
-
-		// Rate limiting to avoid API restrictions
-		time.Sleep(100 * time.Millisecond)
 	}
 
 	fmt.Printf("\n🎯 Found %d coins with signals for AI analysis\n", len(coinsWithSignals))
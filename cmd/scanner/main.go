@@ -33,11 +33,17 @@ func main() {
 		log.Fatalf("❌ Failed to initialize trading client: %v", err)
 	}
 
-	// Cleanup orphaned orders before scanning
-	fmt.Println("🧹 Cleaning up orphaned orders before scanning...")
+	// Read klines through the local candle store when KLINE_STORE_DIR is set
+	klineSource, err := trading.KlineSourceFromEnv(client)
+	if err != nil {
+		log.Fatalf("❌ Failed to open kline store: %v", err)
+	}
+
+	// Reconcile positions with their stop loss and take profit orders before scanning
+	fmt.Println("🧹 Reconciling positions and orders before scanning...")
 	ctx := context.Background()
-	if err := client.CleanupOrphaneOrders(ctx); err != nil {
-		log.Printf("⚠️  Warning: Failed to cleanup orphaned orders: %v", err)
+	if _, err := trading.NewReconciler(client, trading.ReconcileConfigFromEnv()).Reconcile(ctx); err != nil {
+		log.Printf("⚠️  Warning: Failed to reconcile orders: %v", err)
 	}
 	fmt.Println()
 
@@ -81,7 +87,7 @@ func main() {
 		// Show progress
 		fmt.Printf("📊 [%d/%d] Scanning %s...", i+1, len(allPairs), symbol.Symbol)
 
-		signals, err := analyzer.AnalyzeSymbol(client.BinanceClient, symbol.Symbol)
+		signals, err := analyzer.AnalyzeSymbol(klineSource, symbol.Symbol)
 
 		if err != nil {
 			errorCount++
@@ -90,8 +96,6 @@ func main() {
 			if errorCount > 10 {
 				fmt.Printf("⚠️  Too many errors (%d), continuing with remaining symbols...\n", errorCount)
 			}
-			// Longer delay after error
-			time.Sleep(200 * time.Millisecond)
 			continue
 		}
 
@@ -105,9 +109,6 @@ func main() {
 			fmt.Printf(" ⚪ No signals\n")
 		}
 
-		// Rate limiting delay
-		time.Sleep(100 * time.Millisecond)
-
 		// Progress checkpoint every 50 symbols
 		if (i+1)%50 == 0 {
 			fmt.Printf("\n🔄 Progress checkpoint: %d/%d symbols scanned\n", i+1, len(allPairs))
@@ -1,14 +1,12 @@
 package analysis
 
 import (
-	"context"
 	"fmt"
 	"math"
 	"sort"
-	"strconv"
 	"time"
 
-	"github.com/adshao/go-binance/v2/futures"
+	"tread2/pkg/trading"
 )
 
 // Kline represents a candlestick data point
@@ -70,38 +68,35 @@ func NewTechnicalAnalyzer() *TechnicalAnalyzer {
 	}
 }
 
-// GetKlineData retrieves historical kline data
-func (ta *TechnicalAnalyzer) GetKlineData(client *futures.Client, symbol string, interval string, limit int) ([]*Kline, error) {
-	klines, err := client.NewKlinesService().
-		Symbol(symbol).
-		Interval(interval).
-		Limit(limit).
-		Do(context.Background())
+// KlineSource provides raw kline rows in the
+// [OpenTime, Open, High, Low, Close, Volume, CloseTime] layout returned by
+// trading.Exchange.GetKlines and trading.KlineStore
+type KlineSource = trading.KlineSource
 
+// GetKlineData retrieves historical kline data
+func (ta *TechnicalAnalyzer) GetKlineData(source KlineSource, symbol string, interval string, limit int) ([]*Kline, error) {
+	rows, err := source.GetKlines(symbol, interval, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get kline data: %w", err)
 	}
 
 	var result []*Kline
-	for _, k := range klines {
-		open, _ := strconv.ParseFloat(k.Open, 64)
-		high, _ := strconv.ParseFloat(k.High, 64)
-		low, _ := strconv.ParseFloat(k.Low, 64)
-		close, _ := strconv.ParseFloat(k.Close, 64)
-		volume, _ := strconv.ParseFloat(k.Volume, 64)
-
-		kline := &Kline{
-			OpenTime:  k.OpenTime,
-			Open:      open,
-			High:      high,
-			Low:       low,
-			Close:     close,
-			Volume:    volume,
-			CloseTime: k.CloseTime,
-			IsGreen:   close > open,
-			IsRed:     close < open,
+	for _, row := range rows {
+		candle, err := trading.ParseCandle(row)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse kline data: %w", err)
 		}
-		result = append(result, kline)
+		result = append(result, &Kline{
+			OpenTime:  candle.OpenTime,
+			Open:      candle.Open,
+			High:      candle.High,
+			Low:       candle.Low,
+			Close:     candle.Close,
+			Volume:    candle.Volume,
+			CloseTime: candle.CloseTime,
+			IsGreen:   candle.Close > candle.Open,
+			IsRed:     candle.Close < candle.Open,
+		})
 	}
 
 	return result, nil
@@ -670,9 +665,9 @@ func (ta *TechnicalAnalyzer) RSIFilter(rsi float64, signalType string) bool {
 }
 
 // AnalyzeSymbol performs complete breakout analysis for a symbol
-func (ta *TechnicalAnalyzer) AnalyzeSymbol(client *futures.Client, symbol string) ([]*BreakoutSignal, error) {
+func (ta *TechnicalAnalyzer) AnalyzeSymbol(source KlineSource, symbol string) ([]*BreakoutSignal, error) {
 	// Get 1h kline data (enough for analysis + history)
-	klines, err := ta.GetKlineData(client, symbol, "1h", ta.Length+50)
+	klines, err := ta.GetKlineData(source, symbol, "1h", ta.Length+50)
 	if err != nil {
 		return nil, err
 	}
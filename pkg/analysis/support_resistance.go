@@ -0,0 +1,131 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+)
+
+// SRBreakout represents a support/resistance breakout on the latest candle
+type SRBreakout struct {
+	SupportLevel    float64 `json:"support_level"`
+	ResistanceLevel float64 `json:"resistance_level"`
+	BreakoutType    string  `json:"breakout_type"` // "SUPPORT_BREAK", "RESISTANCE_BREAK", "NONE"
+	Signal          string  `json:"signal"`        // "LONG", "SHORT", "NONE"
+	StopLoss        float64 `json:"stop_loss"`     // Low for LONG, High for SHORT
+	Confidence      float64 `json:"confidence"`    // 0-100
+	Analysis        string  `json:"analysis"`
+}
+
+// AnalyzeSupportResistanceBreakout checks whether the latest candle broke the
+// support or resistance level calculated from the preceding price history
+func AnalyzeSupportResistanceBreakout(candles []CandleData) (*SRBreakout, error) {
+	if len(candles) < 50 {
+		return nil, fmt.Errorf("insufficient data for breakout analysis")
+	}
+
+	// Get current and previous candles
+	currentCandle := candles[len(candles)-1]
+	previousCandle := candles[len(candles)-2]
+
+	// Calculate support and resistance levels from recent history
+	supportLevel, resistanceLevel := CalculateSupportResistanceLevels(candles)
+
+	result := &SRBreakout{
+		SupportLevel:    supportLevel,
+		ResistanceLevel: resistanceLevel,
+		BreakoutType:    "NONE",
+		Signal:          "NONE",
+	}
+
+	// Check for support breakout (bearish signal)
+	if previousCandle.Close > supportLevel && // Previous candle was above support
+		previousCandle.Close > previousCandle.Open && // Previous candle was green
+		currentCandle.Close < supportLevel { // Current candle broke below support
+
+		result.BreakoutType = "SUPPORT_BREAK"
+		result.Signal = "LONG"              // Wait for LONG signal as per new logic
+		result.StopLoss = currentCandle.Low // Stop loss at current candle's low
+		result.Confidence = 75
+		result.Analysis = fmt.Sprintf("Support breakout detected: Previous green candle at %.4f above support %.4f, current candle broke below to %.4f",
+			previousCandle.Close, supportLevel, currentCandle.Close)
+	}
+
+	// Check for resistance breakout (bullish signal)
+	if previousCandle.Close < resistanceLevel && // Previous candle was below resistance
+		previousCandle.Close < previousCandle.Open && // Previous candle was red
+		currentCandle.Close > resistanceLevel { // Current candle broke above resistance
+
+		result.BreakoutType = "RESISTANCE_BREAK"
+		result.Signal = "SHORT"              // Wait for SHORT signal as per new logic
+		result.StopLoss = currentCandle.High // Stop loss at current candle's high
+		result.Confidence = 75
+		result.Analysis = fmt.Sprintf("Resistance breakout detected: Previous red candle at %.4f below resistance %.4f, current candle broke above to %.4f",
+			previousCandle.Close, resistanceLevel, currentCandle.Close)
+	}
+
+	return result, nil
+}
+
+// CalculateSupportResistanceLevels calculates support and resistance levels from price history
+func CalculateSupportResistanceLevels(candles []CandleData) (float64, float64) {
+	if len(candles) < 20 {
+		return 0, 0
+	}
+
+	// Use last 50 candles for S/R calculation
+	period := 50
+	if len(candles) < period {
+		period = len(candles)
+	}
+
+	recentData := candles[len(candles)-period:]
+
+	// Find pivot highs and lows
+	var pivotHighs, pivotLows []float64
+
+	for i := 2; i < len(recentData)-2; i++ {
+		// Pivot high: higher than 2 candles on each side
+		if recentData[i].High > recentData[i-1].High && recentData[i].High > recentData[i-2].High &&
+			recentData[i].High > recentData[i+1].High && recentData[i].High > recentData[i+2].High {
+			pivotHighs = append(pivotHighs, recentData[i].High)
+		}
+
+		// Pivot low: lower than 2 candles on each side
+		if recentData[i].Low < recentData[i-1].Low && recentData[i].Low < recentData[i-2].Low &&
+			recentData[i].Low < recentData[i+1].Low && recentData[i].Low < recentData[i+2].Low {
+			pivotLows = append(pivotLows, recentData[i].Low)
+		}
+	}
+
+	// Calculate support (recent significant low) and resistance (recent significant high)
+	return clusterLevel(pivotLows), clusterLevel(pivotHighs)
+}
+
+// clusterLevel returns the most recent pivot, averaged with pivots within 1% of it
+func clusterLevel(pivots []float64) float64 {
+	if len(pivots) == 0 {
+		return 0
+	}
+
+	// Use the most recent significant pivot
+	level := pivots[len(pivots)-1]
+
+	// If multiple pivots are close, use the average
+	tolerance := level * 0.01 // 1% tolerance
+	var closePivots []float64
+	for _, pivot := range pivots {
+		if math.Abs(pivot-level) <= tolerance {
+			closePivots = append(closePivots, pivot)
+		}
+	}
+
+	if len(closePivots) > 1 {
+		sum := 0.0
+		for _, pivot := range closePivots {
+			sum += pivot
+		}
+		level = sum / float64(len(closePivots))
+	}
+
+	return level
+}
@@ -0,0 +1,334 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"tread2/pkg/trading"
+)
+
+// Signal is an entry decision produced by a strategy at the close of a bar
+type Signal struct {
+	Side           string  // LONG or SHORT
+	StopLoss       float64 // Stop loss price
+	TakeProfit     float64 // Take profit price
+	TakeProfitType string  // LIMIT (reduce-only) or TAKE_PROFIT_MARKET (close position)
+	Reason         string
+}
+
+// Strategy evaluates the candles seen so far and optionally returns an entry.
+// history always ends with the bar that just closed; strategies never see later bars.
+type Strategy interface {
+	Name() string
+	Evaluate(symbol string, history []trading.Candle) *Signal
+}
+
+// Config controls how a backtest sizes and simulates trades
+type Config struct {
+	InitialBalance float64              // Starting equity per symbol
+	Sizing         trading.SizingConfig // Risk per trade and notional caps, as used by PositionSizer
+	Leverage       int                  // Fixed leverage; live trading selects it per trade with LeveragePolicy
+	Warmup         int                  // Bars required before the first evaluation
+	Window         int                  // Bars of history passed to the strategy
+	Paper          trading.PaperConfig  // Fees and slippage
+}
+
+// DefaultConfig risks 1% of equity per trade at 3x, the default sizing of
+// executeBreakoutTrade and the auto trader
+func DefaultConfig() Config {
+	paper := trading.DefaultPaperConfig()
+	paper.InitialBalance = 1000
+
+	return Config{
+		InitialBalance: paper.InitialBalance,
+		Sizing:         trading.DefaultSizingConfig(),
+		Leverage:       3,
+		Warmup:         50,
+		Window:         200,
+		Paper:          paper,
+	}
+}
+
+// Engine replays candles bar by bar through a paper exchange
+type Engine struct {
+	config Config
+}
+
+// NewEngine creates a backtest engine
+func NewEngine(config Config) *Engine {
+	if config.Window <= 0 {
+		config.Window = 200
+	}
+	if config.Leverage <= 0 {
+		config.Leverage = 1
+	}
+	config.Paper.InitialBalance = config.InitialBalance
+
+	return &Engine{config: config}
+}
+
+// openTrade tracks the trade currently held by a run
+type openTrade struct {
+	trade     Trade
+	fillIndex int // index of the entry fill in the exchange's fill list
+}
+
+// Run backtests strategy on one symbol's candles, oldest first
+func (e *Engine) Run(strategy Strategy, symbol string, candles []trading.Candle) (*Report, error) {
+	if len(candles) <= e.config.Warmup {
+		return nil, fmt.Errorf("insufficient data for %s: %d candles, need more than %d", symbol, len(candles), e.config.Warmup)
+	}
+
+	ctx := context.Background()
+	exchange := trading.NewPaperExchange(nil, e.config.Paper)
+	if err := exchange.ChangeLeverage(symbol, e.config.Leverage); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Symbol:         symbol,
+		Strategy:       strategy.Name(),
+		InitialBalance: e.config.InitialBalance,
+		Bars:           len(candles),
+	}
+
+	var current *openTrade
+	for i, candle := range candles {
+		exchange.OnCandle(symbol, candle)
+
+		// Close out the trade once the position is flat
+		if current != nil && !hasPosition(ctx, exchange, symbol) {
+			report.Trades = append(report.Trades, e.closeTrade(ctx, exchange, current, symbol))
+			current = nil
+		}
+
+		// Evaluate only once enough history exists and no trade is open
+		if current == nil && i+1 >= e.config.Warmup {
+			start := i + 1 - e.config.Window
+			if start < 0 {
+				start = 0
+			}
+
+			if signal := strategy.Evaluate(symbol, candles[start:i+1]); signal != nil {
+				trade, err := e.openTrade(ctx, exchange, symbol, candle, signal)
+				if err != nil {
+					report.Skipped++
+				} else {
+					current = trade
+					if !hasPosition(ctx, exchange, symbol) {
+						// Protective orders were rejected and the entry was flattened
+						report.Trades = append(report.Trades, e.closeTrade(ctx, exchange, current, symbol))
+						current = nil
+					}
+				}
+			}
+		}
+
+		report.EquityCurve = append(report.EquityCurve, EquityPoint{Time: candle.CloseTime, Equity: exchange.Equity()})
+	}
+
+	// Flatten anything still open at the last close
+	if current != nil {
+		side := "SELL"
+		if current.trade.Side == "SHORT" {
+			side = "BUY"
+		}
+		if _, err := exchange.CreateOrder(&trading.OrderRequest{Symbol: symbol, Side: side, Type: "MARKET", ClosePosition: true, ReduceOnly: true}); err != nil {
+			return nil, fmt.Errorf("failed to close final position for %s: %w", symbol, err)
+		}
+		trade := e.closeTrade(ctx, exchange, current, symbol)
+		trade.ExitReason = "END_OF_DATA"
+		report.Trades = append(report.Trades, trade)
+
+		last := len(report.EquityCurve) - 1
+		report.EquityCurve[last].Equity = exchange.Equity()
+	}
+
+	report.calculate()
+	return report, nil
+}
+
+// RunAll backtests strategy on every symbol and returns per-symbol reports and the aggregate
+func (e *Engine) RunAll(strategy Strategy, data map[string][]trading.Candle) ([]*Report, *Report, error) {
+	var reports []*Report
+	for _, symbol := range sortedSymbols(data) {
+		report, err := e.Run(strategy, symbol, data[symbol])
+		if err != nil {
+			return nil, nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, Aggregate(strategy.Name(), e.config.InitialBalance, reports), nil
+}
+
+// openTrade enters at the bar close and places the protective orders
+func (e *Engine) openTrade(ctx context.Context, exchange *trading.PaperExchange, symbol string, candle trading.Candle, signal *Signal) (*openTrade, error) {
+	quantity, err := e.size(ctx, exchange, candle.Close, signal.StopLoss)
+	if err != nil {
+		return nil, err
+	}
+
+	entrySide, exitSide := "BUY", "SELL"
+	if signal.Side == "SHORT" {
+		entrySide, exitSide = "SELL", "BUY"
+	}
+
+	fillIndex := len(exchange.Fills())
+	entry, err := exchange.CreateOrder(&trading.OrderRequest{
+		Symbol:   symbol,
+		Side:     entrySide,
+		Type:     "MARKET",
+		Quantity: formatQuantity(quantity),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	trade := &openTrade{
+		trade: Trade{
+			Symbol:     symbol,
+			Side:       signal.Side,
+			EntryTime:  candle.CloseTime,
+			EntryPrice: entry.AvgPrice,
+			Quantity:   entry.ExecutedQty,
+			StopLoss:   signal.StopLoss,
+			TakeProfit: signal.TakeProfit,
+			Reason:     signal.Reason,
+		},
+		fillIndex: fillIndex,
+	}
+
+	// Stop loss closes the whole position
+	_, stopErr := exchange.CreateOrder(&trading.OrderRequest{
+		Symbol:        symbol,
+		Side:          exitSide,
+		Type:          "STOP_MARKET",
+		StopPrice:     formatPrice(signal.StopLoss),
+		ClosePosition: true,
+	})
+
+	// Take profit mirrors the live order type of the strategy
+	takeProfit := &trading.OrderRequest{
+		Symbol:        symbol,
+		Side:          exitSide,
+		Type:          "TAKE_PROFIT_MARKET",
+		StopPrice:     formatPrice(signal.TakeProfit),
+		ClosePosition: true,
+	}
+	if signal.TakeProfitType == "LIMIT" {
+		takeProfit = &trading.OrderRequest{
+			Symbol:     symbol,
+			Side:       exitSide,
+			Type:       "LIMIT",
+			Price:      formatPrice(signal.TakeProfit),
+			Quantity:   formatQuantity(entry.ExecutedQty),
+			ReduceOnly: true,
+		}
+	}
+	_, takeProfitErr := exchange.CreateOrder(takeProfit)
+
+	if stopErr != nil || takeProfitErr != nil {
+		// Never hold a position without both protective orders
+		exchange.CreateOrder(&trading.OrderRequest{Symbol: symbol, Side: exitSide, Type: "MARKET", ClosePosition: true, ReduceOnly: true})
+		trade.trade.ExitReason = "PROTECTION_REJECTED"
+	}
+
+	return trade, nil
+}
+
+// size returns the quantity whose stop loss costs RiskPercent of the current
+// equity, capped like PositionSizer by the leverage on equity, MaxNotional and
+// the available margin. Lot sizes and leverage brackets are not applied, as
+// the backtest has no symbol filters.
+func (e *Engine) size(ctx context.Context, exchange *trading.PaperExchange, entry, stop float64) (float64, error) {
+	distance := math.Abs(entry - stop)
+	if entry <= 0 || distance == 0 {
+		return 0, fmt.Errorf("invalid entry %g and stop %g", entry, stop)
+	}
+	balance, err := exchange.GetUSDTBalance(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	leverage := float64(e.config.Leverage)
+	notional := balance.MarginBalance * e.config.Sizing.RiskPercent / 100 / distance * entry
+	notional = math.Min(notional, balance.MarginBalance*leverage)
+	if e.config.Sizing.MaxNotional > 0 {
+		notional = math.Min(notional, e.config.Sizing.MaxNotional)
+	}
+	notional = math.Min(notional, balance.AvailableBalance*e.config.Sizing.MarginUsagePercent/100*leverage)
+
+	quantity := notional / entry
+	if quantity <= 0 || math.IsInf(quantity, 0) || math.IsNaN(quantity) {
+		return 0, fmt.Errorf("invalid position size")
+	}
+	return quantity, nil
+}
+
+// closeTrade builds the finished trade from the fills since entry and cancels leftover orders
+func (e *Engine) closeTrade(ctx context.Context, exchange *trading.PaperExchange, current *openTrade, symbol string) Trade {
+	trade := current.trade
+	fills := exchange.Fills()[current.fillIndex:]
+
+	exitQty, exitValue := 0.0, 0.0
+	for i, fill := range fills {
+		trade.PnL += fill.RealizedPnL - fill.Fee
+		trade.Fees += fill.Fee
+		if i == 0 {
+			continue
+		}
+		exitQty += fill.Quantity
+		exitValue += fill.Price * fill.Quantity
+		trade.ExitTime = fill.Time
+
+		if trade.ExitReason == "" {
+			switch fill.Type {
+			case "STOP_MARKET":
+				trade.ExitReason = "STOP_LOSS"
+			case "LIMIT", "TAKE_PROFIT_MARKET":
+				trade.ExitReason = "TAKE_PROFIT"
+			}
+		}
+	}
+	if exitQty > 0 {
+		trade.ExitPrice = exitValue / exitQty
+	}
+
+	risk := math.Abs(trade.EntryPrice-trade.StopLoss) * trade.Quantity
+	if risk > 0 {
+		trade.RMultiple = trade.PnL / risk
+	}
+
+	// Cancel the sibling protective order left behind
+	orders, _ := exchange.GetOpenOrders(ctx)
+	for _, order := range orders {
+		if order.Symbol == symbol {
+			exchange.CancelOrder(ctx, symbol, order.OrderID)
+		}
+	}
+
+	return trade
+}
+
+// hasPosition reports whether the exchange holds a position in symbol
+func hasPosition(ctx context.Context, exchange trading.Exchange, symbol string) bool {
+	positions, _ := exchange.GetPositions(ctx)
+	for _, pos := range positions {
+		if pos.Symbol == symbol && pos.PositionAmt != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// formatQuantity formats a simulated quantity without rounding it away
+func formatQuantity(quantity float64) string {
+	return fmt.Sprintf("%.8f", quantity)
+}
+
+// formatPrice formats a simulated price without rounding it away
+func formatPrice(price float64) string {
+	return fmt.Sprintf("%.8f", price)
+}
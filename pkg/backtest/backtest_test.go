@@ -0,0 +1,123 @@
+package backtest
+
+import (
+	"math"
+	"testing"
+
+	"tread2/pkg/trading"
+)
+
+// onceStrategy goes long after a fixed bar and records the history length it saw
+type onceStrategy struct {
+	bar    int
+	seen   []int
+	stop   float64
+	target float64
+	tpType string
+}
+
+func (s *onceStrategy) Name() string { return "once" }
+
+func (s *onceStrategy) Evaluate(symbol string, history []trading.Candle) *Signal {
+	s.seen = append(s.seen, len(history))
+	if history[len(history)-1].OpenTime != int64(s.bar)*3600000 {
+		return nil
+	}
+	return &Signal{Side: "LONG", StopLoss: s.stop, TakeProfit: s.target, TakeProfitType: s.tpType}
+}
+
+// flatCandles builds hourly candles at price
+func flatCandles(count int, price float64) []trading.Candle {
+	candles := make([]trading.Candle, count)
+	for i := range candles {
+		candles[i] = trading.Candle{
+			OpenTime:  int64(i) * 3600000,
+			Open:      price,
+			High:      price,
+			Low:       price,
+			Close:     price,
+			CloseTime: int64(i+1)*3600000 - 1,
+		}
+	}
+	return candles
+}
+
+// newTestEngine creates a fee-free engine risking 0.5% of equity at 10x
+func newTestEngine() *Engine {
+	config := DefaultConfig()
+	config.Warmup = 5
+	config.Window = 5
+	config.Sizing.RiskPercent = 0.5
+	config.Leverage = 10
+	config.Paper.MakerFee = 0
+	config.Paper.TakerFee = 0
+	config.Paper.Slippage = 0
+	return NewEngine(config)
+}
+
+// TestRunTakeProfit tests a winning trade, the history window and the report metrics
+func TestRunTakeProfit(t *testing.T) {
+	candles := flatCandles(10, 100)
+	candles[7].High = 111 // Target is hit two bars after entry
+
+	strategy := &onceStrategy{bar: 4, stop: 95, target: 110, tpType: "TAKE_PROFIT_MARKET"}
+	report, err := newTestEngine().Run(strategy, "BTCUSDT", candles)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	for _, n := range strategy.seen {
+		if n > 5 {
+			t.Fatalf("Strategy saw %d bars, window is 5", n)
+		}
+	}
+
+	if len(report.Trades) != 1 {
+		t.Fatalf("Expected 1 trade, got %d", len(report.Trades))
+	}
+	trade := report.Trades[0]
+	if trade.ExitReason != "TAKE_PROFIT" || math.Abs(trade.PnL-10) > 1e-6 || math.Abs(trade.RMultiple-2) > 1e-6 {
+		t.Errorf("Unexpected trade %+v", trade)
+	}
+	if report.WinRate != 100 || math.Abs(report.FinalEquity-1010) > 1e-6 || report.MaxDrawdown != 0 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+// TestRunStopLossDrawdown tests a losing limit-target trade and the drawdown calculation
+func TestRunStopLossDrawdown(t *testing.T) {
+	candles := flatCandles(10, 100)
+	candles[6].Low = 94
+
+	strategy := &onceStrategy{bar: 4, stop: 95, target: 110, tpType: "LIMIT"}
+	report, err := newTestEngine().Run(strategy, "BTCUSDT", candles)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(report.Trades) != 1 || report.Trades[0].ExitReason != "STOP_LOSS" {
+		t.Fatalf("Expected one stop loss trade, got %+v", report.Trades)
+	}
+	if math.Abs(report.NetProfit+5) > 1e-6 || math.Abs(report.MaxDrawdown-5) > 1e-6 || report.WinRate != 0 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+// TestRunSizesByRisk tests that a tighter stop opens a larger position with the same risk
+func TestRunSizesByRisk(t *testing.T) {
+	candles := flatCandles(10, 100)
+	candles[6].Low = 97
+
+	strategy := &onceStrategy{bar: 4, stop: 98, target: 110, tpType: "LIMIT"}
+	report, err := newTestEngine().Run(strategy, "BTCUSDT", candles)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(report.Trades) != 1 {
+		t.Fatalf("Expected one trade, got %+v", report.Trades)
+	}
+	if trade := report.Trades[0]; math.Abs(trade.Quantity-2.5) > 1e-6 || math.Abs(trade.PnL+5) > 1e-6 {
+		t.Errorf("Expected 2.5 BTCUSDT losing 5 USDT, got %+v", trade)
+	}
+}
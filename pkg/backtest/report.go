@@ -0,0 +1,193 @@
+package backtest
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"tread2/pkg/trading"
+)
+
+// Trade is a completed round trip
+type Trade struct {
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"`
+	EntryTime  int64   `json:"entryTime"`
+	EntryPrice float64 `json:"entryPrice"`
+	ExitTime   int64   `json:"exitTime"`
+	ExitPrice  float64 `json:"exitPrice"`
+	Quantity   float64 `json:"quantity"`
+	StopLoss   float64 `json:"stopLoss"`
+	TakeProfit float64 `json:"takeProfit"`
+	Fees       float64 `json:"fees"`
+	PnL        float64 `json:"pnl"` // Net of fees
+	RMultiple  float64 `json:"rMultiple"`
+	ExitReason string  `json:"exitReason"` // STOP_LOSS, TAKE_PROFIT, END_OF_DATA, PROTECTION_REJECTED
+	Reason     string  `json:"reason"`
+}
+
+// EquityPoint is the marked-to-market equity at a bar close
+type EquityPoint struct {
+	Time   int64   `json:"time"`
+	Equity float64 `json:"equity"`
+}
+
+// Report summarizes a backtest run for a symbol, or the aggregate of several
+type Report struct {
+	Symbol         string        `json:"symbol"`
+	Strategy       string        `json:"strategy"`
+	Bars           int           `json:"bars"`
+	InitialBalance float64       `json:"initialBalance"`
+	FinalEquity    float64       `json:"finalEquity"`
+	Trades         []Trade       `json:"trades"`
+	Skipped        int           `json:"skipped"` // Signals whose entry order was rejected
+	Wins           int           `json:"wins"`
+	Losses         int           `json:"losses"`
+	WinRate        float64       `json:"winRate"` // Percent
+	GrossProfit    float64       `json:"grossProfit"`
+	GrossLoss      float64       `json:"grossLoss"`
+	NetProfit      float64       `json:"netProfit"`
+	ProfitFactor   float64       `json:"profitFactor"`
+	Expectancy     float64       `json:"expectancy"` // Average net PnL per trade
+	AverageR       float64       `json:"averageR"`
+	MaxDrawdown    float64       `json:"maxDrawdown"`
+	MaxDrawdownPct float64       `json:"maxDrawdownPct"`
+	EquityCurve    []EquityPoint `json:"equityCurve"`
+}
+
+// calculate derives the summary statistics from trades and the equity curve
+func (r *Report) calculate() {
+	r.Wins, r.Losses = 0, 0
+	r.GrossProfit, r.GrossLoss, r.NetProfit = 0, 0, 0
+	totalR := 0.0
+
+	for _, trade := range r.Trades {
+		r.NetProfit += trade.PnL
+		totalR += trade.RMultiple
+		if trade.PnL > 0 {
+			r.Wins++
+			r.GrossProfit += trade.PnL
+		} else {
+			r.Losses++
+			r.GrossLoss += -trade.PnL
+		}
+	}
+
+	if len(r.Trades) > 0 {
+		r.WinRate = float64(r.Wins) / float64(len(r.Trades)) * 100
+		r.Expectancy = r.NetProfit / float64(len(r.Trades))
+		r.AverageR = totalR / float64(len(r.Trades))
+	}
+	if r.GrossLoss > 0 {
+		r.ProfitFactor = r.GrossProfit / r.GrossLoss
+	}
+
+	r.FinalEquity = r.InitialBalance + r.NetProfit
+	if len(r.EquityCurve) > 0 {
+		r.FinalEquity = r.EquityCurve[len(r.EquityCurve)-1].Equity
+	}
+
+	// Max drawdown from the running equity peak
+	r.MaxDrawdown, r.MaxDrawdownPct = 0, 0
+	peak := r.InitialBalance
+	for _, point := range r.EquityCurve {
+		peak = math.Max(peak, point.Equity)
+		drawdown := peak - point.Equity
+		if drawdown > r.MaxDrawdown {
+			r.MaxDrawdown = drawdown
+		}
+		if peak > 0 && drawdown/peak*100 > r.MaxDrawdownPct {
+			r.MaxDrawdownPct = drawdown / peak * 100
+		}
+	}
+}
+
+// Aggregate combines per-symbol reports into one portfolio report. Each
+// symbol's profit and loss is added to a single starting balance, carrying the
+// last known equity forward where symbols have no bar at a timestamp.
+func Aggregate(strategy string, initialBalance float64, reports []*Report) *Report {
+	aggregate := &Report{
+		Symbol:         "ALL",
+		Strategy:       strategy,
+		InitialBalance: initialBalance,
+	}
+
+	timeSet := make(map[int64]bool)
+	for _, report := range reports {
+		aggregate.Bars += report.Bars
+		aggregate.Skipped += report.Skipped
+		aggregate.Trades = append(aggregate.Trades, report.Trades...)
+		for _, point := range report.EquityCurve {
+			timeSet[point.Time] = true
+		}
+	}
+
+	sort.SliceStable(aggregate.Trades, func(i, j int) bool {
+		return aggregate.Trades[i].ExitTime < aggregate.Trades[j].ExitTime
+	})
+
+	var times []int64
+	for t := range timeSet {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	cursors := make([]int, len(reports))
+	profits := make([]float64, len(reports))
+	for _, t := range times {
+		total := initialBalance
+		for i, report := range reports {
+			for cursors[i] < len(report.EquityCurve) && report.EquityCurve[cursors[i]].Time <= t {
+				profits[i] = report.EquityCurve[cursors[i]].Equity - report.InitialBalance
+				cursors[i]++
+			}
+			total += profits[i]
+		}
+		aggregate.EquityCurve = append(aggregate.EquityCurve, EquityPoint{Time: t, Equity: total})
+	}
+
+	aggregate.calculate()
+	return aggregate
+}
+
+// PrintReport writes a human-readable summary, optionally with the trade list
+func PrintReport(w io.Writer, r *Report, showTrades bool) {
+	fmt.Fprintf(w, "\n📊 BACKTEST: %s | %s\n", r.Symbol, r.Strategy)
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+	fmt.Fprintf(w, "├─ Bars: %d | Trades: %d | Skipped: %d\n", r.Bars, len(r.Trades), r.Skipped)
+	fmt.Fprintf(w, "├─ Wins: %d | Losses: %d | Win Rate: %.1f%%\n", r.Wins, r.Losses, r.WinRate)
+	fmt.Fprintf(w, "├─ Net Profit: %.4f USDT (Gross +%.4f / -%.4f)\n", r.NetProfit, r.GrossProfit, r.GrossLoss)
+	fmt.Fprintf(w, "├─ Profit Factor: %.2f | Expectancy: %.4f USDT | Avg R: %.2f\n", r.ProfitFactor, r.Expectancy, r.AverageR)
+	fmt.Fprintf(w, "├─ Max Drawdown: %.4f USDT (%.2f%%)\n", r.MaxDrawdown, r.MaxDrawdownPct)
+	fmt.Fprintf(w, "└─ Equity: %.4f → %.4f USDT\n", r.InitialBalance, r.FinalEquity)
+
+	if !showTrades || len(r.Trades) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "\n📋 Trades:")
+	for i, trade := range r.Trades {
+		fmt.Fprintf(w, "   %3d. %-12s %-5s %s → %s | %.4f → %.4f | PnL %8.4f (%.2fR) | %s\n",
+			i+1, trade.Symbol, trade.Side,
+			formatTime(trade.EntryTime), formatTime(trade.ExitTime),
+			trade.EntryPrice, trade.ExitPrice, trade.PnL, trade.RMultiple, trade.ExitReason)
+	}
+}
+
+// formatTime formats a millisecond timestamp in UTC
+func formatTime(ms int64) string {
+	return time.UnixMilli(ms).UTC().Format("2006-01-02 15:04")
+}
+
+// sortedSymbols returns map keys in a stable order
+func sortedSymbols(data map[string][]trading.Candle) []string {
+	symbols := make([]string, 0, len(data))
+	for symbol := range data {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
@@ -0,0 +1,141 @@
+package backtest
+
+import (
+	"tread2/pkg/analysis"
+	"tread2/pkg/trading"
+)
+
+// BreakoutStrategy replays the support/resistance breakout used by the main
+// trader. Without the AI target it uses the same risk/reward fallback as
+// setBreakoutStopLossAndTakeProfit and a reduce-only LIMIT take profit.
+type BreakoutStrategy struct {
+	RiskReward float64 // Take profit distance as a multiple of the stop distance
+}
+
+// NewBreakoutStrategy creates a breakout strategy with the 2:1 fallback target
+func NewBreakoutStrategy() *BreakoutStrategy {
+	return &BreakoutStrategy{RiskReward: 2.0}
+}
+
+// Name returns the strategy name
+func (s *BreakoutStrategy) Name() string {
+	return "breakout"
+}
+
+// Evaluate checks the last closed bar for a support/resistance breakout
+func (s *BreakoutStrategy) Evaluate(symbol string, history []trading.Candle) *Signal {
+	breakout, err := analysis.AnalyzeSupportResistanceBreakout(toCandleData(history))
+	if err != nil || breakout.Signal == "NONE" {
+		return nil
+	}
+
+	entry := history[len(history)-1].Close
+	risk := entry - breakout.StopLoss
+	if breakout.Signal == "SHORT" {
+		risk = breakout.StopLoss - entry
+	}
+	if risk <= 0 {
+		return nil
+	}
+
+	takeProfit := entry + risk*s.RiskReward
+	if breakout.Signal == "SHORT" {
+		takeProfit = entry - risk*s.RiskReward
+	}
+
+	return &Signal{
+		Side:           breakout.Signal,
+		StopLoss:       breakout.StopLoss,
+		TakeProfit:     takeProfit,
+		TakeProfitType: "LIMIT",
+		Reason:         breakout.Analysis,
+	}
+}
+
+// RetestStrategy replays the channel retest filter used by the auto-trader.
+// The AI's stop loss and take profit percentages are replaced by fixed ones,
+// placed as close-position STOP_MARKET/TAKE_PROFIT_MARKET orders like
+// AutoTrader.openPosition.
+type RetestStrategy struct {
+	Analyzer      *analysis.TechnicalAnalyzer
+	MinConfidence float64 // Minimum retest confidence (0-1)
+	StopLossPct   float64 // Stop loss distance in percent
+	TakeProfitPct float64 // Take profit distance in percent
+}
+
+// NewRetestStrategy creates a retest strategy with the auto-trader's confidence filter
+func NewRetestStrategy() *RetestStrategy {
+	return &RetestStrategy{
+		Analyzer:      analysis.NewTechnicalAnalyzer(),
+		MinConfidence: 0.6,
+		StopLossPct:   2.0,
+		TakeProfitPct: 4.0,
+	}
+}
+
+// Name returns the strategy name
+func (s *RetestStrategy) Name() string {
+	return "retest"
+}
+
+// Evaluate checks whether the last closed bar is a successful channel retest
+func (s *RetestStrategy) Evaluate(symbol string, history []trading.Candle) *Signal {
+	klines := make([]*analysis.Kline, len(history))
+	for i, candle := range history {
+		klines[i] = &analysis.Kline{
+			OpenTime:  candle.OpenTime,
+			Open:      candle.Open,
+			High:      candle.High,
+			Low:       candle.Low,
+			Close:     candle.Close,
+			Volume:    candle.Volume,
+			CloseTime: candle.CloseTime,
+			IsGreen:   candle.Close > candle.Open,
+			IsRed:     candle.Close < candle.Open,
+		}
+	}
+
+	last := history[len(history)-1]
+	for _, signal := range s.Analyzer.DetectBreakouts(klines, symbol) {
+		// Only act on a retest confirmed by the bar that just closed
+		if signal.Type != "RETEST_SUCCESS" || signal.Timestamp.Unix() != last.OpenTime/1000 || signal.Confidence <= s.MinConfidence {
+			continue
+		}
+
+		entry := last.Close
+		if signal.Price > signal.ChannelLevel {
+			return &Signal{
+				Side:           "LONG",
+				StopLoss:       entry * (1 - s.StopLossPct/100),
+				TakeProfit:     entry * (1 + s.TakeProfitPct/100),
+				TakeProfitType: "TAKE_PROFIT_MARKET",
+				Reason:         signal.Description,
+			}
+		}
+		return &Signal{
+			Side:           "SHORT",
+			StopLoss:       entry * (1 + s.StopLossPct/100),
+			TakeProfit:     entry * (1 - s.TakeProfitPct/100),
+			TakeProfitType: "TAKE_PROFIT_MARKET",
+			Reason:         signal.Description,
+		}
+	}
+
+	return nil
+}
+
+// toCandleData converts candles to the analysis package format
+func toCandleData(history []trading.Candle) []analysis.CandleData {
+	candles := make([]analysis.CandleData, len(history))
+	for i, candle := range history {
+		candles[i] = analysis.CandleData{
+			Timestamp: candle.OpenTime,
+			Open:      candle.Open,
+			High:      candle.High,
+			Low:       candle.Low,
+			Close:     candle.Close,
+			Volume:    candle.Volume,
+		}
+	}
+	return candles
+}
@@ -0,0 +1,240 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+// ErrorClass groups Binance API errors by how the caller should react
+type ErrorClass string
+
+const (
+	ErrorClassTimestamp     ErrorClass = "TIMESTAMP"      // -1021: local clock is out of sync with the server
+	ErrorClassRateLimit     ErrorClass = "RATE_LIMIT"     // -1003, -1015: sent with HTTP 429, or 418 once banned
+	ErrorClassTransient     ErrorClass = "TRANSIENT"      // -1001: server busy, the request was not processed
+	ErrorClassUnknownStatus ErrorClass = "UNKNOWN_STATUS" // -1006, -1007, network errors: the request may have been executed
+	ErrorClassPrecision     ErrorClass = "PRECISION"      // -1111, -1013, -4014, -4023: price or quantity off the filters
+	ErrorClassMargin        ErrorClass = "MARGIN"         // -2018, -2019: balance or margin is insufficient
+	ErrorClassMinNotional   ErrorClass = "MIN_NOTIONAL"   // -4164: order value below the minimum
+	ErrorClassReduceOnly    ErrorClass = "REDUCE_ONLY"    // -2022: reduce-only order would not reduce a position
+	ErrorClassNoChange      ErrorClass = "NO_CHANGE"      // -4046, -4059: setting already has the requested value
+	ErrorClassNotFound      ErrorClass = "NOT_FOUND"      // -2013: order does not exist
+	ErrorClassPositionSide  ErrorClass = "POSITION_SIDE"  // -4061: position side does not match the account's position mode
+	ErrorClassRejected      ErrorClass = "REJECTED"       // Any other error
+)
+
+// ErrorPolicy is what a caller does after an error of a class
+type ErrorPolicy string
+
+const (
+	PolicyRetry       ErrorPolicy = "RETRY"        // Wait with exponential backoff and send again
+	PolicyResyncClock ErrorPolicy = "RESYNC_CLOCK" // Sync the time offset with the server and send again
+	PolicyReround     ErrorPolicy = "REROUND"      // Reload the symbol filters, round again and send once more
+	PolicyIgnore      ErrorPolicy = "IGNORE"       // Treat as success
+	PolicyAbort       ErrorPolicy = "ABORT"        // Return the error to the caller
+)
+
+// Order rejection reasons from the exchange that have no validator counterpart
+var (
+	ErrTimestamp   = errors.New("timestamp outside recvWindow")
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// errorCodeClasses maps Binance error codes to their class
+var errorCodeClasses = map[int64]ErrorClass{
+	-1021: ErrorClassTimestamp,
+	-1003: ErrorClassRateLimit,
+	-1015: ErrorClassRateLimit,
+	-1001: ErrorClassTransient,
+	-1006: ErrorClassUnknownStatus,
+	-1007: ErrorClassUnknownStatus,
+	-1111: ErrorClassPrecision,
+	-1013: ErrorClassPrecision,
+	-4014: ErrorClassPrecision,
+	-4023: ErrorClassPrecision,
+	-2018: ErrorClassMargin,
+	-2019: ErrorClassMargin,
+	-4164: ErrorClassMinNotional,
+	-2022: ErrorClassReduceOnly,
+	-4046: ErrorClassNoChange,
+	-4059: ErrorClassNoChange,
+	-2013: ErrorClassNotFound,
+	-4061: ErrorClassPositionSide,
+}
+
+// Policy returns how errors of the class are handled. Errors of unknown
+// status are not retried because a resent order could fill twice.
+func (c ErrorClass) Policy() ErrorPolicy {
+	switch c {
+	case ErrorClassTimestamp:
+		return PolicyResyncClock
+	case ErrorClassRateLimit, ErrorClassTransient, ErrorClassPositionSide:
+		return PolicyRetry
+	case ErrorClassPrecision:
+		return PolicyReround
+	case ErrorClassNoChange:
+		return PolicyIgnore
+	}
+	return PolicyAbort
+}
+
+// ExchangeError is a Binance API error with its class. errors.Is matches it
+// against the original *common.APIError and the matching rejection reason,
+// such as ErrInsufficientMargin for -2019.
+type ExchangeError struct {
+	Code    int64
+	Message string
+	Class   ErrorClass
+	err     error
+}
+
+// Error returns the Binance code and message
+func (e *ExchangeError) Error() string {
+	return fmt.Sprintf("%s (code %d): %s", e.Class, e.Code, e.Message)
+}
+
+// Unwrap returns the original error and the rejection reason of the class
+func (e *ExchangeError) Unwrap() []error {
+	errs := []error{e.err}
+	switch e.Class {
+	case ErrorClassTimestamp:
+		errs = append(errs, ErrTimestamp)
+	case ErrorClassRateLimit:
+		errs = append(errs, ErrRateLimited)
+	case ErrorClassPrecision:
+		errs = append(errs, ErrPrecision)
+	case ErrorClassMargin:
+		errs = append(errs, ErrInsufficientMargin)
+	case ErrorClassMinNotional:
+		errs = append(errs, ErrBelowMinNotional)
+	case ErrorClassReduceOnly:
+		errs = append(errs, ErrReduceOnly)
+	case ErrorClassNotFound:
+		errs = append(errs, ErrOrderNotFound)
+	}
+	return errs
+}
+
+// ClassifyError returns the class of err, or "" for nil
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ""
+	}
+
+	var exchangeErr *ExchangeError
+	if errors.As(err, &exchangeErr) {
+		return exchangeErr.Class
+	}
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) {
+		if class, ok := errorCodeClasses[apiErr.Code]; ok {
+			return class
+		}
+		return ErrorClassRejected
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassRejected
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorClassUnknownStatus
+	}
+	return ErrorClassRejected
+}
+
+// wrapAPIError returns err as an *ExchangeError when it is a Binance API error
+func wrapAPIError(err error) error {
+	var apiErr *common.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	return &ExchangeError{
+		Code:    apiErr.Code,
+		Message: apiErr.Message,
+		Class:   ClassifyError(apiErr),
+		err:     err,
+	}
+}
+
+// RetryConfig bounds how classified errors are retried
+type RetryConfig struct {
+	MaxAttempts    int           // Attempts including the first
+	BaseDelay      time.Duration // First backoff, doubled on each retry
+	MaxDelay       time.Duration
+	RateLimitDelay time.Duration // Minimum wait after a rate limit error
+}
+
+// DefaultRetryConfig returns the retry limits used by NewTradingClient
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts:    3,
+		BaseDelay:      500 * time.Millisecond,
+		MaxDelay:       10 * time.Second,
+		RateLimitDelay: 5 * time.Second,
+	}
+}
+
+// retry runs call and applies the policy of each error's class until it
+// succeeds, aborts or runs out of attempts. reround prepares a precision
+// retry and may be nil; it is used at most once. The returned error is an
+// *ExchangeError for Binance API errors.
+func (tc *TradingClient) retry(ctx context.Context, call func() error, reround func() error) error {
+	delay := tc.Retry.BaseDelay
+	rerounded := false
+
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil {
+			return nil
+		}
+		err = wrapAPIError(err)
+
+		class := ClassifyError(err)
+		policy := class.Policy()
+		if policy == PolicyIgnore {
+			return nil
+		}
+		if policy == PolicyAbort || attempt >= tc.Retry.MaxAttempts {
+			return err
+		}
+
+		switch policy {
+		case PolicyResyncClock:
+			offset, syncErr := tc.BinanceClient.NewSetServerTimeService().Do(ctx)
+			if syncErr != nil {
+				return err
+			}
+			log.Printf("🕒 Resynced clock with Binance (offset %dms)", offset)
+
+		case PolicyReround:
+			if reround == nil || rerounded {
+				return err
+			}
+			if roundErr := reround(); roundErr != nil {
+				return err
+			}
+			rerounded = true
+
+		case PolicyRetry:
+			wait := delay
+			if class == ErrorClassRateLimit && wait < tc.Retry.RateLimitDelay {
+				wait = tc.Retry.RateLimitDelay
+			}
+			log.Printf("⏳ %v, retrying in %s", err, wait)
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(wait):
+			}
+			if delay *= 2; delay > tc.Retry.MaxDelay {
+				delay = tc.Retry.MaxDelay
+			}
+		}
+	}
+}
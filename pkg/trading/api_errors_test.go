@@ -0,0 +1,98 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+// TestClassifyError tests the class, policy and rejection reason of Binance error codes
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		code   int64
+		class  ErrorClass
+		policy ErrorPolicy
+		reason error
+	}{
+		{-1021, ErrorClassTimestamp, PolicyResyncClock, ErrTimestamp},
+		{-1003, ErrorClassRateLimit, PolicyRetry, ErrRateLimited},
+		{-2019, ErrorClassMargin, PolicyAbort, ErrInsufficientMargin},
+		{-1111, ErrorClassPrecision, PolicyReround, ErrPrecision},
+		{-4164, ErrorClassMinNotional, PolicyAbort, ErrBelowMinNotional},
+		{-2022, ErrorClassReduceOnly, PolicyAbort, ErrReduceOnly},
+		{-4061, ErrorClassPositionSide, PolicyRetry, nil},
+		{-1007, ErrorClassUnknownStatus, PolicyAbort, nil},
+		{-4046, ErrorClassNoChange, PolicyIgnore, nil},
+		{-2011, ErrorClassRejected, PolicyAbort, nil},
+	}
+
+	for _, tt := range tests {
+		apiErr := &common.APIError{Code: tt.code, Message: "test"}
+		err := fmt.Errorf("failed to create order: %w", wrapAPIError(apiErr))
+
+		if class := ClassifyError(err); class != tt.class {
+			t.Errorf("Code %d: expected class %s, got %s", tt.code, tt.class, class)
+		}
+		if policy := tt.class.Policy(); policy != tt.policy {
+			t.Errorf("Code %d: expected policy %s, got %s", tt.code, tt.policy, policy)
+		}
+		if tt.reason != nil && !errors.Is(err, tt.reason) {
+			t.Errorf("Code %d: expected errors.Is(%v)", tt.code, tt.reason)
+		}
+		var original *common.APIError
+		if !errors.As(err, &original) || original.Code != tt.code {
+			t.Errorf("Code %d: original API error not reachable", tt.code)
+		}
+	}
+}
+
+// TestRetryPolicies tests retries, re-rounding and aborts
+func TestRetryPolicies(t *testing.T) {
+	tc := &TradingClient{Retry: RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, RateLimitDelay: time.Millisecond}}
+	ctx := context.Background()
+
+	// Rate limits are retried until they succeed
+	calls := 0
+	err := tc.retry(ctx, func() error {
+		calls++
+		if calls < 3 {
+			return &common.APIError{Code: -1003}
+		}
+		return nil
+	}, nil)
+	if err != nil || calls != 3 {
+		t.Errorf("Expected success on attempt 3, got %v after %d calls", err, calls)
+	}
+
+	// Precision errors are re-rounded once
+	calls, rounds := 0, 0
+	err = tc.retry(ctx, func() error {
+		calls++
+		return &common.APIError{Code: -1111}
+	}, func() error {
+		rounds++
+		return nil
+	})
+	if !errors.Is(err, ErrPrecision) || calls != 2 || rounds != 1 {
+		t.Errorf("Expected one re-round then abort, got %v after %d calls and %d rounds", err, calls, rounds)
+	}
+
+	// Margin errors abort immediately
+	calls = 0
+	err = tc.retry(ctx, func() error {
+		calls++
+		return &common.APIError{Code: -2019}
+	}, nil)
+	if !errors.Is(err, ErrInsufficientMargin) || calls != 1 {
+		t.Errorf("Expected abort after 1 call, got %v after %d calls", err, calls)
+	}
+
+	// Settings that are already applied are not errors
+	if err := tc.retry(ctx, func() error { return &common.APIError{Code: -4046} }, nil); err != nil {
+		t.Errorf("Expected -4046 to be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ErrProtectionFailed is returned when a stop loss or take profit could not be
+// placed and the entry was rolled back
+var ErrProtectionFailed = errors.New("protective order failed")
+
+// Attempts and first backoff for each protective order and for the rollback
+var (
+	bracketAttempts   = 3
+	bracketRetryDelay = 500 * time.Millisecond
+)
+
+// Bracket is an entry order with its stop loss and take profits
+type Bracket struct {
+	Symbol             string  `json:"symbol"`
+	Side               string  `json:"side"`     // Entry side, BUY or SELL
+	Quantity           string  `json:"quantity"` // Entry quantity
+	EntryOrderID       int64   `json:"entryOrderId"`
+	EntryClientOrderID string  `json:"entryClientOrderId,omitempty"` // Looks the entry up to find how much of it filled
+	StopOrderID        int64   `json:"stopOrderId,omitempty"`
+	TakeProfitOrderIDs []int64 `json:"takeProfitOrderIds,omitempty"` // One per target when scaling out
+	EntryPrice         float64 `json:"entryPrice"`                   // Average fill price, 0 if not reported
+}
+
+// OrderIDs returns the IDs of the orders placed for the bracket
+func (b *Bracket) OrderIDs() []int64 {
+	var ids []int64
+	for _, id := range append([]int64{b.EntryOrderID, b.StopOrderID}, b.TakeProfitOrderIDs...) {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// CancelProtection cancels the stop loss and take profits that are still open
+func (b *Bracket) CancelProtection(ctx context.Context, ex Exchange) error {
+	var lastErr error
+	for _, id := range append([]int64{b.StopOrderID}, b.TakeProfitOrderIDs...) {
+		if id == 0 {
+			continue
+		}
+		if err := ex.CancelOrder(ctx, b.Symbol, id); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// PlaceBracket places entry, then its stop loss and take profits, usually one
+// or one per target when scaling out. Each protective order is retried; if one
+// still fails the others are cancelled and the position is flattened with a
+// reduce-only market order, so no position is left without protection. Such
+// failures wrap ErrProtectionFailed. An entry whose client order ID was
+// already used is not resent; the existing orders are returned with
+// ErrDuplicateSignal.
+func PlaceBracket(ctx context.Context, ex Exchange, entry, stopLoss *OrderRequest, takeProfits ...*OrderRequest) (*Bracket, error) {
+	// An entry already placed under the same client order ID, usually by the
+	// run before a restart, is not sent again
+	existing, err := findOrder(ctx, ex, entry)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		bracket := &Bracket{
+			Symbol:             entry.Symbol,
+			Side:               entry.Side,
+			Quantity:           entry.Quantity,
+			EntryOrderID:       existing.OrderID,
+			EntryClientOrderID: entry.ClientOrderID,
+		}
+		if stop, _ := findOrder(ctx, ex, stopLoss); stop != nil {
+			bracket.StopOrderID = stop.OrderID
+		}
+		for _, takeProfit := range takeProfits {
+			if target, _ := findOrder(ctx, ex, takeProfit); target != nil {
+				bracket.TakeProfitOrderIDs = append(bracket.TakeProfitOrderIDs, target.OrderID)
+			}
+		}
+		log.Printf("♻️  Entry %s is already placed (order %d), not sending it again", entry.ClientOrderID, existing.OrderID)
+		return bracket, fmt.Errorf("%w: %s", ErrDuplicateSignal, entry.ClientOrderID)
+	}
+
+	response, err := ex.CreateOrder(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place entry: %w", err)
+	}
+
+	bracket := &Bracket{
+		Symbol:             entry.Symbol,
+		Side:               entry.Side,
+		Quantity:           entry.Quantity,
+		EntryOrderID:       parseOrderID(response.OrderID),
+		EntryClientOrderID: entry.ClientOrderID,
+		EntryPrice:         response.AvgPrice,
+	}
+
+	if err := AttachProtection(ctx, ex, bracket, stopLoss, takeProfits...); err != nil {
+		return bracket, err
+	}
+	return bracket, nil
+}
+
+// AttachProtection places the stop loss and take profits of a filled entry,
+// rolling the position back as PlaceBracket does when any of them fails
+func AttachProtection(ctx context.Context, ex Exchange, bracket *Bracket, stopLoss *OrderRequest, takeProfits ...*OrderRequest) error {
+	stopID, err := placeWithRetry(ctx, ex, stopLoss)
+	if err != nil {
+		return rollbackBracket(ctx, ex, bracket, fmt.Errorf("stop loss: %w", err))
+	}
+	bracket.StopOrderID = stopID
+
+	for i, takeProfit := range takeProfits {
+		takeProfitID, err := placeWithRetry(ctx, ex, takeProfit)
+		if err != nil {
+			return rollbackBracket(ctx, ex, bracket, fmt.Errorf("take profit %d: %w", i+1, err))
+		}
+		bracket.TakeProfitOrderIDs = append(bracket.TakeProfitOrderIDs, takeProfitID)
+	}
+
+	return nil
+}
+
+// placeWithRetry places order and returns its ID, or the ID of the order
+// already placed under its client order ID. Only errors of unknown status are
+// retried, with exponential backoff, as the order is looked up before each
+// send. Rejections are returned at once, and busy or rate limit errors are
+// already retried by TradingClient.
+func placeWithRetry(ctx context.Context, ex Exchange, order *OrderRequest) (int64, error) {
+	delay := bracketRetryDelay
+	var lastErr error
+
+	for attempt := 1; attempt <= bracketAttempts; attempt++ {
+		if existing, err := findOrder(ctx, ex, order); err == nil && existing != nil {
+			log.Printf("♻️  %s order %s is already placed (order %d)", order.Type, order.ClientOrderID, existing.OrderID)
+			return existing.OrderID, nil
+		}
+
+		response, err := ex.CreateOrder(order)
+		if err == nil {
+			return parseOrderID(response.OrderID), nil
+		}
+		if ClassifyError(err) != ErrorClassUnknownStatus {
+			return 0, err
+		}
+		lastErr = err
+		log.Printf("⚠️  Attempt %d/%d to place %s for %s failed: %v", attempt, bracketAttempts, order.Type, order.Symbol, err)
+
+		if attempt < bracketAttempts {
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+	}
+	return 0, lastErr
+}
+
+// replaceStop moves a STOP_MARKET stop loss to stopPrice and returns the new
+// order's ID. A quantity stop is resized to quantity (0 keeps its size) and
+// replaced before the old one is cancelled, so the position stays protected.
+// A closePosition stop has to be cancelled first because Binance allows one
+// per direction; it is restored if the replacement fails.
+func replaceStop(ctx context.Context, ex Exchange, stop Order, stopPrice, quantity float64) (int64, error) {
+	stopPriceStr, err := FormatPriceFor(ctx, ex, stop.Symbol, stopPrice)
+	if err != nil {
+		return 0, err
+	}
+	request := &OrderRequest{
+		Symbol:        stop.Symbol,
+		Side:          stop.Side,
+		Type:          "STOP_MARKET",
+		StopPrice:     stopPriceStr,
+		ClientOrderID: replacementClientOrderID(stop.ClientOrderID),
+		PositionSide:  stop.PositionSide,
+	}
+
+	if stop.ClosePosition {
+		request.ClosePosition = true
+		if err := ex.CancelOrder(ctx, stop.Symbol, stop.OrderID); err != nil {
+			return 0, err
+		}
+
+		newID, err := placeWithRetry(ctx, ex, request)
+		if err != nil {
+			restore := *request
+			restore.StopPrice = formatFloat(stop.StopPrice)
+			restore.ClientOrderID = stop.ClientOrderID
+			if _, restoreErr := placeWithRetry(ctx, ex, &restore); restoreErr != nil {
+				log.Printf("🚨 Failed to restore stop for %s, position is UNPROTECTED: %v", stop.Symbol, restoreErr)
+			}
+			return 0, err
+		}
+		return newID, nil
+	}
+
+	if quantity <= 0 {
+		quantity = stop.OrigQty
+	}
+	if request.Quantity, err = FormatMarketQuantityFor(ctx, ex, stop.Symbol, quantity); err != nil {
+		return 0, err
+	}
+	request.ReduceOnly = true
+
+	newID, err := placeWithRetry(ctx, ex, request)
+	if err != nil {
+		return 0, err
+	}
+	if err := ex.CancelOrder(ctx, stop.Symbol, stop.OrderID); err != nil {
+		log.Printf("⚠️  Failed to cancel replaced stop %d for %s: %v", stop.OrderID, stop.Symbol, err)
+	}
+	return newID, nil
+}
+
+// rollbackBracket cancels the placed protective orders and the entry, and
+// closes whatever filled with a reduce-only market order. Only the filled
+// entry quantity is closed, so a position held before the entry is kept. The
+// flatten is tagged from the entry so a retry finds the first send; it is
+// sent once when the entry is untagged.
+func rollbackBracket(ctx context.Context, ex Exchange, bracket *Bracket, cause error) error {
+	log.Printf("🚨 Protection failed for %s (%v), rolling back the entry", bracket.Symbol, cause)
+
+	if err := bracket.CancelProtection(ctx, ex); err != nil {
+		log.Printf("⚠️  Failed to cancel protective orders for %s: %v", bracket.Symbol, err)
+	}
+	bracket.StopOrderID, bracket.TakeProfitOrderIDs = 0, nil
+
+	// A resting entry is cancelled; the exchange rejects this once it has filled
+	if bracket.EntryOrderID != 0 {
+		ex.CancelOrder(ctx, bracket.Symbol, bracket.EntryOrderID)
+	}
+
+	positions, err := ex.GetPositions(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v; failed to check position, %s may be unprotected: %v", ErrProtectionFailed, cause, bracket.Symbol, err)
+	}
+	position := findPosition(positions, bracket.Symbol, orderDirection(bracket.Side))
+	if position == nil {
+		return fmt.Errorf("%w: %v (entry cancelled)", ErrProtectionFailed, cause)
+	}
+
+	filled := parseFloat(bracket.Quantity)
+	if bracket.EntryClientOrderID != "" {
+		if entry, err := ex.GetOrderByClientID(ctx, bracket.Symbol, bracket.EntryClientOrderID); err == nil {
+			filled = entry.ExecutedQty
+		}
+	}
+	amount := math.Min(filled, position.PositionAmt)
+	if amount <= 0 {
+		return fmt.Errorf("%w: %v (entry cancelled)", ErrProtectionFailed, cause)
+	}
+
+	quantity, err := FormatMarketQuantityFor(ctx, ex, bracket.Symbol, amount)
+	if err != nil {
+		quantity = formatFloat(amount)
+	}
+	side := "SELL"
+	if position.Side == "SHORT" {
+		side = "BUY"
+	}
+
+	flatten := &OrderRequest{
+		Symbol:       bracket.Symbol,
+		Side:         side,
+		Type:         "MARKET",
+		Quantity:     quantity,
+		ReduceOnly:   true,
+		PositionSide: position.Side,
+	}
+	place := placeWithRetry
+	if tag, ok := ParseClientOrderID(bracket.EntryClientOrderID); ok {
+		tag.Leg = LegRollback
+		flatten.ClientOrderID = tag.ClientOrderID()
+	} else {
+		place = func(ctx context.Context, ex Exchange, order *OrderRequest) (int64, error) {
+			response, err := ex.CreateOrder(order)
+			if err != nil {
+				return 0, err
+			}
+			return parseOrderID(response.OrderID), nil
+		}
+	}
+
+	if _, err := place(ctx, ex, flatten); err != nil {
+		log.Printf("🚨 Failed to flatten %s, position is UNPROTECTED: %v", bracket.Symbol, err)
+		return fmt.Errorf("%w: %v; failed to flatten %s: %v", ErrProtectionFailed, cause, bracket.Symbol, err)
+	}
+
+	log.Printf("🔙 Flattened %s %s after protection failed", quantity, bracket.Symbol)
+	return fmt.Errorf("%w: %v (position flattened)", ErrProtectionFailed, cause)
+}
+
+// parseOrderID converts an OrderResponse ID to the numeric order ID
+func parseOrderID(id string) int64 {
+	orderID, _ := strconv.ParseInt(id, 10, 64)
+	return orderID
+}
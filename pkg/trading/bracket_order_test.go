@@ -0,0 +1,186 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestPlaceBracket tests that the entry and both protective orders are tracked
+func TestPlaceBracket(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	ctx := context.Background()
+
+	bracket, err := PlaceBracket(ctx, pe,
+		&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"},
+		&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "95", ClosePosition: true},
+		&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "TAKE_PROFIT_MARKET", StopPrice: "110", ClosePosition: true},
+	)
+	if err != nil {
+		t.Fatalf("PlaceBracket failed: %v", err)
+	}
+	if len(bracket.OrderIDs()) != 3 {
+		t.Errorf("Expected 3 order IDs, got %v", bracket.OrderIDs())
+	}
+
+	orders, _ := pe.GetOpenOrders(ctx)
+	if len(orders) != 2 {
+		t.Errorf("Expected 2 protective orders, got %d", len(orders))
+	}
+}
+
+// TestPlaceBracketRollsBack tests that a rejected stop loss flattens the entry
+func TestPlaceBracketRollsBack(t *testing.T) {
+	bracketRetryDelay = time.Millisecond
+	defer func() { bracketRetryDelay = 500 * time.Millisecond }()
+
+	pe := newTestPaperExchange(100)
+	ctx := context.Background()
+
+	// A sell stop above the market triggers immediately and is rejected
+	bracket, err := PlaceBracket(ctx, pe,
+		&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"},
+		&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "105", ClosePosition: true},
+		&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "TAKE_PROFIT_MARKET", StopPrice: "110", ClosePosition: true},
+	)
+	if !errors.Is(err, ErrProtectionFailed) {
+		t.Fatalf("Expected ErrProtectionFailed, got %v", err)
+	}
+	if bracket == nil || bracket.EntryOrderID == 0 || bracket.StopOrderID != 0 {
+		t.Errorf("Expected only the entry ID after rollback, got %+v", bracket)
+	}
+
+	positions, _ := pe.GetPositions(ctx)
+	if len(positions) != 0 {
+		t.Errorf("Expected position to be flattened, got %+v", positions)
+	}
+	orders, _ := pe.GetOpenOrders(ctx)
+	if len(orders) != 0 {
+		t.Errorf("Expected no open orders, got %+v", orders)
+	}
+}
+
+// stopFailingExchange fails the first stop loss orders with the queued errors
+type stopFailingExchange struct {
+	*PaperExchange
+	errs     []error
+	attempts int
+}
+
+func (se *stopFailingExchange) CreateOrder(order *OrderRequest) (*OrderResponse, error) {
+	if order.Type == "STOP_MARKET" {
+		se.attempts++
+		if len(se.errs) > 0 {
+			err := se.errs[0]
+			se.errs = se.errs[1:]
+			return nil, err
+		}
+	}
+	return se.PaperExchange.CreateOrder(order)
+}
+
+// TestPlaceWithRetryClassifies tests that only errors of unknown status are retried
+func TestPlaceWithRetryClassifies(t *testing.T) {
+	bracketRetryDelay = time.Millisecond
+	defer func() { bracketRetryDelay = 500 * time.Millisecond }()
+
+	unknown := &ExchangeError{Code: -1006, Class: ErrorClassUnknownStatus}
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+		placed   bool
+	}{
+		{"unknown status", []error{unknown}, 2, true},
+		{"margin", []error{&ExchangeError{Code: -2019, Class: ErrorClassMargin}}, 1, false},
+		{"validation", []error{ErrPrecision}, 1, false},
+		{"unknown status every time", []error{unknown, unknown, unknown}, 3, false},
+	}
+
+	for _, tt := range tests {
+		pe := newTestPaperExchange(100)
+		pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"})
+		ex := &stopFailingExchange{PaperExchange: pe, errs: tt.errs}
+
+		_, err := placeWithRetry(context.Background(), ex, &OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "95", ClosePosition: true})
+		if (err == nil) != tt.placed || ex.attempts != tt.attempts {
+			t.Errorf("%s: expected placed=%v after %d attempts, got %v after %d", tt.name, tt.placed, tt.attempts, err, ex.attempts)
+		}
+	}
+}
+
+// TestRollbackKeepsExistingPosition tests that a failed add-on only closes what the add-on filled
+func TestRollbackKeepsExistingPosition(t *testing.T) {
+	bracketRetryDelay = time.Millisecond
+	defer func() { bracketRetryDelay = 500 * time.Millisecond }()
+
+	pe := newTestPaperExchange(100)
+	ctx := context.Background()
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"}); err != nil {
+		t.Fatalf("Failed to open position: %v", err)
+	}
+
+	_, err := PlaceBracket(ctx, pe,
+		&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "0.5", ClientOrderID: "addon"},
+		&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "105", ClosePosition: true},
+	)
+	if !errors.Is(err, ErrProtectionFailed) {
+		t.Fatalf("Expected ErrProtectionFailed, got %v", err)
+	}
+
+	positions, _ := pe.GetPositions(ctx)
+	if len(positions) != 1 || positions[0].PositionAmt != 1 {
+		t.Errorf("Expected the original 1 BTCUSDT to remain, got %+v", positions)
+	}
+}
+
+// lostResponseExchange places reduce-only market orders but reports the first
+// one with an error of unknown status, as when the response is lost
+type lostResponseExchange struct {
+	*PaperExchange
+	flattens int
+}
+
+func (le *lostResponseExchange) CreateOrder(order *OrderRequest) (*OrderResponse, error) {
+	response, err := le.PaperExchange.CreateOrder(order)
+	if err != nil || order.Type != "MARKET" || !order.ReduceOnly {
+		return response, err
+	}
+	le.flattens++
+	if le.flattens == 1 {
+		return nil, &ExchangeError{Code: -1007, Class: ErrorClassUnknownStatus}
+	}
+	return response, nil
+}
+
+// TestRollbackFlattensOnce tests that a flatten with a lost response is found
+// by its tag rather than sent again
+func TestRollbackFlattensOnce(t *testing.T) {
+	bracketRetryDelay = time.Millisecond
+	defer func() { bracketRetryDelay = 500 * time.Millisecond }()
+
+	pe := newTestPaperExchange(100)
+	ctx := context.Background()
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"}); err != nil {
+		t.Fatalf("Failed to open position: %v", err)
+	}
+	ex := &lostResponseExchange{PaperExchange: pe}
+
+	tag := ClientOrderTag{Strategy: "breakout", Symbol: "BTCUSDT", SignalID: "s1", Leg: LegEntry}
+	_, err := PlaceBracket(ctx, ex,
+		&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "0.5", ClientOrderID: tag.ClientOrderID()},
+		&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "105", ClosePosition: true},
+	)
+	if !errors.Is(err, ErrProtectionFailed) {
+		t.Fatalf("Expected ErrProtectionFailed, got %v", err)
+	}
+
+	if ex.flattens != 1 {
+		t.Errorf("Expected one flatten, got %d", ex.flattens)
+	}
+	positions, _ := pe.GetPositions(ctx)
+	if len(positions) != 1 || positions[0].PositionAmt != 1 {
+		t.Errorf("Expected the original 1 BTCUSDT to remain, got %+v", positions)
+	}
+}
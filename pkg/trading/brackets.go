@@ -0,0 +1,86 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LeverageBracket is one notional tier of a symbol's leverage brackets.
+// Larger positions fall into higher tiers with lower maximum leverage and a
+// higher maintenance margin rate.
+type LeverageBracket struct {
+	Bracket          int     `json:"bracket"`
+	InitialLeverage  int     `json:"initialLeverage"` // Maximum leverage in this tier
+	NotionalFloor    float64 `json:"notionalFloor"`
+	NotionalCap      float64 `json:"notionalCap"`
+	MaintMarginRatio float64 `json:"maintMarginRatio"`
+	Cum              float64 `json:"cum"` // Maintenance amount
+}
+
+// MaxNotional returns the largest position notional allowed at leverage, and
+// false when leverage exceeds every bracket
+func MaxNotional(brackets []LeverageBracket, leverage int) (float64, bool) {
+	maxNotional, found := 0.0, false
+	for _, bracket := range brackets {
+		if bracket.InitialLeverage >= leverage && bracket.NotionalCap > maxNotional {
+			maxNotional, found = bracket.NotionalCap, true
+		}
+	}
+	return maxNotional, found
+}
+
+// BracketFor returns the bracket a position of notional falls into
+func BracketFor(brackets []LeverageBracket, notional float64) (LeverageBracket, bool) {
+	for _, bracket := range brackets {
+		if notional >= bracket.NotionalFloor && notional < bracket.NotionalCap {
+			return bracket, true
+		}
+	}
+	return LeverageBracket{}, false
+}
+
+// bracketCache keeps the leverage brackets of every symbol, which change rarely
+type bracketCache struct {
+	mu       sync.Mutex
+	brackets map[string][]LeverageBracket
+	loadedAt time.Time
+}
+
+// GetLeverageBrackets returns the leverage brackets of symbol. All symbols
+// are downloaded in one request and cached for an hour.
+func (tc *TradingClient) GetLeverageBrackets(ctx context.Context, symbol string) ([]LeverageBracket, error) {
+	cache := tc.brackets
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.brackets == nil || time.Since(cache.loadedAt) > time.Hour {
+		result, err := tc.BinanceClient.NewGetLeverageBracketService().Do(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get leverage brackets: %w", err)
+		}
+
+		brackets := make(map[string][]LeverageBracket, len(result))
+		for _, item := range result {
+			for _, b := range item.Brackets {
+				brackets[item.Symbol] = append(brackets[item.Symbol], LeverageBracket{
+					Bracket:          b.Bracket,
+					InitialLeverage:  b.InitialLeverage,
+					NotionalFloor:    b.NotionalFloor,
+					NotionalCap:      b.NotionalCap,
+					MaintMarginRatio: b.MaintMarginRatio,
+					Cum:              b.Cum,
+				})
+			}
+		}
+		cache.brackets = brackets
+		cache.loadedAt = time.Now()
+	}
+
+	brackets, ok := cache.brackets[symbol]
+	if !ok {
+		return nil, fmt.Errorf("no leverage brackets for %s", symbol)
+	}
+	return brackets, nil
+}
@@ -0,0 +1,122 @@
+package trading
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Candle represents a single parsed kline bar
+type Candle struct {
+	OpenTime  int64   `json:"openTime"`
+	Open      float64 `json:"open"`
+	High      float64 `json:"high"`
+	Low       float64 `json:"low"`
+	Close     float64 `json:"close"`
+	Volume    float64 `json:"volume"`
+	CloseTime int64   `json:"closeTime"`
+}
+
+// ParseCandle converts a GetKlines row into a Candle
+func ParseCandle(row []interface{}) (Candle, error) {
+	if len(row) < 7 {
+		return Candle{}, fmt.Errorf("kline row has %d fields, expected 7", len(row))
+	}
+
+	openTime, ok := row[0].(int64)
+	if !ok {
+		return Candle{}, fmt.Errorf("invalid open time %v", row[0])
+	}
+	closeTime, ok := row[6].(int64)
+	if !ok {
+		return Candle{}, fmt.Errorf("invalid close time %v", row[6])
+	}
+
+	var values [5]float64
+	for i := range values {
+		str, ok := row[i+1].(string)
+		if !ok {
+			return Candle{}, fmt.Errorf("invalid kline field %d: %v", i+1, row[i+1])
+		}
+		values[i] = parseFloat(str)
+	}
+
+	return Candle{
+		OpenTime:  openTime,
+		Open:      values[0],
+		High:      values[1],
+		Low:       values[2],
+		Close:     values[3],
+		Volume:    values[4],
+		CloseTime: closeTime,
+	}, nil
+}
+
+// Row converts the candle back into the GetKlines row layout
+func (c Candle) Row() []interface{} {
+	return []interface{}{
+		c.OpenTime,
+		formatFloat(c.Open),
+		formatFloat(c.High),
+		formatFloat(c.Low),
+		formatFloat(c.Close),
+		formatFloat(c.Volume),
+		c.CloseTime,
+	}
+}
+
+// formatFloat formats a float without trailing zeros
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// IntervalDuration returns the length of a kline interval such as "1m", "4h" or "1w"
+func IntervalDuration(interval string) (time.Duration, error) {
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("invalid interval %q", interval)
+	}
+
+	count, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid interval %q", interval)
+	}
+
+	switch interval[len(interval)-1] {
+	case 'm':
+		return time.Duration(count) * time.Minute, nil
+	case 'h':
+		return time.Duration(count) * time.Hour, nil
+	case 'd':
+		return time.Duration(count) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(count) * 7 * 24 * time.Hour, nil
+	default:
+		// Monthly bars have no fixed length
+		return 0, fmt.Errorf("unsupported interval %q", interval)
+	}
+}
+
+// ATR returns the average true range over period candles with Wilder's
+// smoothing, or 0 when there are not enough candles
+func ATR(candles []Candle, period int) float64 {
+	if period <= 0 || len(candles) < period+1 {
+		return 0
+	}
+
+	trueRange := func(i int) float64 {
+		prevClose := candles[i-1].Close
+		return math.Max(candles[i].High-candles[i].Low, math.Max(math.Abs(candles[i].High-prevClose), math.Abs(candles[i].Low-prevClose)))
+	}
+
+	atr := 0.0
+	for i := 1; i <= period; i++ {
+		atr += trueRange(i)
+	}
+	atr /= float64(period)
+
+	for i := period + 1; i < len(candles); i++ {
+		atr = (atr*float64(period-1) + trueRange(i)) / float64(period)
+	}
+	return atr
+}
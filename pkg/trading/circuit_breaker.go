@@ -0,0 +1,342 @@
+package trading
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrTradingHalted is returned while the circuit breaker is tripped
+var ErrTradingHalted = errors.New("trading halted")
+
+// CircuitBreakerConfig configures the loss limits that halt new entries. Zero disables a limit.
+type CircuitBreakerConfig struct {
+	DailyLossPercent float64       // Loss since 00:00 UTC as a share of the equity at the start of the day
+	DailyLossLimit   float64       // Loss since 00:00 UTC in USDT
+	DrawdownPercent  float64       // Fall of equity from its peak within Window
+	Window           time.Duration // Rolling window the drawdown is measured over
+	Flatten          bool          // Close every position when tripped
+	StateFile        string        // Where the halt is kept until an operator resets it
+}
+
+// DefaultCircuitBreakerConfig halts after losing 5% in a UTC day or falling
+// 10% from the peak of the last 7 days, without closing positions
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		DailyLossPercent: 5,
+		DrawdownPercent:  10,
+		Window:           7 * 24 * time.Hour,
+		StateFile:        filepath.Join("logs", "circuit_breaker.json"),
+	}
+}
+
+// CircuitBreakerConfigFromEnv returns the default config overridden by
+// DAILY_LOSS_PERCENT, DAILY_LOSS_LIMIT, MAX_DRAWDOWN_PERCENT,
+// DRAWDOWN_WINDOW_HOURS, HALT_FLATTEN and CIRCUIT_BREAKER_STATE
+func CircuitBreakerConfigFromEnv() CircuitBreakerConfig {
+	cfg := DefaultCircuitBreakerConfig()
+	if percent, err := strconv.ParseFloat(os.Getenv("DAILY_LOSS_PERCENT"), 64); err == nil && percent >= 0 {
+		cfg.DailyLossPercent = percent
+	}
+	if limit, err := strconv.ParseFloat(os.Getenv("DAILY_LOSS_LIMIT"), 64); err == nil && limit >= 0 {
+		cfg.DailyLossLimit = limit
+	}
+	if percent, err := strconv.ParseFloat(os.Getenv("MAX_DRAWDOWN_PERCENT"), 64); err == nil && percent >= 0 {
+		cfg.DrawdownPercent = percent
+	}
+	if hours, err := strconv.Atoi(os.Getenv("DRAWDOWN_WINDOW_HOURS")); err == nil && hours > 0 {
+		cfg.Window = time.Duration(hours) * time.Hour
+	}
+	cfg.Flatten, _ = strconv.ParseBool(os.Getenv("HALT_FLATTEN"))
+	if path := os.Getenv("CIRCUIT_BREAKER_STATE"); path != "" {
+		cfg.StateFile = path
+	}
+	return cfg
+}
+
+// BreakerState is the persisted state of the circuit breaker
+type BreakerState struct {
+	Halted    bool      `json:"halted"`
+	Reason    string    `json:"reason,omitempty"`
+	HaltedAt  time.Time `json:"haltedAt,omitempty"`
+	Flattened bool      `json:"flattened,omitempty"` // Positions were closed when it tripped
+}
+
+// RiskSnapshot is the account PnL the limits are checked against
+type RiskSnapshot struct {
+	Equity         float64 // Wallet balance plus unrealized PnL
+	DayStartEquity float64
+	DailyPnL       float64 // Realized PnL, fees and funding since 00:00 UTC plus unrealized PnL
+	PeakEquity     float64 // Highest wallet balance within the window
+	Drawdown       float64 // Percent below the peak
+}
+
+// CircuitBreaker halts new entries once the daily loss or the drawdown over
+// a rolling window crosses its limit. The halt and its reason are saved to
+// StateFile and hold across restarts until Reset is called, by this process
+// or by another one, such as cmd/circuit-breaker, on the same file.
+type CircuitBreaker struct {
+	ex     Exchange
+	config CircuitBreakerConfig
+
+	mu    sync.Mutex
+	state BreakerState
+}
+
+// NewCircuitBreaker creates a circuit breaker for the account on ex, loading
+// any halt saved by a previous run
+func NewCircuitBreaker(ex Exchange, config CircuitBreakerConfig) (*CircuitBreaker, error) {
+	cb := &CircuitBreaker{ex: ex, config: config}
+
+	state, err := cb.load()
+	if err != nil {
+		return nil, err
+	}
+	cb.state = state
+	if cb.state.Halted {
+		log.Printf("🚨 Trading halted since %s: %s", cb.state.HaltedAt.Format(time.RFC3339), cb.state.Reason)
+	}
+	return cb, nil
+}
+
+// State returns the current state
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// Check returns ErrTradingHalted while halted, and otherwise measures the
+// account and trips the breaker if a limit is crossed. While halted, StateFile
+// is read again so a reset by the operator resumes trading.
+func (cb *CircuitBreaker) Check(ctx context.Context) error {
+	if state := cb.reload(); state.Halted {
+		return fmt.Errorf("%w since %s: %s", ErrTradingHalted, state.HaltedAt.Format(time.RFC3339), state.Reason)
+	}
+
+	snapshot, err := cb.Measure(ctx)
+	if err != nil {
+		return err
+	}
+	reason := cb.evaluate(snapshot)
+	if reason == "" {
+		return nil
+	}
+	if err := cb.Halt(ctx, reason); err != nil {
+		log.Printf("⚠️  Circuit breaker: %v", err)
+	}
+	return fmt.Errorf("%w: %s", ErrTradingHalted, reason)
+}
+
+// Measure returns the daily PnL and the drawdown from the balance and income history
+func (cb *CircuitBreaker) Measure(ctx context.Context) (*RiskSnapshot, error) {
+	balance, err := cb.ex.GetUSDTBalance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+	equity := balance.MarginBalance
+	unrealized := equity - balance.WalletBalance
+
+	now := time.Now().UTC()
+	dayStart := now.Truncate(24 * time.Hour)
+	windowStart := now.Add(-cb.config.Window)
+	since := dayStart
+	if windowStart.Before(since) {
+		since = windowStart
+	}
+
+	incomes, err := cb.ex.GetIncomeHistory(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(incomes, func(i, j int) bool { return incomes[i].Time.Before(incomes[j].Time) })
+
+	daily, window := 0.0, 0.0
+	for _, income := range incomes {
+		if !tradingIncome(income.Type) {
+			continue
+		}
+		if !income.Time.Before(dayStart) {
+			daily += income.Amount
+		}
+		if !income.Time.Before(windowStart) {
+			window += income.Amount
+		}
+	}
+
+	// Replay the window from the wallet balance it started at to find the peak
+	wallet := balance.WalletBalance - window
+	peak := wallet
+	for _, income := range incomes {
+		if tradingIncome(income.Type) && !income.Time.Before(windowStart) {
+			wallet += income.Amount
+			if wallet > peak {
+				peak = wallet
+			}
+		}
+	}
+
+	snapshot := &RiskSnapshot{
+		Equity:         equity,
+		DailyPnL:       daily + unrealized,
+		DayStartEquity: balance.WalletBalance - daily,
+		PeakEquity:     peak,
+	}
+	if peak > 0 && equity < peak {
+		snapshot.Drawdown = (peak - equity) / peak * 100
+	}
+	return snapshot, nil
+}
+
+// evaluate returns why snapshot crosses a limit, or "" when none is crossed
+func (cb *CircuitBreaker) evaluate(snapshot *RiskSnapshot) string {
+	loss := -snapshot.DailyPnL
+	if cb.config.DailyLossPercent > 0 && loss > 0 && snapshot.DayStartEquity > 0 {
+		if percent := loss / snapshot.DayStartEquity * 100; percent >= cb.config.DailyLossPercent {
+			return fmt.Sprintf("daily loss %.2f USDT (%.2f%%) reached the %.2f%% limit", loss, percent, cb.config.DailyLossPercent)
+		}
+	}
+	if cb.config.DailyLossLimit > 0 && loss >= cb.config.DailyLossLimit {
+		return fmt.Sprintf("daily loss %.2f USDT reached the %.2f USDT limit", loss, cb.config.DailyLossLimit)
+	}
+	if cb.config.DrawdownPercent > 0 && snapshot.Drawdown >= cb.config.DrawdownPercent {
+		return fmt.Sprintf("drawdown %.2f%% from the %.2f USDT peak of the last %s reached the %.2f%% limit",
+			snapshot.Drawdown, snapshot.PeakEquity, cb.config.Window, cb.config.DrawdownPercent)
+	}
+	return ""
+}
+
+// Halt stops new entries for reason and saves it. Resting entry orders are
+// cancelled, and every position is closed when Flatten is set.
+func (cb *CircuitBreaker) Halt(ctx context.Context, reason string) error {
+	cb.mu.Lock()
+	cb.state = BreakerState{Halted: true, Reason: reason, HaltedAt: time.Now().UTC()}
+	err := cb.save()
+	cb.mu.Unlock()
+
+	log.Printf("🚨 Circuit breaker tripped, halting new entries: %s", reason)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if orders, err := cb.ex.GetOpenOrders(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to get open orders: %w", err))
+	} else {
+		for _, order := range orders {
+			if ClassifyOrder(order) != RoleEntry {
+				continue
+			}
+			if err := cb.ex.CancelOrder(ctx, order.Symbol, order.OrderID); err != nil {
+				errs = append(errs, fmt.Errorf("failed to cancel entry %d for %s: %w", order.OrderID, order.Symbol, err))
+			}
+		}
+	}
+
+	if cb.config.Flatten {
+		errs = append(errs, cb.flatten(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// flatten closes every open position and records it in the state
+func (cb *CircuitBreaker) flatten(ctx context.Context) error {
+	positions, err := cb.ex.GetPositions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	var errs []error
+	closed := make(map[string]bool)
+	for _, position := range positions {
+		if position.PositionAmt == 0 || closed[position.Symbol] {
+			continue
+		}
+		closed[position.Symbol] = true
+		if _, err := ClosePositionFor(ctx, cb.ex, position.Symbol); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %s: %w", position.Symbol, err))
+		}
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.state.Flattened = len(errs) == 0
+	return errors.Join(append(errs, cb.save())...)
+}
+
+// Reset clears a halt so trading resumes; it is only called by an operator
+func (cb *CircuitBreaker) Reset() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state.Halted {
+		log.Printf("🔧 Circuit breaker reset, was halted since %s: %s", cb.state.HaltedAt.Format(time.RFC3339), cb.state.Reason)
+	}
+	cb.state = BreakerState{}
+	return cb.save()
+}
+
+// reload returns the current state, taking a reset saved to StateFile by
+// another process while halted
+func (cb *CircuitBreaker) reload() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if !cb.state.Halted {
+		return cb.state
+	}
+
+	state, err := cb.load()
+	if err != nil {
+		log.Printf("⚠️  Circuit breaker: %v", err)
+		return cb.state
+	}
+	if !state.Halted {
+		log.Printf("🔧 Circuit breaker reset by the operator, was halted since %s: %s", cb.state.HaltedAt.Format(time.RFC3339), cb.state.Reason)
+		cb.state = state
+	}
+	return cb.state
+}
+
+// load reads the state saved to StateFile; a missing file is not halted
+func (cb *CircuitBreaker) load() (BreakerState, error) {
+	var state BreakerState
+	data, err := os.ReadFile(cb.config.StateFile)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read circuit breaker state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("failed to parse circuit breaker state %s: %w", cb.config.StateFile, err)
+	}
+	return state, nil
+}
+
+// save writes the state to StateFile, replacing it atomically
+func (cb *CircuitBreaker) save() error {
+	data, err := json.MarshalIndent(cb.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode circuit breaker state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cb.config.StateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create circuit breaker state directory: %w", err)
+	}
+
+	tmp := cb.config.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write circuit breaker state: %w", err)
+	}
+	if err := os.Rename(tmp, cb.config.StateFile); err != nil {
+		return fmt.Errorf("failed to save circuit breaker state: %w", err)
+	}
+	return nil
+}
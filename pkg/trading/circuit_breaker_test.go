@@ -0,0 +1,196 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// incomeExchange is a paper exchange reporting a fixed balance and income history
+type incomeExchange struct {
+	*PaperExchange
+	balance AccountBalance
+	incomes []Income
+}
+
+func (ie *incomeExchange) GetUSDTBalance(ctx context.Context) (*AccountBalance, error) {
+	balance := ie.balance
+	return &balance, nil
+}
+
+func (ie *incomeExchange) GetIncomeHistory(ctx context.Context, since time.Time) ([]Income, error) {
+	return ie.incomes, nil
+}
+
+// TestCircuitBreakerLimits tests the daily loss and drawdown limits
+func TestCircuitBreakerLimits(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		config  CircuitBreakerConfig
+		balance AccountBalance
+		incomes []Income
+		reason  string // Start of the halt reason, "" when trading continues
+	}{
+		{
+			"daily loss with unrealized",
+			CircuitBreakerConfig{DailyLossPercent: 5, Window: 24 * time.Hour},
+			AccountBalance{WalletBalance: 950, MarginBalance: 940},
+			[]Income{{Type: IncomeRealizedPnL, Amount: -40, Time: now}, {Type: "TRANSFER", Amount: -500, Time: now}},
+			"daily loss 50.00 USDT",
+		},
+		{
+			"daily loss within limit",
+			CircuitBreakerConfig{DailyLossPercent: 5, Window: 24 * time.Hour},
+			AccountBalance{WalletBalance: 970, MarginBalance: 970},
+			[]Income{{Type: IncomeRealizedPnL, Amount: -25, Time: now}, {Type: IncomeCommission, Amount: -5, Time: now}},
+			"",
+		},
+		{
+			"daily loss in USDT",
+			CircuitBreakerConfig{DailyLossLimit: 20, Window: 24 * time.Hour},
+			AccountBalance{WalletBalance: 970, MarginBalance: 970},
+			[]Income{{Type: IncomeRealizedPnL, Amount: -25, Time: now}, {Type: IncomeCommission, Amount: -5, Time: now}},
+			"daily loss 30.00 USDT reached the 20.00 USDT limit",
+		},
+		{
+			"drawdown from the window peak",
+			CircuitBreakerConfig{DrawdownPercent: 10, Window: 7 * 24 * time.Hour},
+			AccountBalance{WalletBalance: 900, MarginBalance: 900},
+			[]Income{
+				{Type: IncomeRealizedPnL, Amount: 100, Time: now.Add(-72 * time.Hour)},
+				{Type: IncomeRealizedPnL, Amount: -200, Time: now.Add(-48 * time.Hour)},
+			},
+			"drawdown 18.18% from the 1100.00 USDT peak",
+		},
+		{
+			"drawdown before the window",
+			CircuitBreakerConfig{DrawdownPercent: 10, Window: 24 * time.Hour},
+			AccountBalance{WalletBalance: 900, MarginBalance: 900},
+			[]Income{
+				{Type: IncomeRealizedPnL, Amount: 100, Time: now.Add(-72 * time.Hour)},
+				{Type: IncomeRealizedPnL, Amount: -200, Time: now.Add(-48 * time.Hour)},
+			},
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		tt.config.StateFile = filepath.Join(t.TempDir(), "state.json")
+		ex := &incomeExchange{PaperExchange: newTestPaperExchange(100), balance: tt.balance, incomes: tt.incomes}
+		breaker, err := NewCircuitBreaker(ex, tt.config)
+		if err != nil {
+			t.Fatalf("%s: NewCircuitBreaker failed: %v", tt.name, err)
+		}
+
+		err = breaker.Check(context.Background())
+		if tt.reason == "" {
+			if err != nil {
+				t.Errorf("%s: expected trading to continue, got %v", tt.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrTradingHalted) || !strings.HasPrefix(breaker.State().Reason, tt.reason) {
+			t.Errorf("%s: expected a halt for %q, got %v", tt.name, tt.reason, err)
+		}
+	}
+}
+
+// TestCircuitBreakerHalt tests that a halt cancels entries, flattens, survives
+// a restart and lasts until reset
+func TestCircuitBreakerHalt(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinPrice: 0.1, MaxPrice: 1000000, TickSize: 0.1, MinQty: 0.001, MaxQty: 1000, StepSize: 0.001}}
+	ctx := context.Background()
+
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"}); err != nil {
+		t.Fatalf("Entry failed: %v", err)
+	}
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Quantity: "1", Price: "90", TimeInForce: "GTC"}); err != nil {
+		t.Fatalf("Limit entry failed: %v", err)
+	}
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "80", ClosePosition: true}); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	ex := &incomeExchange{
+		PaperExchange: pe,
+		balance:       AccountBalance{WalletBalance: 900, MarginBalance: 900},
+		incomes:       []Income{{Type: IncomeRealizedPnL, Amount: -100, Time: time.Now()}},
+	}
+	config := CircuitBreakerConfig{DailyLossPercent: 5, Window: 24 * time.Hour, Flatten: true, StateFile: filepath.Join(t.TempDir(), "breaker", "state.json")}
+	breaker, err := NewCircuitBreaker(ex, config)
+	if err != nil {
+		t.Fatalf("NewCircuitBreaker failed: %v", err)
+	}
+	if err := breaker.Check(ctx); !errors.Is(err, ErrTradingHalted) {
+		t.Fatalf("Expected a halt, got %v", err)
+	}
+
+	if positions, _ := pe.GetPositions(ctx); len(positions) != 0 {
+		t.Errorf("Expected the position closed, got %+v", positions)
+	}
+	orders, _ := pe.GetOpenOrders(ctx)
+	for _, order := range orders {
+		if ClassifyOrder(order) == RoleEntry {
+			t.Errorf("Expected the limit entry cancelled, got %+v", order)
+		}
+	}
+
+	// The halt holds after a restart even once the loss is gone
+	ex.incomes = nil
+	restarted, err := NewCircuitBreaker(ex, config)
+	if err != nil {
+		t.Fatalf("NewCircuitBreaker failed: %v", err)
+	}
+	if state := restarted.State(); !state.Halted || !state.Flattened || !strings.HasPrefix(state.Reason, "daily loss") {
+		t.Errorf("Expected the saved halt, got %+v", state)
+	}
+	if err := restarted.Check(ctx); !errors.Is(err, ErrTradingHalted) {
+		t.Errorf("Expected trading to stay halted, got %v", err)
+	}
+
+	if err := restarted.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if err := restarted.Check(ctx); err != nil {
+		t.Errorf("Expected trading to resume after a reset, got %v", err)
+	}
+}
+
+// TestCircuitBreakerResetByOperator tests that a running breaker resumes once
+// another process resets the state file
+func TestCircuitBreakerResetByOperator(t *testing.T) {
+	ctx := context.Background()
+	ex := &incomeExchange{PaperExchange: newTestPaperExchange(100), balance: AccountBalance{WalletBalance: 1000, MarginBalance: 1000}}
+	config := CircuitBreakerConfig{DailyLossPercent: 5, Window: 24 * time.Hour, StateFile: filepath.Join(t.TempDir(), "state.json")}
+
+	running, err := NewCircuitBreaker(ex, config)
+	if err != nil {
+		t.Fatalf("NewCircuitBreaker failed: %v", err)
+	}
+	if err := running.Halt(ctx, "manual halt"); err != nil {
+		t.Fatalf("Halt failed: %v", err)
+	}
+	if err := running.Check(ctx); !errors.Is(err, ErrTradingHalted) {
+		t.Fatalf("Expected a halt, got %v", err)
+	}
+
+	operator, err := NewCircuitBreaker(ex, config)
+	if err != nil {
+		t.Fatalf("NewCircuitBreaker failed: %v", err)
+	}
+	if err := operator.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+
+	if err := running.Check(ctx); err != nil {
+		t.Errorf("Expected trading to resume after the operator reset, got %v", err)
+	}
+	if state := running.State(); state.Halted {
+		t.Errorf("Expected the reset state, got %+v", state)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/adshao/go-binance/v2/futures"
 	"github.com/joho/godotenv"
@@ -17,6 +19,15 @@ import (
 type TradingClient struct {
 	BinanceClient *futures.Client
 	UseTestnet    bool
+	Retry         RetryConfig
+	symbols       *SymbolRegistry
+	brackets      *bracketCache
+
+	modeMu       sync.Mutex
+	positionMode PositionMode // Cached by GetPositionMode
+
+	leverageMu sync.Mutex
+	leverages  map[string]cachedLeverage // By symbol; loaded by GetLeverage, kept current by ChangeLeverage
 }
 
 // NewTradingClient creates a new trading client
@@ -39,11 +50,21 @@ func NewTradingClient() (*TradingClient, error) {
 	// Initialize Binance Futures client
 	futures.UseTestnet = useTestnet
 	binanceClient := futures.NewClient(apiKey, secretKey)
+	binanceClient.HTTPClient = &http.Client{Transport: sharedRateLimiter.Transport(http.DefaultTransport)}
 
-	return &TradingClient{
+	tc := &TradingClient{
 		BinanceClient: binanceClient,
 		UseTestnet:    useTestnet,
-	}, nil
+		Retry:         DefaultRetryConfig(),
+		brackets:      &bracketCache{},
+	}
+	tc.symbols = NewSymbolRegistry(tc)
+	return tc, nil
+}
+
+// Symbols returns the cached symbol filters used to round orders
+func (tc *TradingClient) Symbols() *SymbolRegistry {
+	return tc.symbols
 }
 
 // Client is an alias for TradingClient for backward compatibility
@@ -367,23 +388,30 @@ func (tc *TradingClient) DisplayUSDTPairs(ctx context.Context, showAll bool) err
 
 // OrderRequest represents a trading order request
 type OrderRequest struct {
-	Symbol        string
-	Side          string // BUY or SELL
-	Type          string // MARKET, LIMIT, STOP_MARKET, TAKE_PROFIT_MARKET
-	Quantity      string
-	Price         string
-	StopPrice     string
-	ReduceOnly    bool
-	ClosePosition bool
+	Symbol        string `json:"symbol"`
+	Side          string `json:"side"` // BUY or SELL
+	Type          string `json:"type"` // MARKET, LIMIT, STOP_MARKET, TAKE_PROFIT_MARKET, TRAILING_STOP_MARKET
+	Quantity      string `json:"quantity,omitempty"`
+	Price         string `json:"price,omitempty"`
+	StopPrice     string `json:"stopPrice,omitempty"`
+	TimeInForce   string `json:"timeInForce,omitempty"` // GTC, IOC, FOK, GTX (LIMIT orders only)
+	ReduceOnly    bool   `json:"reduceOnly,omitempty"`
+	ClosePosition bool   `json:"closePosition,omitempty"`
+	ClientOrderID string `json:"clientOrderId,omitempty"` // newClientOrderId, see ClientOrderTag
+	PositionSide  string `json:"positionSide,omitempty"`  // LONG or SHORT in hedge mode; filled in from Side by CreateOrder when empty
+
+	// TRAILING_STOP_MARKET only
+	CallbackRate    string `json:"callbackRate,omitempty"`    // Retracement from the best price that triggers the order, in percent (0.1 to 10)
+	ActivationPrice string `json:"activationPrice,omitempty"` // Price at which trailing starts; the market price when empty
 }
 
 // OrderResponse represents a trading order response
 type OrderResponse struct {
-	OrderID     string
-	Symbol      string
-	Status      string
-	ExecutedQty float64
-	AvgPrice    float64
+	OrderID     string  `json:"orderId"`
+	Symbol      string  `json:"symbol"`
+	Status      string  `json:"status"`
+	ExecutedQty float64 `json:"executedQty"`
+	AvgPrice    float64 `json:"avgPrice"`
 }
 
 // Position represents a trading position
@@ -395,6 +423,7 @@ type Position struct {
 	UnrealizedProfit float64 `json:"unrealizedProfit"`
 	Leverage         int     `json:"leverage"`
 	Side             string  `json:"side"`
+	PositionSide     string  `json:"positionSide"` // BOTH in one-way mode, LONG or SHORT in hedge mode
 }
 
 // Order represents an order
@@ -409,44 +438,102 @@ type Order struct {
 	StopPrice     float64 `json:"stopPrice"`
 	ReduceOnly    bool    `json:"reduceOnly"`
 	ClosePosition bool    `json:"closePosition"`
+	ClientOrderID string  `json:"clientOrderId"`
+	ExecutedQty   float64 `json:"executedQty"`
+	AvgPrice      float64 `json:"avgPrice"` // Average fill price, 0 until filled
+	PositionSide  string  `json:"positionSide"` // BOTH in one-way mode, LONG or SHORT in hedge mode
+
+	CallbackRate    float64 `json:"priceRate"`     // TRAILING_STOP_MARKET callback in percent
+	ActivationPrice float64 `json:"activatePrice"` // TRAILING_STOP_MARKET activation price
+}
+
+// leverageCacheTTL is how long cached leverage is trusted; it can also be changed outside the bot
+const leverageCacheTTL = time.Hour
+
+// cachedLeverage is the leverage of a symbol and when it was learned
+type cachedLeverage struct {
+	leverage int
+	at       time.Time
 }
 
-// GetLeverage gets current leverage for a symbol
+// fresh reports whether the cached leverage can still be trusted
+func (c cachedLeverage) fresh() bool {
+	return time.Since(c.at) <= leverageCacheTTL
+}
+
+// GetLeverage gets current leverage for a symbol. The leverage of every
+// symbol is loaded with one position risk request and cached.
 func (tc *TradingClient) GetLeverage(symbol string) (int, error) {
 	ctx := context.Background()
 
+	tc.leverageMu.Lock()
+	defer tc.leverageMu.Unlock()
+
+	if cached, ok := tc.leverages[symbol]; ok && cached.fresh() {
+		return cached.leverage, nil
+	}
+
 	// Get position information
-	positions, err := tc.BinanceClient.NewGetPositionRiskService().Do(ctx)
+	var positions []*futures.PositionRisk
+	err := tc.retry(ctx, func() error {
+		var err error
+		positions, err = tc.BinanceClient.NewGetPositionRiskService().Do(ctx)
+		return err
+	}, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get position info: %w", err)
 	}
 
+	if tc.leverages == nil {
+		tc.leverages = make(map[string]cachedLeverage, len(positions))
+	}
+	now := time.Now()
 	for _, pos := range positions {
-		if pos.Symbol == symbol {
-			leverage, err := strconv.Atoi(pos.Leverage)
-			if err != nil {
-				return 0, fmt.Errorf("failed to parse leverage: %w", err)
-			}
-			return leverage, nil
+		leverage, err := strconv.Atoi(pos.Leverage)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse leverage: %w", err)
 		}
+		tc.leverages[pos.Symbol] = cachedLeverage{leverage: leverage, at: now}
 	}
 
-	return 0, fmt.Errorf("symbol %s not found", symbol)
+	cached, ok := tc.leverages[symbol]
+	if !ok {
+		return 0, fmt.Errorf("symbol %s not found", symbol)
+	}
+	return cached.leverage, nil
 }
 
-// ChangeLeverage changes leverage for a symbol
+// ChangeLeverage changes leverage for a symbol; nothing is sent when the
+// cached leverage already matches
 func (tc *TradingClient) ChangeLeverage(symbol string, leverage int) error {
 	ctx := context.Background()
 
-	_, err := tc.BinanceClient.NewChangeLeverageService().
-		Symbol(symbol).
-		Leverage(leverage).
-		Do(ctx)
+	tc.leverageMu.Lock()
+	cached, ok := tc.leverages[symbol]
+	tc.leverageMu.Unlock()
+	if ok && cached.fresh() && cached.leverage == leverage {
+		return nil
+	}
+
+	err := tc.retry(ctx, func() error {
+		_, err := tc.BinanceClient.NewChangeLeverageService().
+			Symbol(symbol).
+			Leverage(leverage).
+			Do(ctx)
+		return err
+	}, nil)
 
 	if err != nil {
 		return fmt.Errorf("failed to change leverage: %w", err)
 	}
 
+	tc.leverageMu.Lock()
+	if tc.leverages == nil {
+		tc.leverages = make(map[string]cachedLeverage)
+	}
+	tc.leverages[symbol] = cachedLeverage{leverage: leverage, at: time.Now()}
+	tc.leverageMu.Unlock()
+
 	return nil
 }
 
@@ -493,27 +580,75 @@ func (tc *TradingClient) ChangeMarginMode(symbol string, marginMode string) erro
 		return fmt.Errorf("invalid margin mode: %s (must be ISOLATED or CROSSED)", marginMode)
 	}
 
-	err := tc.BinanceClient.NewChangeMarginTypeService().
-		Symbol(symbol).
-		MarginType(mode).
-		Do(ctx)
+	// Already being in the requested mode (-4046) is not an error
+	err := tc.retry(ctx, func() error {
+		return tc.BinanceClient.NewChangeMarginTypeService().
+			Symbol(symbol).
+			MarginType(mode).
+			Do(ctx)
+	}, nil)
 
 	if err != nil {
-		// Check for specific Binance error messages
-		if strings.Contains(err.Error(), "No need to change margin type") {
-			// Already in the requested margin mode, so this is not an error
-			return nil
-		}
 		return fmt.Errorf("failed to change margin mode: %w", err)
 	}
 
 	return nil
 }
 
-// CreateOrder creates a new trading order
+// CreateOrder creates a new trading order. Retryable errors are retried by
+// their class policy; precision errors are re-rounded once with fresh filters.
 func (tc *TradingClient) CreateOrder(order *OrderRequest) (*OrderResponse, error) {
 	ctx := context.Background()
+	request := *order
+
+	mode, err := tc.GetPositionMode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+	applyPositionMode(&request, mode)
+
+	var result *futures.CreateOrderResponse
+	err = tc.retry(ctx, func() error {
+		var err error
+		result, err = tc.newCreateOrderService(&request).Do(ctx)
+		if err != nil && ClassifyError(wrapAPIError(err)) == ErrorClassPositionSide {
+			// The mode was changed since it was cached; send the order for the new one
+			tc.resetPositionMode()
+			if mode, modeErr := tc.GetPositionMode(ctx); modeErr == nil {
+				request = *order
+				applyPositionMode(&request, mode)
+			}
+		}
+		return err
+	}, func() error {
+		return tc.reroundOrder(ctx, &request)
+	})
+	if err != nil && request.ClientOrderID != "" && ClassifyError(err) == ErrorClassUnknownStatus {
+		// The order may have reached the exchange; its client order ID tells
+		if existing, lookupErr := tc.GetOrderByClientID(ctx, request.Symbol, request.ClientOrderID); lookupErr == nil {
+			log.Printf("♻️  Order %s was placed despite %v", request.ClientOrderID, err)
+			return &OrderResponse{
+				OrderID: fmt.Sprintf("%d", existing.OrderID),
+				Symbol:  existing.Symbol,
+				Status:  existing.Status,
+			}, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
 
+	return &OrderResponse{
+		OrderID:     fmt.Sprintf("%d", result.OrderID),
+		Symbol:      result.Symbol,
+		Status:      string(result.Status),
+		ExecutedQty: parseFloat(result.ExecutedQuantity),
+		AvgPrice:    parseFloat(result.AvgPrice),
+	}, nil
+}
+
+// newCreateOrderService builds the Binance request for order
+func (tc *TradingClient) newCreateOrderService(order *OrderRequest) *futures.CreateOrderService {
 	service := tc.BinanceClient.NewCreateOrderService().
 		Symbol(order.Symbol).
 		Side(futures.SideType(order.Side)).
@@ -534,6 +669,13 @@ func (tc *TradingClient) CreateOrder(order *OrderRequest) (*OrderResponse, error
 		service = service.StopPrice(order.StopPrice)
 	}
 
+	// Set time in force for limit orders
+	if order.TimeInForce != "" {
+		service = service.TimeInForce(futures.TimeInForceType(order.TimeInForce))
+	} else if order.Type == "LIMIT" {
+		service = service.TimeInForce(futures.TimeInForceTypeGTC)
+	}
+
 	// Set reduce only flag
 	if order.ReduceOnly {
 		service = service.ReduceOnly(true)
@@ -544,16 +686,51 @@ func (tc *TradingClient) CreateOrder(order *OrderRequest) (*OrderResponse, error
 		service = service.ClosePosition(true)
 	}
 
-	result, err := service.Do(ctx)
+	// Name the position in hedge mode
+	if order.PositionSide != "" {
+		service = service.PositionSide(futures.PositionSideType(order.PositionSide))
+	}
+
+	// Set trailing stop parameters
+	if order.CallbackRate != "" {
+		service = service.CallbackRate(order.CallbackRate)
+	}
+	if order.ActivationPrice != "" {
+		service = service.ActivationPrice(order.ActivationPrice)
+	}
+
+	// Tag the order so it can be found again after a restart
+	if order.ClientOrderID != "" {
+		service = service.NewClientOrderID(order.ClientOrderID)
+	}
+
+	return service
+}
+
+// reroundOrder reloads the symbol filters and rounds the order's quantity and prices again
+func (tc *TradingClient) reroundOrder(ctx context.Context, order *OrderRequest) error {
+	if err := tc.symbols.Refresh(ctx); err != nil {
+		return err
+	}
+	filters, err := tc.symbols.Get(ctx, order.Symbol)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
+		return err
 	}
 
-	return &OrderResponse{
-		OrderID: fmt.Sprintf("%d", result.OrderID),
-		Symbol:  result.Symbol,
-		Status:  string(result.Status),
-	}, nil
+	if order.Quantity != "" {
+		order.Quantity = filters.FormatQuantity(parseFloat(order.Quantity), order.Type)
+	}
+	if order.Price != "" {
+		order.Price = filters.FormatPrice(parseFloat(order.Price))
+	}
+	if order.StopPrice != "" {
+		order.StopPrice = filters.FormatPrice(parseFloat(order.StopPrice))
+	}
+	if order.ActivationPrice != "" {
+		order.ActivationPrice = filters.FormatPrice(parseFloat(order.ActivationPrice))
+	}
+	log.Printf("🔧 Re-rounded %s order for %s: quantity %s, price %s, stop %s", order.Type, order.Symbol, order.Quantity, order.Price, order.StopPrice)
+	return nil
 }
 
 // AccountInfo represents account information
@@ -613,6 +790,24 @@ func (tc *TradingClient) GetTicker(symbol string) (*TickerPrice, error) {
 	}, nil
 }
 
+// GetMarkPrice gets the mark price, which Binance checks PERCENT_PRICE against
+func (tc *TradingClient) GetMarkPrice(ctx context.Context, symbol string) (float64, error) {
+	var indexes []*futures.PremiumIndex
+	err := tc.retry(ctx, func() error {
+		var err error
+		indexes, err = tc.BinanceClient.NewPremiumIndexService().Symbol(symbol).Do(ctx)
+		return err
+	}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get mark price: %w", err)
+	}
+
+	if len(indexes) == 0 {
+		return 0, fmt.Errorf("no mark price for symbol %s", symbol)
+	}
+	return strconv.ParseFloat(indexes[0].MarkPrice, 64)
+}
+
 // TickerPrice represents ticker price information
 type TickerPrice struct {
 	Symbol string
@@ -670,19 +865,51 @@ func (tc *TradingClient) GetKlines(symbol string, interval string, limit int) ([
 	return result, nil
 }
 
+// GetKlinesRange gets up to limit klines opening at or after startTime (milliseconds)
+func (tc *TradingClient) GetKlinesRange(symbol string, interval string, startTime int64, limit int) ([][]interface{}, error) {
+	ctx := context.Background()
+
+	klines, err := tc.BinanceClient.NewKlinesService().
+		Symbol(symbol).
+		Interval(interval).
+		StartTime(startTime).
+		Limit(limit).
+		Do(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get klines: %w", err)
+	}
+
+	var result [][]interface{}
+	for _, kline := range klines {
+		result = append(result, []interface{}{
+			kline.OpenTime,
+			kline.Open,
+			kline.High,
+			kline.Low,
+			kline.Close,
+			kline.Volume,
+			kline.CloseTime,
+		})
+	}
+
+	return result, nil
+}
+
 // PlaceOrder places a market order
 func (tc *TradingClient) PlaceOrder(ctx context.Context, symbol, side, orderType string, quantity, price float64) (*OrderResponse, error) {
 	// Get symbol info to determine proper precision
-	quantityStr, err := tc.FormatQuantity(ctx, symbol, quantity)
+	quantityStr, err := tc.symbols.FormatQuantity(ctx, symbol, quantity, orderType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to format quantity: %w", err)
 	}
 
-	service := tc.BinanceClient.NewCreateOrderService().
-		Symbol(symbol).
-		Side(futures.SideType(side)).
-		Type(futures.OrderType(orderType)).
-		Quantity(quantityStr)
+	order := &OrderRequest{
+		Symbol:   symbol,
+		Side:     side,
+		Type:     orderType,
+		Quantity: quantityStr,
+	}
 
 	// Add price for limit orders
 	if orderType == "LIMIT" && price > 0 {
@@ -690,25 +917,20 @@ func (tc *TradingClient) PlaceOrder(ctx context.Context, symbol, side, orderType
 		if err != nil {
 			return nil, fmt.Errorf("failed to format price: %w", err)
 		}
-		service = service.Price(priceStr)
+		order.Price = priceStr
 	}
 
-	result, err := service.Do(ctx)
+	result, err := tc.CreateOrder(order)
 	if err != nil {
 		return nil, fmt.Errorf("failed to place order: %w", err)
 	}
 
-	return &OrderResponse{
-		OrderID:     fmt.Sprintf("%d", result.OrderID),
-		Status:      string(result.Status),
-		ExecutedQty: parseFloat(result.ExecutedQuantity),
-		AvgPrice:    parseFloat(result.AvgPrice),
-	}, nil
+	return result, nil
 }
 
 // PlaceStopOrder places a stop loss order
 func (tc *TradingClient) PlaceStopOrder(ctx context.Context, symbol, side string, quantity, stopPrice float64) (*OrderResponse, error) {
-	quantityStr, err := tc.FormatQuantity(ctx, symbol, quantity)
+	quantityStr, err := tc.symbols.FormatQuantity(ctx, symbol, quantity, "STOP_MARKET")
 	if err != nil {
 		return nil, fmt.Errorf("failed to format quantity: %w", err)
 	}
@@ -718,30 +940,24 @@ func (tc *TradingClient) PlaceStopOrder(ctx context.Context, symbol, side string
 		return nil, fmt.Errorf("failed to format stop price: %w", err)
 	}
 
-	service := tc.BinanceClient.NewCreateOrderService().
-		Symbol(symbol).
-		Side(futures.SideType(side)).
-		Type(futures.OrderTypeStopMarket).
-		Quantity(quantityStr).
-		StopPrice(stopPriceStr).
-		ReduceOnly(true)
-
-	result, err := service.Do(ctx)
+	result, err := tc.CreateOrder(&OrderRequest{
+		Symbol:     symbol,
+		Side:       side,
+		Type:       "STOP_MARKET",
+		Quantity:   quantityStr,
+		StopPrice:  stopPriceStr,
+		ReduceOnly: true,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to place stop order: %w", err)
 	}
 
-	return &OrderResponse{
-		OrderID:     fmt.Sprintf("%d", result.OrderID),
-		Status:      string(result.Status),
-		ExecutedQty: parseFloat(result.ExecutedQuantity),
-		AvgPrice:    parseFloat(result.AvgPrice),
-	}, nil
+	return result, nil
 }
 
 // PlaceTakeProfitOrder places a take profit order
 func (tc *TradingClient) PlaceTakeProfitOrder(ctx context.Context, symbol, side string, quantity, takeProfitPrice float64) (*OrderResponse, error) {
-	quantityStr, err := tc.FormatQuantity(ctx, symbol, quantity)
+	quantityStr, err := tc.symbols.FormatQuantity(ctx, symbol, quantity, "TAKE_PROFIT_MARKET")
 	if err != nil {
 		return nil, fmt.Errorf("failed to format quantity: %w", err)
 	}
@@ -751,41 +967,24 @@ func (tc *TradingClient) PlaceTakeProfitOrder(ctx context.Context, symbol, side
 		return nil, fmt.Errorf("failed to format take profit price: %w", err)
 	}
 
-	service := tc.BinanceClient.NewCreateOrderService().
-		Symbol(symbol).
-		Side(futures.SideType(side)).
-		Type(futures.OrderTypeTakeProfitMarket).
-		Quantity(quantityStr).
-		StopPrice(takeProfitPriceStr).
-		ReduceOnly(true)
-
-	result, err := service.Do(ctx)
+	result, err := tc.CreateOrder(&OrderRequest{
+		Symbol:     symbol,
+		Side:       side,
+		Type:       "TAKE_PROFIT_MARKET",
+		Quantity:   quantityStr,
+		StopPrice:  takeProfitPriceStr,
+		ReduceOnly: true,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to place take profit order: %w", err)
 	}
 
-	return &OrderResponse{
-		OrderID:     fmt.Sprintf("%d", result.OrderID),
-		Status:      string(result.Status),
-		ExecutedQty: parseFloat(result.ExecutedQuantity),
-		AvgPrice:    parseFloat(result.AvgPrice),
-	}, nil
+	return result, nil
 }
 
-// SetLeverage sets leverage for a symbol
+// SetLeverage sets leverage for a symbol, keeping the cached leverage current
 func (tc *TradingClient) SetLeverage(symbol string, leverage int) error {
-	ctx := context.Background()
-
-	_, err := tc.BinanceClient.NewChangeLeverageService().
-		Symbol(symbol).
-		Leverage(leverage).
-		Do(ctx)
-
-	if err != nil {
-		return fmt.Errorf("failed to set leverage: %w", err)
-	}
-
-	return nil
+	return tc.ChangeLeverage(symbol, leverage)
 }
 
 // GetPositions retrieves all current positions
@@ -819,6 +1018,7 @@ func (tc *TradingClient) GetPositions(ctx context.Context) ([]Position, error) {
 				UnrealizedProfit: unrealizedProfit,
 				Leverage:         leverage,
 				Side:             side,
+				PositionSide:     pos.PositionSide,
 			})
 		}
 	}
@@ -840,240 +1040,82 @@ func (tc *TradingClient) GetOpenOrders(ctx context.Context) ([]Order, error) {
 		stopPrice, _ := strconv.ParseFloat(order.StopPrice, 64)
 
 		result = append(result, Order{
-			OrderID:       order.OrderID,
-			Symbol:        order.Symbol,
-			Status:        string(order.Status),
-			Side:          string(order.Side),
-			Type:          string(order.Type),
-			OrigQty:       origQty,
-			Price:         price,
-			StopPrice:     stopPrice,
-			ReduceOnly:    order.ReduceOnly,
-			ClosePosition: order.ClosePosition,
+			OrderID:         order.OrderID,
+			Symbol:          order.Symbol,
+			Status:          string(order.Status),
+			Side:            string(order.Side),
+			Type:            string(order.Type),
+			OrigQty:         origQty,
+			Price:           price,
+			StopPrice:       stopPrice,
+			ReduceOnly:      order.ReduceOnly,
+			ClosePosition:   order.ClosePosition,
+			ClientOrderID:   order.ClientOrderID,
+			ExecutedQty:     parseFloat(order.ExecutedQuantity),
+			AvgPrice:        parseFloat(order.AvgPrice),
+			PositionSide:    string(order.PositionSide),
+			CallbackRate:    parseFloat(order.PriceRate),
+			ActivationPrice: parseFloat(order.ActivatePrice),
 		})
 	}
 
 	return result, nil
 }
 
-// CancelOrder cancels an order by order ID
-func (tc *TradingClient) CancelOrder(ctx context.Context, symbol string, orderID int64) error {
-	_, err := tc.BinanceClient.NewCancelOrderService().
-		Symbol(symbol).
-		OrderID(orderID).
-		Do(ctx)
-
-	if err != nil {
-		return fmt.Errorf("failed to cancel order %d for %s: %w", orderID, symbol, err)
-	}
-
-	return nil
-}
-
-// CleanupOrphaneOrders Simple cleanup: 1 position = 2 orders (ไม่สนใจประเภท), ไม่มี position = ปิดทุก orders
-func (tc *TradingClient) CleanupOrphaneOrders(ctx context.Context) error {
-	fmt.Println("🧹 Comprehensive Order/Position Cleanup")
-	fmt.Println("   Rules: 1 position = exactly 2 orders, no position = no orders")
-	fmt.Println("   If position has < 2 orders → close position + cancel all orders")
-
-	// Get positions
-	positions, err := tc.GetPositions(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get positions: %w", err)
-	}
-
-	// Create position map with actual quantities
-	positionMap := make(map[string]float64)
-	for _, pos := range positions {
-		if pos.PositionAmt != 0 {
-			positionMap[pos.Symbol] = pos.PositionAmt
-		}
-	}
-
-	// Get open orders
-	orders, err := tc.GetOpenOrders(ctx)
+// GetOrderByClientID gets an order by its client order ID, returning
+// ErrOrderNotFound when there is none
+func (tc *TradingClient) GetOrderByClientID(ctx context.Context, symbol string, clientOrderID string) (*Order, error) {
+	var order *futures.Order
+	err := tc.retry(ctx, func() error {
+		var err error
+		order, err = tc.BinanceClient.NewGetOrderService().
+			Symbol(symbol).
+			OrigClientOrderID(clientOrderID).
+			Do(ctx)
+		return err
+	}, nil)
 	if err != nil {
-		return fmt.Errorf("failed to get open orders: %w", err)
-	}
-
-	fmt.Printf("📊 Analysis: %d positions, %d orders\n", len(positionMap), len(orders))
-
-	// Group orders by symbol
-	ordersBySymbol := make(map[string][]Order)
-	for _, order := range orders {
-		ordersBySymbol[order.Symbol] = append(ordersBySymbol[order.Symbol], order)
-	}
-
-	// Get all symbols (from both positions and orders)
-	allSymbols := make(map[string]bool)
-	for symbol := range positionMap {
-		allSymbols[symbol] = true
-	}
-	for symbol := range ordersBySymbol {
-		allSymbols[symbol] = true
-	}
-
-	// Analysis and action planning
-	var symbolsToClosePositions []string
-	var ordersToCancel []Order
-	var symbolsWithCorrectSetup []string
-	var problematicSymbols []string
-
-	fmt.Println("\n🔍 Detailed Analysis by Symbol:")
-	
-	for symbol := range allSymbols {
-		positionAmt := positionMap[symbol]
-		symbolOrders := ordersBySymbol[symbol]
-		hasPosition := positionAmt != 0
-		orderCount := len(symbolOrders)
-
-		fmt.Printf("   %s: Position=%.4f, Orders=%d", symbol, positionAmt, orderCount)
-
-		if !hasPosition {
-			// No position - should have no orders
-			if orderCount > 0 {
-				fmt.Printf(" → Cancel %d orphaned orders\n", orderCount)
-				ordersToCancel = append(ordersToCancel, symbolOrders...)
-				problematicSymbols = append(problematicSymbols, symbol)
-			} else {
-				fmt.Printf(" → ✅ Correct (no position, no orders)\n")
-			}
-		} else {
-			// Has position - should have exactly 2 orders
-			if orderCount < 2 {
-				fmt.Printf(" → ❌ Close position (insufficient orders)\n")
-				symbolsToClosePositions = append(symbolsToClosePositions, symbol)
-				ordersToCancel = append(ordersToCancel, symbolOrders...)
-				problematicSymbols = append(problematicSymbols, symbol)
-			} else if orderCount == 2 {
-				fmt.Printf(" → ✅ Correct (position with 2 orders)\n")
-				symbolsWithCorrectSetup = append(symbolsWithCorrectSetup, symbol)
-			} else {
-				fmt.Printf(" → Cancel %d excess orders (keep 2)\n", orderCount-2)
-				ordersToCancel = append(ordersToCancel, symbolOrders[2:]...)
-				problematicSymbols = append(problematicSymbols, symbol)
-			}
-		}
-	}
-
-	// Summary
-	fmt.Printf("\n📋 Cleanup Summary:\n")
-	fmt.Printf("   ✅ Correct symbols: %d\n", len(symbolsWithCorrectSetup))
-	fmt.Printf("   ⚠️  Problematic symbols: %d\n", len(problematicSymbols))
-	fmt.Printf("   🗑️  Orders to cancel: %d\n", len(ordersToCancel))
-	fmt.Printf("   ❌ Positions to close: %d\n", len(symbolsToClosePositions))
-
-	if len(problematicSymbols) == 0 {
-		fmt.Println("🎉 All positions and orders are perfectly balanced!")
-		return nil
-	}
-
-	// Execute cleanup actions
-	fmt.Println("\n🔧 Executing Cleanup Actions:")
-	
-	// Step 1: Close positions that have insufficient orders
-	var failedPositionCloses []string
-	for _, symbol := range symbolsToClosePositions {
-		fmt.Printf("   🔴 Closing position %s (insufficient orders)\n", symbol)
-		if err := tc.ClosePosition(ctx, symbol); err != nil {
-			fmt.Printf("      ❌ Failed to close position %s: %v\n", symbol, err)
-			failedPositionCloses = append(failedPositionCloses, symbol)
-		} else {
-			fmt.Printf("      ✅ Position %s closed successfully\n", symbol)
-		}
-	}
-
-	// Step 2: Cancel orders
-	canceledCount := 0
-	failedCancels := 0
-	
-	for _, order := range ordersToCancel {
-		fmt.Printf("   🗑️  Canceling %s Order #%d\n", order.Symbol, order.OrderID)
-		if err := tc.CancelOrder(ctx, order.Symbol, order.OrderID); err != nil {
-			fmt.Printf("      ❌ Failed: %v\n", err)
-			failedCancels++
-		} else {
-			canceledCount++
-		}
-	}
-
-	fmt.Printf("\n✅ Cleanup Complete:\n")
-	fmt.Printf("   📊 Orders canceled: %d/%d\n", canceledCount, len(ordersToCancel))
-	fmt.Printf("   🔴 Positions closed: %d/%d\n", len(symbolsToClosePositions)-len(failedPositionCloses), len(symbolsToClosePositions))
-	
-	if failedCancels > 0 {
-		fmt.Printf("   ⚠️  Failed order cancellations: %d\n", failedCancels)
-	}
-	
-	if len(failedPositionCloses) > 0 {
-		fmt.Printf("   ⚠️  Failed position closes: %d (%v)\n", len(failedPositionCloses), failedPositionCloses)
-	}
-
-	// Final verification
-	fmt.Println("\n🔍 Post-Cleanup Verification:")
-	if err := tc.verifyOrderPositionBalance(ctx); err != nil {
-		fmt.Printf("   ⚠️  Verification found issues: %v\n", err)
-	} else {
-		fmt.Println("   ✅ All positions and orders are now balanced")
-	}
-
-	return nil
+		return nil, fmt.Errorf("failed to get order %s for %s: %w", clientOrderID, symbol, err)
+	}
+
+	// Triggered stop orders are reported as MARKET; keep the original type
+	orderType := string(order.OrigType)
+	if orderType == "" {
+		orderType = string(order.Type)
+	}
+
+	return &Order{
+		OrderID:         order.OrderID,
+		Symbol:          order.Symbol,
+		Status:          string(order.Status),
+		Side:            string(order.Side),
+		Type:            orderType,
+		OrigQty:         parseFloat(order.OrigQuantity),
+		Price:           parseFloat(order.Price),
+		StopPrice:       parseFloat(order.StopPrice),
+		ReduceOnly:      order.ReduceOnly,
+		ClosePosition:   order.ClosePosition,
+		ClientOrderID:   order.ClientOrderID,
+		ExecutedQty:     parseFloat(order.ExecutedQuantity),
+		AvgPrice:        parseFloat(order.AvgPrice),
+		PositionSide:    string(order.PositionSide),
+		CallbackRate:    parseFloat(order.PriceRate),
+		ActivationPrice: parseFloat(order.ActivatePrice),
+	}, nil
 }
 
-// verifyOrderPositionBalance verifies that every position has exactly 2 orders and no orphaned orders exist
-func (tc *TradingClient) verifyOrderPositionBalance(ctx context.Context) error {
-	// Get positions
-	positions, err := tc.GetPositions(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get positions: %w", err)
-	}
-
-	// Create position map with actual quantities
-	positionMap := make(map[string]float64)
-	for _, pos := range positions {
-		if pos.PositionAmt != 0 {
-			positionMap[pos.Symbol] = pos.PositionAmt
-		}
-	}
+// CancelOrder cancels an order by order ID
+func (tc *TradingClient) CancelOrder(ctx context.Context, symbol string, orderID int64) error {
+	err := tc.retry(ctx, func() error {
+		_, err := tc.BinanceClient.NewCancelOrderService().
+			Symbol(symbol).
+			OrderID(orderID).
+			Do(ctx)
+		return err
+	}, nil)
 
-	// Get open orders
-	orders, err := tc.GetOpenOrders(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get open orders: %w", err)
-	}
-
-	// Group orders by symbol
-	ordersBySymbol := make(map[string][]Order)
-	for _, order := range orders {
-		ordersBySymbol[order.Symbol] = append(ordersBySymbol[order.Symbol], order)
-	}
-
-	// Check all symbols
-	allSymbols := make(map[string]bool)
-	for symbol := range positionMap {
-		allSymbols[symbol] = true
-	}
-	for symbol := range ordersBySymbol {
-		allSymbols[symbol] = true
-	}
-
-	var errors []string
-
-	for symbol := range allSymbols {
-		positionAmt := positionMap[symbol]
-		symbolOrders := ordersBySymbol[symbol]
-		hasPosition := positionAmt != 0
-		orderCount := len(symbolOrders)
-
-		if !hasPosition && orderCount > 0 {
-			errors = append(errors, fmt.Sprintf("%s: has %d orphaned orders", symbol, orderCount))
-		} else if hasPosition && orderCount != 2 {
-			errors = append(errors, fmt.Sprintf("%s: position exists but has %d orders (expected 2)", symbol, orderCount))
-		}
-	}
-
-	if len(errors) > 0 {
-		return fmt.Errorf("balance issues found: %s", strings.Join(errors, "; "))
+		return fmt.Errorf("failed to cancel order %d for %s: %w", orderID, symbol, err)
 	}
 
 	return nil
@@ -1101,64 +1143,12 @@ func (tc *TradingClient) GetSwingTradingBalance(ctx context.Context) (float64, e
 
 // FormatQuantity formats quantity according to symbol's step size
 func (tc *TradingClient) FormatQuantity(ctx context.Context, symbol string, quantity float64) (string, error) {
-	pairs, err := tc.GetUSDTPairs(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get symbol info: %w", err)
-	}
-
-	for _, pair := range pairs {
-		if pair.Symbol == symbol {
-			// Use quantity precision to format
-			precision := pair.QuantityPrecision
-			if precision < 0 {
-				precision = 3 // default precision
-			}
-
-			// Ensure quantity respects step size and min quantity
-			if pair.StepSize > 0 {
-				quantity = math.Floor(quantity/pair.StepSize) * pair.StepSize
-			}
-
-			if quantity < pair.MinQty {
-				return "", fmt.Errorf("quantity %.8f is below minimum %.8f for %s", quantity, pair.MinQty, symbol)
-			}
-
-			format := fmt.Sprintf("%%.%df", precision)
-			return fmt.Sprintf(format, quantity), nil
-		}
-	}
-
-	// Fallback: use 3 decimal places
-	return fmt.Sprintf("%.3f", quantity), nil
+	return FormatQuantityFor(ctx, tc, symbol, quantity)
 }
 
 // FormatPrice formats price according to symbol's tick size
 func (tc *TradingClient) FormatPrice(ctx context.Context, symbol string, price float64) (string, error) {
-	pairs, err := tc.GetUSDTPairs(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get symbol info: %w", err)
-	}
-
-	for _, pair := range pairs {
-		if pair.Symbol == symbol {
-			// Use price precision to format
-			precision := pair.PricePrecision
-			if precision < 0 {
-				precision = 4 // default precision
-			}
-
-			// Ensure price respects tick size
-			if pair.TickSize > 0 {
-				price = math.Round(price/pair.TickSize) * pair.TickSize
-			}
-
-			format := fmt.Sprintf("%%.%df", precision)
-			return fmt.Sprintf(format, price), nil
-		}
-	}
-
-	// Fallback: use 4 decimal places
-	return fmt.Sprintf("%.4f", price), nil
+	return FormatPriceFor(ctx, tc, symbol, price)
 }
 
 // parseFloat safely converts string to float64
@@ -1173,264 +1163,8 @@ func parseFloat(s string) float64 {
 	return f
 }
 
-// ClosePosition closes a position by placing a market order in the opposite direction
-// Uses multiple fallback methods for stuck positions
-func (tc *TradingClient) ClosePosition(ctx context.Context, symbol string) error {
-	// Get current position
-	positions, err := tc.GetPositions(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get positions: %w", err)
-	}
-	
-	var targetPosition *Position
-	for _, pos := range positions {
-		if pos.Symbol == symbol && pos.PositionAmt != 0 {
-			targetPosition = &pos
-			break
-		}
-	}
-	
-	if targetPosition == nil {
-		return fmt.Errorf("no position found for symbol %s", symbol)
-	}
-	
-	log.Printf("Attempting to close position for %s: Size %.8f", symbol, targetPosition.PositionAmt)
-	
-	// Try multiple methods in sequence
-	methods := []func() error{
-		func() error { return tc.closeWithReduceOnly(ctx, symbol, targetPosition) },
-		func() error { return tc.closeWithClosePositionFlag(ctx, symbol, targetPosition) },
-		func() error { return tc.closeWithMarketOrder(ctx, symbol, targetPosition) },
-		func() error { return tc.closeWithPrecisionRounding(ctx, symbol, targetPosition) },
-		func() error { return tc.closeWithMultipleOrders(ctx, symbol, targetPosition) },
-		func() error { return tc.forceCloseWithHedge(ctx, symbol, targetPosition) },
-	}
-	
-	var lastErr error
-	for i, method := range methods {
-		log.Printf("Trying close method %d for %s", i+1, symbol)
-		err := method()
-		if err == nil {
-			log.Printf("Successfully closed position for %s using method %d", symbol, i+1)
-			return nil
-		}
-		lastErr = err
-		log.Printf("Method %d failed for %s: %v", i+1, symbol, err)
-	}
-	
-	return fmt.Errorf("all close methods failed for %s, last error: %w", symbol, lastErr)
-}
-
-// Method 1: Standard ReduceOnly approach
-func (tc *TradingClient) closeWithReduceOnly(ctx context.Context, symbol string, position *Position) error {
-	side := futures.SideTypeSell
-	if position.PositionAmt < 0 {
-		side = futures.SideTypeBuy
-	}
-	
-	quantity := fmt.Sprintf("%.8f", math.Abs(position.PositionAmt))
-	
-	_, err := tc.BinanceClient.NewCreateOrderService().
-		Symbol(symbol).
-		Side(side).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantity).
-		ReduceOnly(true).
-		Do(ctx)
-	
-	return err
-}
-
-// Method 2: ClosePosition flag approach
-func (tc *TradingClient) closeWithClosePositionFlag(ctx context.Context, symbol string, position *Position) error {
-	side := futures.SideTypeSell
-	if position.PositionAmt < 0 {
-		side = futures.SideTypeBuy
-	}
-	
-	quantity := fmt.Sprintf("%.8f", math.Abs(position.PositionAmt))
-	
-	_, err := tc.BinanceClient.NewCreateOrderService().
-		Symbol(symbol).
-		Side(side).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantity).
-		ClosePosition(true).
-		Do(ctx)
-	
-	return err
-}
-
-// Method 3: Plain market order (no flags)
-func (tc *TradingClient) closeWithMarketOrder(ctx context.Context, symbol string, position *Position) error {
-	side := futures.SideTypeSell
-	if position.PositionAmt < 0 {
-		side = futures.SideTypeBuy
-	}
-	
-	quantity := fmt.Sprintf("%.8f", math.Abs(position.PositionAmt))
-	
-	_, err := tc.BinanceClient.NewCreateOrderService().
-		Symbol(symbol).
-		Side(side).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantity).
-		Do(ctx)
-	
-	return err
-}
-
-// Method 4: Round quantity to avoid precision errors
-func (tc *TradingClient) closeWithPrecisionRounding(ctx context.Context, symbol string, position *Position) error {
-	side := futures.SideTypeSell
-	if position.PositionAmt < 0 {
-		side = futures.SideTypeBuy
-	}
-	
-	// Get symbol info for precision
-	exchangeInfo, err := tc.BinanceClient.NewExchangeInfoService().Do(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get exchange info: %w", err)
-	}
-	
-	var stepSize float64 = 0.001 // default
-	for _, symbolInfo := range exchangeInfo.Symbols {
-		if symbolInfo.Symbol == symbol {
-			for _, filter := range symbolInfo.Filters {
-				if filter["filterType"] == "LOT_SIZE" {
-					if ss, ok := filter["stepSize"].(string); ok {
-						if parsed, err := strconv.ParseFloat(ss, 64); err == nil {
-							stepSize = parsed
-						}
-					}
-				}
-			}
-			break
-		}
-	}
-	
-	// Round quantity to step size
-	absAmount := math.Abs(position.PositionAmt)
-	roundedAmount := math.Floor(absAmount/stepSize) * stepSize
-	
-	if roundedAmount <= 0 {
-		return fmt.Errorf("rounded quantity is zero")
-	}
-	
-	quantity := fmt.Sprintf("%.8f", roundedAmount)
-	
-	_, err = tc.BinanceClient.NewCreateOrderService().
-		Symbol(symbol).
-		Side(side).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantity).
-		ReduceOnly(true).
-		Do(ctx)
-	
-	return err
-}
-
-// Method 5: Close in multiple smaller orders
-func (tc *TradingClient) closeWithMultipleOrders(ctx context.Context, symbol string, position *Position) error {
-	side := futures.SideTypeSell
-	if position.PositionAmt < 0 {
-		side = futures.SideTypeBuy
-	}
-	
-	absAmount := math.Abs(position.PositionAmt)
-	numOrders := 3
-	orderSize := absAmount / float64(numOrders)
-	
-	for i := 0; i < numOrders; i++ {
-		quantity := fmt.Sprintf("%.8f", orderSize)
-		
-		_, err := tc.BinanceClient.NewCreateOrderService().
-			Symbol(symbol).
-			Side(side).
-			Type(futures.OrderTypeMarket).
-			Quantity(quantity).
-			ReduceOnly(true).
-			Do(ctx)
-		
-		if err != nil {
-			return fmt.Errorf("failed on order %d: %w", i+1, err)
-		}
-		
-		// Small delay between orders
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			// Continue immediately
-		}
-	}
-	
-	return nil
-}
-
-// Method 6: Force close by opening opposite position then closing both
-func (tc *TradingClient) forceCloseWithHedge(ctx context.Context, symbol string, position *Position) error {
-	log.Printf("WARNING: Using hedge method for %s - this opens a temporary opposite position", symbol)
-	
-	// Determine opposite side for hedge
-	hedgeSide := futures.SideTypeBuy
-	if position.PositionAmt < 0 {
-		hedgeSide = futures.SideTypeSell
-	}
-	
-	absAmount := math.Abs(position.PositionAmt)
-	quantity := fmt.Sprintf("%.8f", absAmount)
-	
-	// Step 1: Open opposite position (hedge)
-	_, err := tc.BinanceClient.NewCreateOrderService().
-		Symbol(symbol).
-		Side(hedgeSide).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantity).
-		Do(ctx)
-	
-	if err != nil {
-		return fmt.Errorf("failed to create hedge position: %w", err)
-	}
-	
-	log.Printf("Created hedge position for %s, now attempting to close all", symbol)
-	
-	// Step 2: Wait a moment for position to update
-	// (In production, you might want to poll for position updates)
-	
-	// Step 3: Try to close the now-neutral position
-	// Since we have equal opposite positions, they should cancel out
-	// But if not, try to close both individually
-	
-	// Get updated positions
-	positions, err := tc.GetPositions(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get updated positions after hedge: %w", err)
-	}
-	
-	for _, pos := range positions {
-		if pos.Symbol == symbol && pos.PositionAmt != 0 {
-			// Try to close remaining position
-			side := futures.SideTypeSell
-			if pos.PositionAmt < 0 {
-				side = futures.SideTypeBuy
-			}
-			
-			qty := fmt.Sprintf("%.8f", math.Abs(pos.PositionAmt))
-			
-			_, err := tc.BinanceClient.NewCreateOrderService().
-				Symbol(symbol).
-				Side(side).
-				Type(futures.OrderTypeMarket).
-				Quantity(qty).
-				ReduceOnly(true).
-				Do(ctx)
-			
-			if err != nil {
-				log.Printf("Warning: Failed to close remaining position after hedge for %s: %v", symbol, err)
-			}
-		}
-	}
-	
-	return nil
+// ClosePosition closes the open positions of a symbol with reduce-only market
+// orders and waits until they are flat; see PositionCloser
+func (tc *TradingClient) ClosePosition(ctx context.Context, symbol string) ([]CloseResult, error) {
+	return ClosePositionFor(ctx, tc, symbol)
 }
@@ -0,0 +1,140 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OrderLeg is the part of a trade an order belongs to
+type OrderLeg string
+
+const (
+	LegEntry      OrderLeg = "ENTRY"
+	LegStopLoss   OrderLeg = "SL"
+	LegTakeProfit OrderLeg = "TP"
+	LegRollback   OrderLeg = "RB" // Flattens an entry whose protection failed
+)
+
+// maxClientOrderIDLength is the longest newClientOrderId Binance accepts
+const maxClientOrderIDLength = 36
+
+// ErrOrderNotFound is returned when no order matches the requested ID
+var ErrOrderNotFound = errors.New("order does not exist")
+
+// ErrDuplicateSignal is returned when the entry of a signal was already placed,
+// usually by a run before a restart
+var ErrDuplicateSignal = errors.New("signal already traded")
+
+// ClientOrderTag identifies the strategy, signal and leg an order was placed for
+type ClientOrderTag struct {
+	Strategy string
+	Symbol   string
+	SignalID string
+	Leg      OrderLeg
+}
+
+// ClientOrderID encodes the tag as "<strategy>-<symbol>-<signal>-<leg>". The
+// same tag always gives the same ID, so an order resent after a crash can be
+// recognised. The strategy is shortened when the ID would exceed 36 characters.
+func (t ClientOrderTag) ClientOrderID() string {
+	strategy := sanitizeTagPart(t.Strategy)
+	rest := "-" + t.Symbol + "-" + sanitizeTagPart(t.SignalID) + "-" + sanitizeTagPart(string(t.Leg))
+	if excess := len(strategy) + len(rest) - maxClientOrderIDLength; excess > 0 {
+		strategy = strategy[:max(1, len(strategy)-excess)]
+	}
+	return strategy + rest
+}
+
+// ParseClientOrderID decodes a client order ID built by ClientOrderID. IDs
+// generated by Binance or other tools are reported as not ok.
+func ParseClientOrderID(id string) (ClientOrderTag, bool) {
+	parts := strings.Split(id, "-")
+	if len(parts) != 4 {
+		return ClientOrderTag{}, false
+	}
+	for _, part := range parts {
+		if part == "" {
+			return ClientOrderTag{}, false
+		}
+	}
+	return ClientOrderTag{Strategy: parts[0], Symbol: parts[1], SignalID: parts[2], Leg: OrderLeg(parts[3])}, true
+}
+
+// SignalID derives a short signal ID from the open time of the candle a signal
+// fired on, so re-evaluating the same candle gives the same ID
+func SignalID(candleOpen time.Time) string {
+	return strconv.FormatInt(candleOpen.Unix(), 36)
+}
+
+// TaggedOpenOrders returns the open orders placed by strategy, or by any
+// strategy when strategy is empty, keyed by their tag
+func TaggedOpenOrders(ctx context.Context, ex Exchange, strategy string) (map[ClientOrderTag]Order, error) {
+	orders, err := ex.GetOpenOrders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tagged := make(map[ClientOrderTag]Order)
+	for _, order := range orders {
+		tag, ok := ParseClientOrderID(order.ClientOrderID)
+		if !ok || (strategy != "" && tag.Strategy != sanitizeTagPart(strategy)) {
+			continue
+		}
+		tagged[tag] = order
+	}
+	return tagged, nil
+}
+
+// findOrder returns the order already placed with order's client order ID, or
+// nil if there is none. Cancelled and expired orders are ignored since their
+// ID may be reused.
+func findOrder(ctx context.Context, ex Exchange, order *OrderRequest) (*Order, error) {
+	if order.ClientOrderID == "" {
+		return nil, nil
+	}
+
+	existing, err := ex.GetOrderByClientID(ctx, order.Symbol, order.ClientOrderID)
+	if errors.Is(err, ErrOrderNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up order %s: %w", order.ClientOrderID, err)
+	}
+
+	switch existing.Status {
+	case "CANCELED", "EXPIRED", "REJECTED":
+		return nil, nil
+	}
+	return existing, nil
+}
+
+// replacementClientOrderID returns the client order ID for an order replacing
+// the one with id. Both may be open for a moment and client order IDs must be
+// unique among open orders, so the leg toggles an R suffix. IDs that are not
+// tags give "", letting Binance generate one.
+func replacementClientOrderID(id string) string {
+	tag, ok := ParseClientOrderID(id)
+	if !ok {
+		return ""
+	}
+	if strings.HasSuffix(string(tag.Leg), "R") {
+		tag.Leg = OrderLeg(strings.TrimSuffix(string(tag.Leg), "R"))
+	} else {
+		tag.Leg += "R"
+	}
+	return tag.ClientOrderID()
+}
+
+// sanitizeTagPart keeps only letters and digits so parts cannot contain the separator
+func sanitizeTagPart(part string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, part)
+}
@@ -0,0 +1,75 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestClientOrderID tests that tags survive a round trip and fit Binance's limit
+func TestClientOrderID(t *testing.T) {
+	tag := ClientOrderTag{Strategy: "breakout", Symbol: "BTCUSDT", SignalID: SignalID(time.Unix(1700000000, 0)), Leg: LegStopLoss}
+	id := tag.ClientOrderID()
+	if id != "breakout-BTCUSDT-s44we8-SL" {
+		t.Errorf("Unexpected client order ID %s", id)
+	}
+	if parsed, ok := ParseClientOrderID(id); !ok || parsed != tag {
+		t.Errorf("Expected %+v, got %+v", tag, parsed)
+	}
+
+	long := ClientOrderTag{Strategy: "very_long-strategy", Symbol: "1000SHIBUSDT_250627", SignalID: "s4pv0g", Leg: LegEntry}.ClientOrderID()
+	if len(long) > maxClientOrderIDLength {
+		t.Errorf("Client order ID %s is longer than %d", long, maxClientOrderIDLength)
+	}
+	if parsed, ok := ParseClientOrderID(long); !ok || parsed.Symbol != "1000SHIBUSDT_250627" || parsed.Leg != LegEntry {
+		t.Errorf("Failed to parse shortened ID %s: %+v", long, parsed)
+	}
+
+	if _, ok := ParseClientOrderID("web_8sF2kq1ZxYp0"); ok {
+		t.Error("Expected foreign client order ID not to parse")
+	}
+}
+
+// TestPlaceBracketIsIdempotent tests that a signal's entry is not sent twice
+func TestPlaceBracketIsIdempotent(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	ctx := context.Background()
+
+	tag := ClientOrderTag{Strategy: "test", Symbol: "BTCUSDT", SignalID: "abc"}
+	orders := func() (entry, stopLoss, takeProfit *OrderRequest) {
+		tag.Leg = LegEntry
+		entry = &OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1", ClientOrderID: tag.ClientOrderID()}
+		tag.Leg = LegStopLoss
+		stopLoss = &OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "95", ClosePosition: true, ClientOrderID: tag.ClientOrderID()}
+		tag.Leg = LegTakeProfit
+		takeProfit = &OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "TAKE_PROFIT_MARKET", StopPrice: "110", ClosePosition: true, ClientOrderID: tag.ClientOrderID()}
+		return
+	}
+
+	entry, stopLoss, takeProfit := orders()
+	first, err := PlaceBracket(ctx, pe, entry, stopLoss, takeProfit)
+	if err != nil {
+		t.Fatalf("PlaceBracket failed: %v", err)
+	}
+
+	// A restart re-evaluates the same signal
+	entry, stopLoss, takeProfit = orders()
+	second, err := PlaceBracket(ctx, pe, entry, stopLoss, takeProfit)
+	if !errors.Is(err, ErrDuplicateSignal) {
+		t.Fatalf("Expected ErrDuplicateSignal, got %v", err)
+	}
+	if second.EntryOrderID != first.EntryOrderID || second.StopOrderID != first.StopOrderID ||
+		len(second.TakeProfitOrderIDs) != 1 || second.TakeProfitOrderIDs[0] != first.TakeProfitOrderIDs[0] {
+		t.Errorf("Expected the existing orders %+v, got %+v", first, second)
+	}
+
+	positions, _ := pe.GetPositions(ctx)
+	if len(positions) != 1 || positions[0].PositionAmt != 1 {
+		t.Errorf("Expected a single 1 BTC position, got %+v", positions)
+	}
+	tagged, _ := TaggedOpenOrders(ctx, pe, "test")
+	if len(tagged) != 2 {
+		t.Errorf("Expected the stop and take profit to be tagged, got %+v", tagged)
+	}
+}
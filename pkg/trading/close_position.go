@@ -0,0 +1,284 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrPositionNotFlat is returned when a position is still open after closing
+var ErrPositionNotFlat = errors.New("position is not flat")
+
+// CloseFill is one order that closed part of a position
+type CloseFill struct {
+	OrderID       int64
+	ClientOrderID string
+	Quantity      float64
+	Price         float64 // Average fill price
+}
+
+// CloseResult reports how a position was closed
+type CloseResult struct {
+	Symbol       string
+	Side         string  // Position side closed, LONG or SHORT
+	Quantity     float64 // Position size before closing
+	EntryPrice   float64
+	Fills        []CloseFill
+	AvgExitPrice float64 // Quantity-weighted over the fills
+	RealizedPnL  float64 // Before fees
+}
+
+// PositionCloser flattens positions with reduce-only MARKET orders. The size
+// is rounded to the MARKET_LOT_SIZE step and split into chunks no larger than
+// its maximum quantity; the last chunk is rounded up, which a reduce-only
+// order caps at the position, so no dust is left behind. Positions are then
+// polled until flat, and anything left is closed again.
+type PositionCloser struct {
+	ex Exchange
+
+	PollInterval time.Duration // How often the position is checked after sending the orders
+	Timeout      time.Duration // How long to wait for the position to be flat
+	MaxRounds    int           // Times the remainder is sent again before giving up
+}
+
+// NewPositionCloser creates a closer for positions on ex
+func NewPositionCloser(ex Exchange) *PositionCloser {
+	return &PositionCloser{
+		ex:           ex,
+		PollInterval: 500 * time.Millisecond,
+		Timeout:      30 * time.Second,
+		MaxRounds:    3,
+	}
+}
+
+// ClosePositionFor closes the open positions of symbol on ex, one result per
+// position side closed (both the LONG and the SHORT one in hedge mode)
+func ClosePositionFor(ctx context.Context, ex Exchange, symbol string) ([]CloseResult, error) {
+	return NewPositionCloser(ex).Close(ctx, symbol)
+}
+
+// Close closes the open positions of symbol and returns how each was closed.
+// A position that is not flat in time is reported with ErrPositionNotFlat
+// along with the fills so far.
+func (c *PositionCloser) Close(ctx context.Context, symbol string) ([]CloseResult, error) {
+	positions, err := c.ex.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	var results []CloseResult
+	for _, position := range positions {
+		if position.Symbol != symbol || position.PositionAmt == 0 {
+			continue
+		}
+		result, err := c.closeSide(ctx, position)
+		results = append(results, *result)
+		if err != nil {
+			return results, err
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no position found for symbol %s", symbol)
+	}
+	return results, nil
+}
+
+// closeSide closes one position and waits for it to be flat
+func (c *PositionCloser) closeSide(ctx context.Context, position Position) (*CloseResult, error) {
+	result := &CloseResult{
+		Symbol:     position.Symbol,
+		Side:       position.Side,
+		Quantity:   position.PositionAmt,
+		EntryPrice: position.EntryPrice,
+	}
+	log.Printf("🔴 Closing %s %s position of %s", position.Symbol, position.Side, formatFloat(position.PositionAmt))
+
+	filters, err := c.ex.Symbols().Get(ctx, position.Symbol)
+	if err != nil {
+		return result, err
+	}
+
+	tag := ClientOrderTag{Strategy: "close", Symbol: position.Symbol, SignalID: strconv.FormatInt(time.Now().UnixMilli(), 36)}
+	var chunks []*Order
+	var orderErr error // Last failed close order, the likely cause if the position stays open
+	deadline := time.Now().Add(c.Timeout)
+	remaining := position.PositionAmt
+
+	for round := 1; ; round++ {
+		for _, quantity := range closeChunks(filters, remaining) {
+			tag.Leg = OrderLeg(fmt.Sprintf("C%d", len(chunks)+1))
+			request := &OrderRequest{
+				Symbol:        position.Symbol,
+				Side:          "SELL",
+				Type:          "MARKET",
+				Quantity:      quantity,
+				ReduceOnly:    true,
+				PositionSide:  position.Side,
+				ClientOrderID: tag.ClientOrderID(),
+			}
+			if position.Side == "SHORT" {
+				request.Side = "BUY"
+			}
+
+			response, err := c.ex.CreateOrder(request)
+			if err != nil {
+				// A chunk may fail once a concurrent stop has closed the rest
+				log.Printf("⚠️  Close order %s for %s failed: %v", request.Quantity, position.Symbol, err)
+				orderErr = err
+				continue
+			}
+			chunks = append(chunks, &Order{
+				OrderID:       parseOrderID(response.OrderID),
+				Symbol:        position.Symbol,
+				Status:        response.Status,
+				ClientOrderID: request.ClientOrderID,
+				ExecutedQty:   response.ExecutedQty,
+				AvgPrice:      response.AvgPrice,
+			})
+		}
+
+		remaining, err = c.waitFlat(ctx, position, chunks, deadline)
+		if err != nil {
+			c.collectFills(ctx, result, chunks)
+			return result, withCloseOrderError(err, orderErr)
+		}
+		if remaining == 0 {
+			break
+		}
+		if round >= c.MaxRounds {
+			c.collectFills(ctx, result, chunks)
+			err := fmt.Errorf("%w: %s %s still %s after %d rounds", ErrPositionNotFlat, position.Symbol, position.Side, formatFloat(remaining), round)
+			return result, withCloseOrderError(err, orderErr)
+		}
+		log.Printf("⚠️  %s %s position still %s, closing the remainder", position.Symbol, position.Side, formatFloat(remaining))
+	}
+
+	c.collectFills(ctx, result, chunks)
+	log.Printf("✅ Closed %s %s: %s @ %s, realized PnL %.4f USDT", position.Symbol, position.Side,
+		formatFloat(result.Quantity), formatFloat(result.AvgExitPrice), result.RealizedPnL)
+	return result, nil
+}
+
+// withCloseOrderError adds the last failed close order to err, if any
+func withCloseOrderError(err, orderErr error) error {
+	if orderErr == nil {
+		return err
+	}
+	return fmt.Errorf("%w; last close order failed: %w", err, orderErr)
+}
+
+// waitFlat polls the position until it is flat, or until every close order
+// has finished and some of it is still open, and returns what remains
+func (c *PositionCloser) waitFlat(ctx context.Context, position Position, chunks []*Order, deadline time.Time) (float64, error) {
+	for {
+		positions, err := c.ex.GetPositions(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get positions: %w", err)
+		}
+		open := findPosition(positions, position.Symbol, position.Side)
+		if open == nil {
+			return 0, nil
+		}
+		if c.chunksDone(ctx, chunks) {
+			return open.PositionAmt, nil
+		}
+		if time.Now().After(deadline) {
+			return open.PositionAmt, fmt.Errorf("%w: %s %s still %s after %s", ErrPositionNotFlat, position.Symbol, position.Side, formatFloat(open.PositionAmt), c.Timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return open.PositionAmt, ctx.Err()
+		case <-time.After(c.PollInterval):
+		}
+	}
+}
+
+// chunksDone refreshes the close orders and reports whether all have finished
+func (c *PositionCloser) chunksDone(ctx context.Context, chunks []*Order) bool {
+	done := true
+	for _, chunk := range chunks {
+		if orderFinished(chunk.Status) {
+			continue
+		}
+		if order, err := c.ex.GetOrderByClientID(ctx, chunk.Symbol, chunk.ClientOrderID); err == nil {
+			*chunk = *order
+		}
+		done = done && orderFinished(chunk.Status)
+	}
+	return done
+}
+
+// collectFills records the filled close orders and the exit price and PnL they give
+func (c *PositionCloser) collectFills(ctx context.Context, result *CloseResult, chunks []*Order) {
+	c.chunksDone(ctx, chunks)
+
+	direction := 1.0
+	if result.Side == "SHORT" {
+		direction = -1.0
+	}
+	filled, value := 0.0, 0.0
+	for _, chunk := range chunks {
+		if chunk.ExecutedQty == 0 {
+			continue
+		}
+		result.Fills = append(result.Fills, CloseFill{
+			OrderID:       chunk.OrderID,
+			ClientOrderID: chunk.ClientOrderID,
+			Quantity:      chunk.ExecutedQty,
+			Price:         chunk.AvgPrice,
+		})
+		filled += chunk.ExecutedQty
+		value += chunk.ExecutedQty * chunk.AvgPrice
+	}
+	if filled > 0 {
+		result.AvgExitPrice = value / filled
+		result.RealizedPnL = (result.AvgExitPrice - result.EntryPrice) * filled * direction
+	}
+}
+
+// closeChunks splits quantity into MARKET order sizes of at most the maximum
+// quantity, each a multiple of the step, with the last rounded up
+func closeChunks(filters *SymbolFilters, quantity float64) []string {
+	step, minQty, maxQty := filters.LotSize("MARKET")
+	// Float noise such as 0.30000000000000004 must not round up a whole step
+	total := ceilToStep(decimal.NewFromFloat(quantity).Round(8), step)
+	if total.LessThan(minQty) {
+		total = minQty
+	}
+
+	var chunks []string
+	for total.IsPositive() {
+		chunk := total
+		if maxQty.IsPositive() && chunk.GreaterThan(maxQty) {
+			if chunk = floorToStep(maxQty, step); !chunk.IsPositive() {
+				break
+			}
+		}
+		chunks = append(chunks, chunk.StringFixed(stepDecimals(step)))
+		total = total.Sub(chunk)
+	}
+	return chunks
+}
+
+// ceilToStep rounds value up to a multiple of step
+func ceilToStep(value, step decimal.Decimal) decimal.Decimal {
+	if !step.IsPositive() {
+		return value
+	}
+	return value.Div(step).Ceil().Mul(step)
+}
+
+// orderFinished reports whether an order status is final
+func orderFinished(status string) bool {
+	switch status {
+	case "FILLED", "CANCELED", "EXPIRED", "REJECTED":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestClosePosition tests closing in MARKET_LOT_SIZE chunks until flat
+func TestClosePosition(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinPrice: 0.1, MaxPrice: 1000000, TickSize: 0.1, MinQty: 0.001, MaxQty: 1, StepSize: 0.001}}
+	ctx := context.Background()
+
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "2.5"}); err != nil {
+		t.Fatalf("Failed to open position: %v", err)
+	}
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 3600000, Open: 100, High: 110, Low: 100, Close: 110, CloseTime: 7199999})
+
+	closer := NewPositionCloser(pe)
+	closer.PollInterval = time.Millisecond
+	results, err := closer.Close(ctx, "BTCUSDT")
+	if err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected one result, got %+v", results)
+	}
+	result := results[0]
+	if len(result.Fills) != 3 || result.Fills[0].Quantity != 1 || result.Fills[2].Quantity != 0.5 {
+		t.Errorf("Expected fills of 1, 1 and 0.5, got %+v", result.Fills)
+	}
+	if result.AvgExitPrice != 110 || result.RealizedPnL != 25 {
+		t.Errorf("Expected exit at 110 for 25 USDT, got %v and %v", result.AvgExitPrice, result.RealizedPnL)
+	}
+	if positions, _ := pe.GetPositions(ctx); len(positions) != 0 {
+		t.Errorf("Expected the position to be flat, got %+v", positions)
+	}
+
+	if _, err := closer.Close(ctx, "BTCUSDT"); err == nil || !strings.Contains(err.Error(), "no position") {
+		t.Errorf("Expected no position to close, got %v", err)
+	}
+}
+
+// TestCloseChunks tests rounding the size to the step without leaving dust
+func TestCloseChunks(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinQty: 0.001, MaxQty: 1, StepSize: 0.001}}
+	filters, err := pe.Symbols().Get(context.Background(), "BTCUSDT")
+	if err != nil {
+		t.Fatalf("Failed to get filters: %v", err)
+	}
+
+	tests := []struct {
+		quantity float64
+		chunks   string
+	}{
+		{0.1 + 0.2, "0.300"},
+		{0.0015, "0.002"},
+		{2.0004, "1.000 1.000 0.001"},
+	}
+	for _, tt := range tests {
+		if chunks := strings.Join(closeChunks(filters, tt.quantity), " "); chunks != tt.chunks {
+			t.Errorf("%v: expected %s, got %s", tt.quantity, tt.chunks, chunks)
+		}
+	}
+}
+
+// rejectingExchange rejects every order with err
+type rejectingExchange struct {
+	*PaperExchange
+	err error
+}
+
+func (re *rejectingExchange) CreateOrder(order *OrderRequest) (*OrderResponse, error) {
+	return nil, re.err
+}
+
+// TestClosePositionReportsCause tests that a close that never fills returns why its orders failed
+func TestClosePositionReportsCause(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinPrice: 0.1, MaxPrice: 1000000, TickSize: 0.1, MinQty: 0.001, MaxQty: 1000, StepSize: 0.001}}
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"}); err != nil {
+		t.Fatalf("Failed to open position: %v", err)
+	}
+
+	closer := NewPositionCloser(&rejectingExchange{PaperExchange: pe, err: ErrInsufficientMargin})
+	closer.PollInterval = time.Millisecond
+	_, err := closer.Close(context.Background(), "BTCUSDT")
+	if !errors.Is(err, ErrPositionNotFlat) || !errors.Is(err, ErrInsufficientMargin) {
+		t.Errorf("Expected ErrPositionNotFlat caused by ErrInsufficientMargin, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package trading
+
+import (
+	"context"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// Exchange is the set of venue operations the strategies depend on.
+// TradingClient implements it against Binance Futures; other backends
+// (paper trading, recorded-data replay, test fakes) can be plugged in
+// by implementing the same methods.
+type Exchange interface {
+	// Market data
+	GetKlines(symbol string, interval string, limit int) ([][]interface{}, error)
+	GetTicker(symbol string) (*TickerPrice, error)
+	GetMarkPrice(ctx context.Context, symbol string) (float64, error)
+	GetExchangeInfo(ctx context.Context) (*futures.ExchangeInfo, error)
+	GetUSDTPairs(ctx context.Context) ([]TradingPair, error)
+	GetUSDTSymbols() ([]string, error)
+	Symbols() *SymbolRegistry
+
+	// Account
+	GetAccountInfoSimple() (*AccountInfo, error)
+	GetUSDTBalance(ctx context.Context) (*AccountBalance, error)
+	GetPositions(ctx context.Context) ([]Position, error)
+	GetOpenOrders(ctx context.Context) ([]Order, error)
+	GetOrderByClientID(ctx context.Context, symbol string, clientOrderID string) (*Order, error)
+	GetIncomeHistory(ctx context.Context, since time.Time) ([]Income, error)
+
+	// Orders
+	CreateOrder(order *OrderRequest) (*OrderResponse, error)
+	CancelOrder(ctx context.Context, symbol string, orderID int64) error
+
+	// Leverage and margin mode
+	GetLeverage(symbol string) (int, error)
+	ChangeLeverage(symbol string, leverage int) error
+	GetMarginMode(symbol string) (string, error)
+	ChangeMarginMode(symbol string, marginMode string) error
+	GetLeverageBrackets(ctx context.Context, symbol string) ([]LeverageBracket, error)
+	GetPositionMode(ctx context.Context) (PositionMode, error)
+}
+
+// Ensure TradingClient satisfies the Exchange interface
+var _ Exchange = (*TradingClient)(nil)
+
+// FormatQuantityFor rounds quantity down to the symbol's LOT_SIZE step on any exchange
+func FormatQuantityFor(ctx context.Context, ex Exchange, symbol string, quantity float64) (string, error) {
+	return ex.Symbols().FormatQuantity(ctx, symbol, quantity, "LIMIT")
+}
+
+// FormatMarketQuantityFor rounds quantity down to the symbol's MARKET_LOT_SIZE step on any exchange
+func FormatMarketQuantityFor(ctx context.Context, ex Exchange, symbol string, quantity float64) (string, error) {
+	return ex.Symbols().FormatQuantity(ctx, symbol, quantity, "MARKET")
+}
+
+// FormatPriceFor rounds price to the symbol's tick size on any exchange
+func FormatPriceFor(ctx context.Context, ex Exchange, symbol string, price float64) (string, error) {
+	return ex.Symbols().FormatPrice(ctx, symbol, price)
+}
+
+// klineSourceExchange serves GetKlines from another source and everything else from the exchange
+type klineSourceExchange struct {
+	Exchange
+	klines KlineSource
+}
+
+// WithKlineSource returns ex with GetKlines answered by source, such as a
+// KlineStore or KlineStream
+func WithKlineSource(ex Exchange, source KlineSource) Exchange {
+	return &klineSourceExchange{Exchange: ex, klines: source}
+}
+
+// GetKlines returns klines from the wrapped source
+func (ke *klineSourceExchange) GetKlines(symbol string, interval string, limit int) ([][]interface{}, error) {
+	return ke.klines.GetKlines(symbol, interval, limit)
+}
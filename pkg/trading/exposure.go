@@ -0,0 +1,158 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ErrExposureLimit is returned when a new position would exceed a portfolio limit
+var ErrExposureLimit = errors.New("exposure limit reached")
+
+// ExposureConfig limits the open positions across the portfolio. Zero disables a limit.
+type ExposureConfig struct {
+	MaxPositions         int     // Open positions, a position on a new symbol or side counting as one more
+	MaxSameDirection     int     // Open positions on the side of the new one, such as several LONGs on correlated alts
+	MaxTotalNotional     float64 // USDT notional of every position together
+	MaxDirectionNotional float64 // USDT notional of the positions on the side of the new one
+	MaxSymbolNotional    float64 // USDT notional of the symbol's position on the side of the new one
+}
+
+// DefaultExposureConfig allows 5 open positions, at most 3 in the same direction
+func DefaultExposureConfig() ExposureConfig {
+	return ExposureConfig{
+		MaxPositions:     5,
+		MaxSameDirection: 3,
+	}
+}
+
+// ExposureConfigFromEnv returns the default config overridden by
+// MAX_OPEN_POSITIONS, MAX_SAME_DIRECTION, MAX_TOTAL_NOTIONAL,
+// MAX_DIRECTION_NOTIONAL and MAX_SYMBOL_NOTIONAL
+func ExposureConfigFromEnv() ExposureConfig {
+	cfg := DefaultExposureConfig()
+	if count, err := strconv.Atoi(os.Getenv("MAX_OPEN_POSITIONS")); err == nil && count >= 0 {
+		cfg.MaxPositions = count
+	}
+	if count, err := strconv.Atoi(os.Getenv("MAX_SAME_DIRECTION")); err == nil && count >= 0 {
+		cfg.MaxSameDirection = count
+	}
+	if notional, err := strconv.ParseFloat(os.Getenv("MAX_TOTAL_NOTIONAL"), 64); err == nil && notional >= 0 {
+		cfg.MaxTotalNotional = notional
+	}
+	if notional, err := strconv.ParseFloat(os.Getenv("MAX_DIRECTION_NOTIONAL"), 64); err == nil && notional >= 0 {
+		cfg.MaxDirectionNotional = notional
+	}
+	if notional, err := strconv.ParseFloat(os.Getenv("MAX_SYMBOL_NOTIONAL"), 64); err == nil && notional >= 0 {
+		cfg.MaxSymbolNotional = notional
+	}
+	return cfg
+}
+
+// ExposureGate checks a new entry against the open positions, and the resting
+// limit entries that will open more, before it is sent
+type ExposureGate struct {
+	ex      Exchange
+	config  ExposureConfig
+	entries *LimitEntryManager // nil when entries never rest
+}
+
+// NewExposureGate creates a gate for the positions on ex
+func NewExposureGate(ex Exchange, config ExposureConfig) *ExposureGate {
+	return &ExposureGate{ex: ex, config: config}
+}
+
+// WithLimitEntries counts the pending entries of entries as positions at
+// their limit price; entries may be nil
+func (g *ExposureGate) WithLimitEntries(entries *LimitEntryManager) *ExposureGate {
+	g.entries = entries
+	return g
+}
+
+// exposure is the notional held, or waiting to fill, on one position side
+type exposure struct {
+	symbol, side string
+	notional     float64
+}
+
+// Check returns ErrExposureLimit with the limit that would be exceeded by
+// opening notional USDT more of symbol on side (LONG or SHORT)
+func (g *ExposureGate) Check(ctx context.Context, symbol, side string, notional float64) error {
+	exposures, err := g.exposures(ctx)
+	if err != nil {
+		return err
+	}
+
+	count, sameDirection := 0, 0
+	total, direction, symbolNotional := notional, notional, notional
+	adding := false // Adds to an open position rather than opening another
+	for _, open := range exposures {
+		count++
+		total += open.notional
+		if open.side == side {
+			sameDirection++
+			direction += open.notional
+			if open.symbol == symbol {
+				symbolNotional += open.notional
+				adding = true
+			}
+		}
+	}
+	if !adding {
+		count++
+		sameDirection++
+	}
+
+	switch {
+	case g.config.MaxPositions > 0 && count > g.config.MaxPositions:
+		return fmt.Errorf("%w: %s %s would be position %d of at most %d", ErrExposureLimit, symbol, side, count, g.config.MaxPositions)
+	case g.config.MaxSameDirection > 0 && sameDirection > g.config.MaxSameDirection:
+		return fmt.Errorf("%w: %s %s would be %s position %d of at most %d", ErrExposureLimit, symbol, side, side, sameDirection, g.config.MaxSameDirection)
+	case g.config.MaxTotalNotional > 0 && total > g.config.MaxTotalNotional:
+		return fmt.Errorf("%w: total notional would be %.2f USDT, above %.2f", ErrExposureLimit, total, g.config.MaxTotalNotional)
+	case g.config.MaxDirectionNotional > 0 && direction > g.config.MaxDirectionNotional:
+		return fmt.Errorf("%w: %s notional would be %.2f USDT, above %.2f", ErrExposureLimit, side, direction, g.config.MaxDirectionNotional)
+	case g.config.MaxSymbolNotional > 0 && symbolNotional > g.config.MaxSymbolNotional:
+		return fmt.Errorf("%w: %s notional would be %.2f USDT, above %.2f", ErrExposureLimit, sideLabel(symbol, side), symbolNotional, g.config.MaxSymbolNotional)
+	}
+	return nil
+}
+
+// exposures returns the open positions and pending limit entries by positionKey;
+// an entry resting on the side of an open position adds to it
+func (g *ExposureGate) exposures(ctx context.Context) (map[string]*exposure, error) {
+	positions, err := g.ex.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	exposures := make(map[string]*exposure)
+	add := func(symbol, side string, notional float64) {
+		key := positionKey(symbol, side)
+		if exposures[key] == nil {
+			exposures[key] = &exposure{symbol: symbol, side: side}
+		}
+		exposures[key].notional += notional
+	}
+
+	for _, position := range positions {
+		if position.PositionAmt == 0 {
+			continue
+		}
+		price := position.MarkPrice
+		if price <= 0 {
+			price = position.EntryPrice
+		}
+		add(position.Symbol, position.Side, position.PositionAmt*price)
+	}
+
+	if g.entries != nil {
+		for _, pending := range g.entries.Pending() {
+			entry := pending.Entry
+			add(entry.Symbol, orderDirection(entry.Side), parseFloat(entry.Quantity)*parseFloat(entry.Price))
+		}
+	}
+	return exposures, nil
+}
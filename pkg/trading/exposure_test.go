@@ -0,0 +1,79 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestExposureGate tests each portfolio limit against the open positions
+func TestExposureGate(t *testing.T) {
+	positions := []Position{
+		{Symbol: "BTCUSDT", PositionAmt: 1, EntryPrice: 100, MarkPrice: 110, Side: "LONG"},
+		{Symbol: "ETHUSDT", PositionAmt: 2, EntryPrice: 50, Side: "LONG"},
+		{Symbol: "SOLUSDT", PositionAmt: 4, EntryPrice: 25, MarkPrice: 25, Side: "SHORT"},
+	}
+
+	tests := []struct {
+		name     string
+		config   ExposureConfig
+		symbol   string
+		side     string
+		notional float64
+		allowed  bool
+	}{
+		{"within limits", DefaultExposureConfig(), "ADAUSDT", "SHORT", 100, true},
+		{"max positions", ExposureConfig{MaxPositions: 3}, "ADAUSDT", "SHORT", 100, false},
+		{"adding to a position", ExposureConfig{MaxPositions: 3}, "BTCUSDT", "LONG", 100, true},
+		{"same direction", ExposureConfig{MaxSameDirection: 2}, "ADAUSDT", "LONG", 100, false},
+		{"other direction", ExposureConfig{MaxSameDirection: 2}, "ADAUSDT", "SHORT", 100, true},
+		{"total notional", ExposureConfig{MaxTotalNotional: 400}, "ADAUSDT", "SHORT", 100, false},
+		{"direction notional", ExposureConfig{MaxDirectionNotional: 300}, "ADAUSDT", "LONG", 100, false},
+		{"symbol notional", ExposureConfig{MaxSymbolNotional: 200}, "BTCUSDT", "LONG", 100, false},
+		{"symbol notional on a new symbol", ExposureConfig{MaxSymbolNotional: 200}, "ADAUSDT", "LONG", 100, true},
+	}
+
+	for _, tt := range tests {
+		gate := NewExposureGate(&hedgeExchange{PaperExchange: newTestPaperExchange(100), positions: positions}, tt.config)
+		err := gate.Check(context.Background(), tt.symbol, tt.side, tt.notional)
+		if tt.allowed && err != nil {
+			t.Errorf("%s: expected the trade to be allowed, got %v", tt.name, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrExposureLimit) {
+			t.Errorf("%s: expected ErrExposureLimit, got %v", tt.name, err)
+		}
+	}
+}
+
+// TestExposureGateCountsLimitEntries tests that resting limit entries count as positions
+func TestExposureGateCountsLimitEntries(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	entries := NewLimitEntryManager(pe, DefaultLimitEntryConfig())
+	entries.pending[positionKey("ETHUSDT", "LONG")] = &PendingEntry{
+		Entry: &OrderRequest{Symbol: "ETHUSDT", Side: "BUY", Type: "LIMIT", Quantity: "2", Price: "50", ClientOrderID: "entry"},
+	}
+	positions := []Position{{Symbol: "BTCUSDT", PositionAmt: 1, MarkPrice: 100, Side: "LONG"}}
+
+	tests := []struct {
+		name    string
+		config  ExposureConfig
+		symbol  string
+		allowed bool
+	}{
+		{"max positions", ExposureConfig{MaxPositions: 2}, "SOLUSDT", false},
+		{"adding to a resting entry", ExposureConfig{MaxPositions: 2}, "ETHUSDT", true},
+		{"direction notional", ExposureConfig{MaxDirectionNotional: 250}, "SOLUSDT", false},
+		{"symbol notional", ExposureConfig{MaxSymbolNotional: 150}, "ETHUSDT", false},
+	}
+
+	for _, tt := range tests {
+		gate := NewExposureGate(&hedgeExchange{PaperExchange: pe, positions: positions}, tt.config).WithLimitEntries(entries)
+		err := gate.Check(context.Background(), tt.symbol, "LONG", 100)
+		if tt.allowed && err != nil {
+			t.Errorf("%s: expected the trade to be allowed, got %v", tt.name, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrExposureLimit) {
+			t.Errorf("%s: expected ErrExposureLimit, got %v", tt.name, err)
+		}
+	}
+}
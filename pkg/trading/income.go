@@ -0,0 +1,93 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// Income types that come from trading, as opposed to transfers
+const (
+	IncomeRealizedPnL = "REALIZED_PNL"
+	IncomeCommission  = "COMMISSION"
+	IncomeFundingFee  = "FUNDING_FEE"
+)
+
+// incomePageSize is the most records the income endpoint returns per request
+const incomePageSize = 1000
+
+// Income is one change of the USDT wallet, such as realized PnL or a fee
+type Income struct {
+	Symbol string
+	Type   string
+	Amount float64 // Negative for fees and losses
+	Time   time.Time
+}
+
+// tradingIncome reports whether an income type is a result of trading
+func tradingIncome(incomeType string) bool {
+	switch incomeType {
+	case IncomeRealizedPnL, IncomeCommission, IncomeFundingFee:
+		return true
+	}
+	return false
+}
+
+// incomeKey identifies an income record; a fill's PnL and commission share a transaction ID
+type incomeKey struct {
+	tranID     int64
+	incomeType string
+}
+
+// GetIncomeHistory returns the USDT income since the given time, oldest first.
+// Each page after the first starts at the time of the last record, since records
+// of one fill share a millisecond, and records seen on the previous page are skipped.
+func (tc *TradingClient) GetIncomeHistory(ctx context.Context, since time.Time) ([]Income, error) {
+	var incomes []Income
+	seen := make(map[incomeKey]bool)
+	start := since.UnixMilli()
+	for {
+		var page []*futures.IncomeHistory
+		err := tc.retry(ctx, func() error {
+			var err error
+			page, err = tc.BinanceClient.NewGetIncomeHistoryService().StartTime(start).Limit(incomePageSize).Do(ctx)
+			return err
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get income history: %w", err)
+		}
+
+		added := 0
+		for _, record := range page {
+			key := incomeKey{tranID: record.TranID, incomeType: record.IncomeType}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			added++
+
+			if record.Asset != "USDT" {
+				continue
+			}
+			amount, _ := strconv.ParseFloat(record.Income, 64)
+			incomes = append(incomes, Income{
+				Symbol: record.Symbol,
+				Type:   record.IncomeType,
+				Amount: amount,
+				Time:   time.UnixMilli(record.Time),
+			})
+		}
+		if len(page) < incomePageSize {
+			return incomes, nil
+		}
+
+		start = page[len(page)-1].Time
+		if added == 0 {
+			// A whole page in one millisecond would repeat forever
+			start++
+		}
+	}
+}
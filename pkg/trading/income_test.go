@@ -0,0 +1,54 @@
+package trading
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// TestGetIncomeHistoryPages tests that records sharing a millisecond across a page boundary are all returned once
+func TestGetIncomeHistoryPages(t *testing.T) {
+	// A funding fee, then the PnL and commission of 500 fills, each pair in one millisecond
+	records := []futures.IncomeHistory{{Asset: "USDT", Income: "-1", IncomeType: IncomeFundingFee, Time: 999, TranID: 1}}
+	for i := int64(0); i < 500; i++ {
+		records = append(records,
+			futures.IncomeHistory{Asset: "USDT", Income: "-2", IncomeType: IncomeRealizedPnL, Time: 1000 + i, TranID: 2 + i},
+			futures.IncomeHistory{Asset: "USDT", Income: "-0.1", IncomeType: IncomeCommission, Time: 1000 + i, TranID: 2 + i},
+		)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.ParseInt(r.URL.Query().Get("startTime"), 10, 64)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		page := []futures.IncomeHistory{}
+		for _, record := range records {
+			if record.Time >= start && len(page) < limit {
+				page = append(page, record)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	client := futures.NewClient("key", "secret")
+	client.BaseURL = server.URL
+	tc := &TradingClient{BinanceClient: client, Retry: RetryConfig{MaxAttempts: 1}}
+
+	incomes, err := tc.GetIncomeHistory(context.Background(), time.UnixMilli(0))
+	if err != nil {
+		t.Fatalf("GetIncomeHistory failed: %v", err)
+	}
+	if len(incomes) != len(records) {
+		t.Fatalf("Expected %d records, got %d", len(records), len(incomes))
+	}
+	if last := incomes[len(incomes)-1]; last.Type != IncomeCommission || last.Time.UnixMilli() != 1499 {
+		t.Errorf("Expected the last commission, got %+v", last)
+	}
+}
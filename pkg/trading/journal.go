@@ -0,0 +1,482 @@
+package trading
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JournalEvent is the kind of a journal entry
+type JournalEvent string
+
+const (
+	JournalEventSignal  JournalEvent = "SIGNAL"  // Signal and analysis that triggered a trade
+	JournalEventOrder   JournalEvent = "ORDER"   // Order request with the exchange response or error
+	JournalEventCancel  JournalEvent = "CANCEL"  // Order cancellation
+	JournalEventFill    JournalEvent = "FILL"    // Trade execution reported by the user data stream
+	JournalEventBracket JournalEvent = "BRACKET" // Entry linked to its stop loss and take profits
+	JournalEventTrade   JournalEvent = "TRADE"   // Final outcome once a position is flat
+)
+
+// JournalEntry is one line of the journal
+type JournalEntry struct {
+	Time          time.Time    `json:"time"`
+	Event         JournalEvent `json:"event"`
+	Strategy      string       `json:"strategy,omitempty"`
+	Symbol        string       `json:"symbol"`
+	SignalID      string       `json:"signalId,omitempty"`
+	ClientOrderID string       `json:"clientOrderId,omitempty"`
+	OrderID       int64        `json:"orderId,omitempty"`
+
+	Request  *OrderRequest  `json:"request,omitempty"`
+	Response *OrderResponse `json:"response,omitempty"`
+	Fill     *JournalFill   `json:"fill,omitempty"`
+	Bracket  *Bracket       `json:"bracket,omitempty"`
+	Trade    *TradeOutcome  `json:"trade,omitempty"`
+	Signal   interface{}    `json:"signal,omitempty"`   // Strategy signal, such as a breakout or AI decision
+	Analysis string         `json:"analysis,omitempty"` // AI reasoning behind the signal
+	Error    string         `json:"error,omitempty"`
+}
+
+// JournalFill is an execution of an order
+type JournalFill struct {
+	Side        string  `json:"side"`
+	Type        string  `json:"type"`
+	Quantity    float64 `json:"quantity"`
+	Price       float64 `json:"price"`
+	Commission  float64 `json:"commission"`
+	RealizedPnL float64 `json:"realizedPnl"`
+	Status      string  `json:"status"`
+
+	PositionSide string `json:"positionSide,omitempty"` // LONG or SHORT position the fill opened or closed
+	Exit         bool   `json:"exit,omitempty"`         // The fill reduced the position
+}
+
+// TradeOutcome summarises a position from its first fill until it is flat
+type TradeOutcome struct {
+	Side        string    `json:"side"` // LONG or SHORT
+	Quantity    float64   `json:"quantity"`
+	EntryPrice  float64   `json:"entryPrice"` // Average entry fill price
+	ExitPrice   float64   `json:"exitPrice"`  // Average exit fill price
+	RealizedPnL float64   `json:"realizedPnl"`
+	Commission  float64   `json:"commission"`
+	NetPnL      float64   `json:"netPnl"`
+	Opened      time.Time `json:"opened"`
+	Closed      time.Time `json:"closed"`
+}
+
+// openTrade accumulates the fills of a position until it is flat
+type openTrade struct {
+	strategy, signalID string
+	outcome            TradeOutcome
+	entryValue         float64
+	exitQty, exitValue float64
+}
+
+// journalRestoreWindow is how far back restoreTrades replays the journal; a
+// trade open longer than this closes without its earlier fills
+const journalRestoreWindow = 30 * 24 * time.Hour
+
+// Journal appends every order, fill, bracket and trade outcome to daily JSONL
+// files (journal-2006-01-02.jsonl, by UTC date) so what the bot did can be
+// reviewed and queried later. A nil *Journal records nothing.
+//
+// Fills and trade outcomes come from the user data stream, so paper trading,
+// which has none, records orders but no fills or outcomes. Trades still open
+// when the journal is created are rebuilt from the FILL lines of the last 30 days.
+type Journal struct {
+	dir string
+
+	mu     sync.Mutex
+	trades map[string]*openTrade // By positionKey
+}
+
+// NewJournal creates a journal writing to dir
+func NewJournal(dir string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	j := &Journal{dir: dir, trades: make(map[string]*openTrade)}
+	if err := j.restoreTrades(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// restoreTrades replays the FILL and TRADE lines of journalRestoreWindow so a
+// trade opened before a restart closes with its earlier fills
+func (j *Journal) restoreTrades() error {
+	entries, err := j.Query(JournalQuery{
+		From:   time.Now().Add(-journalRestoreWindow),
+		Events: []JournalEvent{JournalEventFill, JournalEventTrade},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore open trades: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, entry := range entries {
+		if entry.Trade != nil {
+			delete(j.trades, positionKey(entry.Symbol, entry.Trade.Side))
+		} else if entry.Fill != nil {
+			j.addFill(entry)
+		}
+	}
+	if len(j.trades) > 0 {
+		log.Printf("📒 Restored %d open trade(s) from the journal", len(j.trades))
+	}
+	return nil
+}
+
+// JournalFromEnv returns a journal in JOURNAL_DIR, or nil when it is unset
+func JournalFromEnv() (*Journal, error) {
+	dir := os.Getenv("JOURNAL_DIR")
+	if dir == "" {
+		return nil, nil
+	}
+	return NewJournal(dir)
+}
+
+// Record appends entry, filling in the time and, from a tagged client order
+// ID, the strategy and signal
+func (j *Journal) Record(entry JournalEntry) error {
+	if j == nil {
+		return nil
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if tag, ok := ParseClientOrderID(entry.ClientOrderID); ok {
+		if entry.Strategy == "" {
+			entry.Strategy = tag.Strategy
+		}
+		if entry.SignalID == "" {
+			entry.SignalID = tag.SignalID
+		}
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, err := os.OpenFile(j.path(entry.Time), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// RecordSignal records the signal and analysis a trade was opened on
+func (j *Journal) RecordSignal(tag ClientOrderTag, signal interface{}, analysis string) {
+	j.logError(j.Record(JournalEntry{
+		Event:    JournalEventSignal,
+		Strategy: tag.Strategy,
+		Symbol:   tag.Symbol,
+		SignalID: tag.SignalID,
+		Signal:   signal,
+		Analysis: analysis,
+	}))
+}
+
+// RecordBracket records the entry with the stop loss and take profits protecting it
+func (j *Journal) RecordBracket(tag ClientOrderTag, bracket *Bracket) {
+	j.logError(j.Record(JournalEntry{
+		Event:    JournalEventBracket,
+		Strategy: tag.Strategy,
+		Symbol:   bracket.Symbol,
+		SignalID: tag.SignalID,
+		OrderID:  bracket.EntryOrderID,
+		Bracket:  bracket,
+	}))
+}
+
+// Attach records fills from the stream and a trade outcome each time a position goes flat
+func (j *Journal) Attach(us *UserDataStream) {
+	if j == nil {
+		return
+	}
+	us.OnOrderUpdate(func(update OrderUpdate) {
+		if update.ExecutionType == "TRADE" {
+			j.recordFill(update)
+		}
+	})
+	us.OnPositionUpdate(func(pos Position) {
+		if pos.PositionAmt == 0 {
+			j.recordTrades(pos)
+		}
+	})
+}
+
+// recordFill records a fill and adds it to the open trade of its position
+func (j *Journal) recordFill(update OrderUpdate) {
+	order := update.Order
+	exit := order.ReduceOnly || order.ClosePosition || closesHedgePosition(order)
+	side := orderDirection(order.Side)
+	if exit {
+		side = exitPositionSide(order)
+	}
+
+	entry := JournalEntry{
+		Time:          time.UnixMilli(update.TradeTime),
+		Event:         JournalEventFill,
+		Symbol:        order.Symbol,
+		ClientOrderID: update.ClientOrderID,
+		OrderID:       order.OrderID,
+		Fill: &JournalFill{
+			Side:        order.Side,
+			Type:        order.Type,
+			Quantity:    update.LastFilledQty,
+			Price:       update.LastFilledPrice,
+			Commission:  update.Commission,
+			RealizedPnL: update.RealizedPnL,
+			Status:      order.Status,
+
+			PositionSide: side,
+			Exit:         exit,
+		},
+	}
+	j.logError(j.Record(entry))
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if tag, ok := ParseClientOrderID(entry.ClientOrderID); ok {
+		entry.Strategy, entry.SignalID = tag.Strategy, tag.SignalID
+	}
+	j.addFill(entry)
+}
+
+// addFill adds a FILL entry to the open trade of its position; the caller holds mu
+func (j *Journal) addFill(entry JournalEntry) {
+	fill := entry.Fill
+	side := fill.PositionSide
+	if side == "" {
+		side = orderDirection(fill.Side)
+	}
+
+	key := positionKey(entry.Symbol, side)
+	trade := j.trades[key]
+	if trade == nil {
+		trade = &openTrade{strategy: entry.Strategy, signalID: entry.SignalID, outcome: TradeOutcome{Side: side, Opened: entry.Time}}
+		j.trades[key] = trade
+	}
+	trade.outcome.RealizedPnL += fill.RealizedPnL
+	trade.outcome.Commission += fill.Commission
+	if fill.Exit {
+		trade.exitQty += fill.Quantity
+		trade.exitValue += fill.Quantity * fill.Price
+	} else {
+		trade.outcome.Quantity += fill.Quantity
+		trade.entryValue += fill.Quantity * fill.Price
+	}
+}
+
+// recordTrades records the outcome of the trades of a position that went flat.
+// A one-way position reports no side once flat, so every trade of the symbol is closed.
+func (j *Journal) recordTrades(pos Position) {
+	var closed []JournalEntry
+
+	j.mu.Lock()
+	for key, trade := range j.trades {
+		if !strings.HasPrefix(key, pos.Symbol+"/") {
+			continue
+		}
+		if (pos.PositionSide == PositionSideLong || pos.PositionSide == PositionSideShort) && trade.outcome.Side != pos.PositionSide {
+			continue
+		}
+		delete(j.trades, key)
+
+		outcome := trade.outcome
+		outcome.Closed = time.Now()
+		if outcome.Quantity > 0 {
+			outcome.EntryPrice = trade.entryValue / outcome.Quantity
+		}
+		if trade.exitQty > 0 {
+			outcome.ExitPrice = trade.exitValue / trade.exitQty
+		}
+		outcome.NetPnL = outcome.RealizedPnL - outcome.Commission
+		closed = append(closed, JournalEntry{
+			Event:    JournalEventTrade,
+			Strategy: trade.strategy,
+			Symbol:   pos.Symbol,
+			SignalID: trade.signalID,
+			Trade:    &outcome,
+		})
+	}
+	j.mu.Unlock()
+
+	for _, entry := range closed {
+		log.Printf("📕 %s %s closed: net PnL %.4f USDT", entry.Symbol, entry.Trade.Side, entry.Trade.NetPnL)
+		j.logError(j.Record(entry))
+	}
+}
+
+// JournalQuery selects journal entries; empty fields match everything
+type JournalQuery struct {
+	Symbol   string
+	Strategy string
+	From     time.Time // Inclusive
+	To       time.Time // Exclusive
+	Events   []JournalEvent
+}
+
+// matches reports whether entry is selected by the query
+func (q JournalQuery) matches(entry JournalEntry) bool {
+	if q.Symbol != "" && entry.Symbol != q.Symbol {
+		return false
+	}
+	if q.Strategy != "" && entry.Strategy != q.Strategy {
+		return false
+	}
+	if !q.From.IsZero() && entry.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !entry.Time.Before(q.To) {
+		return false
+	}
+	if len(q.Events) == 0 {
+		return true
+	}
+	for _, event := range q.Events {
+		if entry.Event == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the entries selected by q in the order they were recorded
+func (j *Journal) Query(q JournalQuery) ([]JournalEntry, error) {
+	paths, err := filepath.Glob(filepath.Join(j.dir, "journal-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var entries []JournalEntry
+	for _, path := range paths {
+		// Skip days outside the range by the file name
+		day, err := time.Parse("2006-01-02", strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "journal-"), ".jsonl"))
+		if err == nil && ((!q.From.IsZero() && day.Add(24*time.Hour).Before(q.From)) || (!q.To.IsZero() && !day.Before(q.To))) {
+			continue
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open journal: %w", err)
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			var entry JournalEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue // A line cut short by a crash
+			}
+			if q.matches(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read journal %s: %w", path, err)
+		}
+	}
+	return entries, nil
+}
+
+// BySymbol returns every entry for symbol
+func (j *Journal) BySymbol(symbol string) ([]JournalEntry, error) {
+	return j.Query(JournalQuery{Symbol: symbol})
+}
+
+// ByStrategy returns every entry recorded for strategy
+func (j *Journal) ByStrategy(strategy string) ([]JournalEntry, error) {
+	return j.Query(JournalQuery{Strategy: strategy})
+}
+
+// Between returns the entries recorded from from until to
+func (j *Journal) Between(from, to time.Time) ([]JournalEntry, error) {
+	return j.Query(JournalQuery{From: from, To: to})
+}
+
+// path returns the file of the day t falls on
+func (j *Journal) path(t time.Time) string {
+	return filepath.Join(j.dir, "journal-"+t.UTC().Format("2006-01-02")+".jsonl")
+}
+
+// logError logs a failed journal write; the journal never stops trading
+func (j *Journal) logError(err error) {
+	if err != nil {
+		log.Printf("⚠️  Journal: %v", err)
+	}
+}
+
+// journalingExchange records every order and cancellation in a journal
+type journalingExchange struct {
+	Exchange
+	journal *Journal
+}
+
+// WithJournal returns ex with every order request, response and cancellation
+// recorded in journal; ex itself when journal is nil
+func WithJournal(ex Exchange, journal *Journal) Exchange {
+	if journal == nil {
+		return ex
+	}
+	return &journalingExchange{Exchange: ex, journal: journal}
+}
+
+// CreateOrder sends order and records it with the response or error
+func (je *journalingExchange) CreateOrder(order *OrderRequest) (*OrderResponse, error) {
+	response, err := je.Exchange.CreateOrder(order)
+
+	request := *order
+	entry := JournalEntry{
+		Event:         JournalEventOrder,
+		Symbol:        order.Symbol,
+		ClientOrderID: order.ClientOrderID,
+		Request:       &request,
+		Response:      response,
+	}
+	if response != nil {
+		entry.OrderID = parseOrderID(response.OrderID)
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	je.journal.logError(je.journal.Record(entry))
+	return response, err
+}
+
+// CancelOrder cancels an order and records it
+func (je *journalingExchange) CancelOrder(ctx context.Context, symbol string, orderID int64) error {
+	err := je.Exchange.CancelOrder(ctx, symbol, orderID)
+
+	entry := JournalEntry{Event: JournalEventCancel, Symbol: symbol, OrderID: orderID}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	je.journal.logError(je.journal.Record(entry))
+	return err
+}
@@ -0,0 +1,186 @@
+package trading
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestJournalQuery tests recording orders through the exchange wrapper and
+// querying them by symbol, strategy and date range
+func TestJournalQuery(t *testing.T) {
+	journal, err := NewJournal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJournal failed: %v", err)
+	}
+	pe := newTestPaperExchange(100)
+	ex := WithJournal(pe, journal)
+
+	tag := ClientOrderTag{Strategy: "breakout", Symbol: "BTCUSDT", SignalID: "s1", Leg: LegEntry}
+	journal.RecordSignal(tag, map[string]string{"signal": "LONG"}, "resistance broken")
+	if _, err := ex.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1", ClientOrderID: tag.ClientOrderID()}); err != nil {
+		t.Fatalf("CreateOrder failed: %v", err)
+	}
+	if _, err := ex.CreateOrder(&OrderRequest{Symbol: "ETHUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"}); err == nil {
+		t.Fatal("Expected an order without a price to fail")
+	}
+
+	// An entry from two days ago falls outside today's range
+	past := time.Now().AddDate(0, 0, -2)
+	if err := journal.Record(JournalEntry{Time: past, Event: JournalEventSignal, Strategy: "autotrader", Symbol: "BTCUSDT"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	entries, err := journal.BySymbol("BTCUSDT")
+	if err != nil {
+		t.Fatalf("BySymbol failed: %v", err)
+	}
+	if len(entries) != 3 || !entries[0].Time.Equal(past) {
+		t.Fatalf("Expected 3 BTCUSDT entries, oldest first, got %+v", entries)
+	}
+
+	entries, _ = journal.ByStrategy("breakout")
+	if len(entries) != 2 {
+		t.Fatalf("Expected the signal and order of the breakout strategy, got %+v", entries)
+	}
+	order := entries[1]
+	if order.Event != JournalEventOrder || order.SignalID != "s1" || order.Request == nil || order.Response == nil || order.OrderID == 0 {
+		t.Errorf("Expected the order with its request, response and signal, got %+v", order)
+	}
+	if entries[0].Analysis != "resistance broken" {
+		t.Errorf("Expected the signal analysis, got %q", entries[0].Analysis)
+	}
+
+	entries, _ = journal.Query(JournalQuery{Events: []JournalEvent{JournalEventOrder}})
+	if len(entries) != 2 || entries[1].Symbol != "ETHUSDT" || entries[1].Error == "" {
+		t.Errorf("Expected both orders with the failure recorded, got %+v", entries)
+	}
+
+	entries, _ = journal.Between(past.Add(-time.Hour), past.Add(time.Hour))
+	if len(entries) != 1 || entries[0].Strategy != "autotrader" {
+		t.Errorf("Expected only the entry from two days ago, got %+v", entries)
+	}
+}
+
+// TestJournalTradeOutcome tests that fills add up to a trade outcome once the position is flat
+func TestJournalTradeOutcome(t *testing.T) {
+	journal, err := NewJournal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewJournal failed: %v", err)
+	}
+
+	tag := ClientOrderTag{Strategy: "autotrader", Symbol: "BTCUSDT", SignalID: "s1", Leg: LegEntry}
+	journal.recordFill(OrderUpdate{
+		Order:         Order{OrderID: 1, Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Status: "FILLED"},
+		ClientOrderID: tag.ClientOrderID(),
+		ExecutionType: "TRADE",
+		LastFilledQty: 2, LastFilledPrice: 100, Commission: 0.1,
+	})
+	tag.Leg = LegTakeProfit
+	for i, price := range []float64{110, 120} {
+		journal.recordFill(OrderUpdate{
+			Order:         Order{OrderID: int64(2 + i), Symbol: "BTCUSDT", Side: "SELL", Type: "LIMIT", Status: "FILLED", ReduceOnly: true},
+			ClientOrderID: tag.ClientOrderID(),
+			ExecutionType: "TRADE",
+			LastFilledQty: 1, LastFilledPrice: price, RealizedPnL: price - 100, Commission: 0.1,
+		})
+	}
+	journal.recordTrades(Position{Symbol: "BTCUSDT", PositionSide: PositionSideBoth})
+
+	entries, err := journal.Query(JournalQuery{Strategy: "autotrader", Events: []JournalEvent{JournalEventTrade}})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected one trade, got %+v", entries)
+	}
+	trade := entries[0].Trade
+	if trade.Side != "LONG" || trade.Quantity != 2 || trade.EntryPrice != 100 || trade.ExitPrice != 115 ||
+		trade.RealizedPnL != 30 || trade.NetPnL < 29.69 || trade.NetPnL > 29.71 || entries[0].SignalID != "s1" {
+		t.Errorf("Unexpected trade outcome %+v", trade)
+	}
+
+	if fills, _ := journal.Query(JournalQuery{Events: []JournalEvent{JournalEventFill}}); len(fills) != 3 {
+		t.Errorf("Expected 3 fills, got %d", len(fills))
+	}
+}
+
+// TestJournalRestoresOpenTrade tests that a trade opened before a restart closes with its entry fills
+func TestJournalRestoresOpenTrade(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := NewJournal(dir)
+	if err != nil {
+		t.Fatalf("NewJournal failed: %v", err)
+	}
+
+	tag := ClientOrderTag{Strategy: "autotrader", Symbol: "BTCUSDT", SignalID: "s1", Leg: LegEntry}
+	journal.recordFill(OrderUpdate{
+		Order:         Order{OrderID: 1, Symbol: "BTCUSDT", Side: "SELL", Type: "MARKET", Status: "FILLED"},
+		ClientOrderID: tag.ClientOrderID(),
+		ExecutionType: "TRADE",
+		TradeTime:     time.Now().UnixMilli(),
+		LastFilledQty: 1, LastFilledPrice: 100,
+	})
+	journal.RecordBracket(tag, &Bracket{Symbol: "BTCUSDT", Side: "SELL", Quantity: "1", EntryOrderID: 1, StopOrderID: 2, TakeProfitOrderIDs: []int64{3}})
+
+	restarted, err := NewJournal(dir)
+	if err != nil {
+		t.Fatalf("NewJournal failed: %v", err)
+	}
+	tag.Leg = LegStopLoss
+	restarted.recordFill(OrderUpdate{
+		Order:         Order{OrderID: 2, Symbol: "BTCUSDT", Side: "BUY", Type: "STOP_MARKET", Status: "FILLED", ReduceOnly: true},
+		ClientOrderID: tag.ClientOrderID(),
+		ExecutionType: "TRADE",
+		TradeTime:     time.Now().UnixMilli(),
+		LastFilledQty: 1, LastFilledPrice: 105, RealizedPnL: -5,
+	})
+	restarted.recordTrades(Position{Symbol: "BTCUSDT", PositionSide: PositionSideBoth})
+
+	entries, err := restarted.Query(JournalQuery{Events: []JournalEvent{JournalEventTrade}})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one trade, got %+v (%v)", entries, err)
+	}
+	if trade := entries[0].Trade; trade.Side != "SHORT" || trade.Quantity != 1 || trade.EntryPrice != 100 || trade.ExitPrice != 105 || entries[0].SignalID != "s1" {
+		t.Errorf("Unexpected trade outcome %+v", trade)
+	}
+
+	// Closed trades are not restored again
+	if again, _ := NewJournal(dir); len(again.trades) != 0 {
+		t.Errorf("Expected no open trades, got %d", len(again.trades))
+	}
+
+	data, err := os.ReadFile(restarted.path(time.Now()))
+	if err != nil {
+		t.Fatalf("Failed to read the journal: %v", err)
+	}
+	for _, key := range []string{`"takeProfitOrderIds":[3]`, `"clientOrderId"`, `"positionSide":"SHORT"`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("Expected %s in the journal", key)
+		}
+	}
+}
+
+// TestJournalRestoreWindow tests that fills older than the restore window are not replayed
+func TestJournalRestoreWindow(t *testing.T) {
+	dir := t.TempDir()
+	journal, err := NewJournal(dir)
+	if err != nil {
+		t.Fatalf("NewJournal failed: %v", err)
+	}
+	journal.recordFill(OrderUpdate{
+		Order:         Order{OrderID: 1, Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Status: "FILLED"},
+		ExecutionType: "TRADE",
+		TradeTime:     time.Now().Add(-journalRestoreWindow - 48*time.Hour).UnixMilli(),
+		LastFilledQty: 1, LastFilledPrice: 100,
+	})
+
+	restarted, err := NewJournal(dir)
+	if err != nil {
+		t.Fatalf("NewJournal failed: %v", err)
+	}
+	if len(restarted.trades) != 0 {
+		t.Errorf("Expected no trades restored from outside the window, got %d", len(restarted.trades))
+	}
+}
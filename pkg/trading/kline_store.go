@@ -0,0 +1,465 @@
+package trading
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxKlinesPerRequest is the largest page Binance returns for futures klines
+const maxKlinesPerRequest = 1500
+
+// KlineSource fetches the latest klines for a symbol
+type KlineSource interface {
+	GetKlines(symbol string, interval string, limit int) ([][]interface{}, error)
+}
+
+// klineRangeSource fetches klines from a start time; TradingClient implements it
+type klineRangeSource interface {
+	GetKlinesRange(symbol string, interval string, startTime int64, limit int) ([][]interface{}, error)
+}
+
+// KlineGap is a run of missing bars in a stored series
+type KlineGap struct {
+	From    int64 `json:"from"` // Open time of the first missing bar
+	To      int64 `json:"to"`   // Open time of the last missing bar
+	Missing int   `json:"missing"`
+}
+
+// klineSeries is the in-memory copy of one symbol/interval file
+type klineSeries struct {
+	candles  []Candle
+	duration time.Duration
+	oldest   bool // The source has no bars before the first stored one
+}
+
+// KlineStore keeps closed candles on disk, one append-only JSONL file per
+// symbol and interval, and only downloads bars that closed since the last sync
+type KlineStore struct {
+	dir         string
+	source      KlineSource
+	InitialBars int // Bars downloaded the first time a series is synced
+
+	mu     sync.Mutex
+	series map[string]*klineSeries
+	now    func() time.Time
+}
+
+// NewKlineStore creates a store in dir that syncs from source (nil for offline use)
+func NewKlineStore(dir string, source KlineSource) (*KlineStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create kline store directory: %w", err)
+	}
+
+	return &KlineStore{
+		dir:         dir,
+		source:      source,
+		InitialBars: 500,
+		series:      make(map[string]*klineSeries),
+		now:         time.Now,
+	}, nil
+}
+
+// KlineStoreDir returns the store directory from KLINE_STORE_DIR, or "" when disabled
+func KlineStoreDir() string {
+	return os.Getenv("KLINE_STORE_DIR")
+}
+
+// KlineSourceFromEnv returns a store in KLINE_STORE_DIR backed by source, or source itself when unset
+func KlineSourceFromEnv(source KlineSource) (KlineSource, error) {
+	dir := KlineStoreDir()
+	if dir == "" {
+		return source, nil
+	}
+	return NewKlineStore(dir, source)
+}
+
+// Sync appends the bars that closed since the last stored bar and returns how many were added
+func (s *KlineStore) Sync(symbol, interval string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sync(symbol, interval, s.InitialBars)
+}
+
+// GetKlines syncs the series and returns the latest limit closed bars in the
+// GetKlines row layout, so the store can stand in for an exchange
+func (s *KlineStore) GetKlines(symbol string, interval string, limit int) ([][]interface{}, error) {
+	candles, err := s.Candles(symbol, interval, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]interface{}, len(candles))
+	for i, candle := range candles {
+		rows[i] = candle.Row()
+	}
+	return rows, nil
+}
+
+// Candles syncs the series and returns the latest limit closed bars,
+// downloading older history when fewer are stored
+func (s *KlineStore) Candles(symbol, interval string, limit int) ([]Candle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.sync(symbol, interval, max(s.InitialBars, limit)); err != nil {
+		return nil, err
+	}
+	if _, err := s.backfill(symbol, interval, limit); err != nil {
+		return nil, err
+	}
+
+	candles := s.series[seriesKey(symbol, interval)].candles
+	if limit > 0 && len(candles) > limit {
+		candles = candles[len(candles)-limit:]
+	}
+	return append([]Candle(nil), candles...), nil
+}
+
+// Range returns stored bars opening within [start, end] (milliseconds, end 0 = no limit) without syncing
+func (s *KlineStore) Range(symbol, interval string, start, end int64) ([]Candle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	series, err := s.load(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	candles := series.candles
+	from := sort.Search(len(candles), func(i int) bool { return candles[i].OpenTime >= start })
+	to := len(candles)
+	if end > 0 {
+		to = sort.Search(len(candles), func(i int) bool { return candles[i].OpenTime > end })
+	}
+	if from >= to {
+		return nil, nil
+	}
+	return append([]Candle(nil), candles[from:to]...), nil
+}
+
+// Gaps returns the runs of missing bars between the first and last stored bar
+func (s *KlineStore) Gaps(symbol, interval string) ([]KlineGap, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	series, err := s.load(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	step := series.duration.Milliseconds()
+	var gaps []KlineGap
+	for i := 1; i < len(series.candles); i++ {
+		prev, next := series.candles[i-1].OpenTime, series.candles[i].OpenTime
+		if next-prev > step {
+			gaps = append(gaps, KlineGap{
+				From:    prev + step,
+				To:      next - step,
+				Missing: int((next-prev)/step) - 1,
+			})
+		}
+	}
+	return gaps, nil
+}
+
+// FillGaps downloads the bars missing inside the stored range and returns how
+// many were recovered. Bars the exchange never produced remain as gaps.
+func (s *KlineStore) FillGaps(symbol, interval string) (int, error) {
+	gaps, err := s.Gaps(symbol, interval)
+	if err != nil || len(gaps) == 0 {
+		return 0, err
+	}
+
+	rangeSource, ok := s.source.(klineRangeSource)
+	if !ok {
+		return 0, fmt.Errorf("kline source cannot fetch historical ranges")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	series := s.series[seriesKey(symbol, interval)]
+	step := series.duration.Milliseconds()
+	now := s.now().UnixMilli()
+
+	var recovered []Candle
+	for _, gap := range gaps {
+		for start := gap.From; start <= gap.To; {
+			rows, err := rangeSource.GetKlinesRange(symbol, interval, start, maxKlinesPerRequest)
+			if err != nil {
+				return 0, err
+			}
+			page, err := parseClosedCandles(rows, start, now)
+			if err != nil {
+				return 0, err
+			}
+
+			for _, candle := range page {
+				if candle.OpenTime <= gap.To {
+					recovered = append(recovered, candle)
+				}
+			}
+			if len(page) == 0 || len(rows) < maxKlinesPerRequest {
+				break
+			}
+			start = page[len(page)-1].OpenTime + step
+		}
+	}
+
+	if len(recovered) == 0 {
+		return 0, nil
+	}
+	// The file is re-sorted when loaded, so recovered bars can be appended
+	if err := s.appendFile(symbol, interval, recovered); err != nil {
+		return 0, err
+	}
+	series.candles = append(series.candles, recovered...)
+	sort.Slice(series.candles, func(i, j int) bool {
+		return series.candles[i].OpenTime < series.candles[j].OpenTime
+	})
+
+	return len(recovered), nil
+}
+
+// sync fetches new closed bars, starting initialBars back for an empty
+// series; the caller must hold the lock
+func (s *KlineStore) sync(symbol, interval string, initialBars int) (int, error) {
+	series, err := s.load(symbol, interval)
+	if err != nil {
+		return 0, err
+	}
+	if s.source == nil {
+		return 0, nil
+	}
+
+	now := s.now().UnixMilli()
+	step := series.duration.Milliseconds()
+
+	// Start from initialBars back, or right after the last stored bar
+	empty := len(series.candles) == 0
+	start := now - int64(initialBars+1)*step
+	if !empty {
+		last := series.candles[len(series.candles)-1]
+		if now <= last.CloseTime+step {
+			// Nothing new can have closed yet
+			return 0, nil
+		}
+		start = last.OpenTime + step
+	}
+
+	var fetched []Candle
+	if rangeSource, ok := s.source.(klineRangeSource); ok {
+		// Page forward until the forming bar is reached
+		for start < now {
+			rows, err := rangeSource.GetKlinesRange(symbol, interval, start, maxKlinesPerRequest)
+			if err != nil {
+				return 0, err
+			}
+			page, err := parseClosedCandles(rows, start, now)
+			if err != nil {
+				return 0, err
+			}
+			fetched = append(fetched, page...)
+			if len(rows) < maxKlinesPerRequest || len(page) == 0 {
+				break
+			}
+			start = page[len(page)-1].OpenTime + step
+		}
+	} else {
+		limit := initialBars
+		if !empty {
+			// Bars missed since the last sync plus the forming one
+			limit = int((now-start)/step) + 1
+		}
+		if limit > maxKlinesPerRequest {
+			limit = maxKlinesPerRequest
+		}
+
+		rows, err := s.source.GetKlines(symbol, interval, limit)
+		if err != nil {
+			return 0, err
+		}
+		if fetched, err = parseClosedCandles(rows, start, now); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(fetched) == 0 {
+		return 0, nil
+	}
+	if err := s.appendFile(symbol, interval, fetched); err != nil {
+		return 0, err
+	}
+	series.candles = append(series.candles, fetched...)
+
+	return len(fetched), nil
+}
+
+// backfill downloads the bars before the first stored one until the series
+// holds bars of them, and returns how many were added; the caller must hold the lock
+func (s *KlineStore) backfill(symbol, interval string, bars int) (int, error) {
+	series := s.series[seriesKey(symbol, interval)]
+	missing := bars - len(series.candles)
+	if s.source == nil || series.oldest || missing <= 0 || len(series.candles) == 0 {
+		return 0, nil
+	}
+
+	now := s.now().UnixMilli()
+	step := series.duration.Milliseconds()
+	first := series.candles[0].OpenTime
+
+	var fetched []Candle
+	if rangeSource, ok := s.source.(klineRangeSource); ok {
+		// Page forward from the missing bars up to the first stored one
+		for start := first - int64(missing)*step; start < first; {
+			rows, err := rangeSource.GetKlinesRange(symbol, interval, start, maxKlinesPerRequest)
+			if err != nil {
+				return 0, err
+			}
+			page, err := parseClosedCandles(rows, start, now)
+			if err != nil {
+				return 0, err
+			}
+			for _, candle := range page {
+				if candle.OpenTime < first {
+					fetched = append(fetched, candle)
+				}
+			}
+			if len(rows) < maxKlinesPerRequest || len(page) == 0 {
+				break
+			}
+			start = page[len(page)-1].OpenTime + step
+		}
+	} else {
+		// Only the latest bars can be fetched: the stored ones, the missing ones and the forming one
+		rows, err := s.source.GetKlines(symbol, interval, min(bars+1, maxKlinesPerRequest))
+		if err != nil {
+			return 0, err
+		}
+		page, err := parseClosedCandles(rows, 0, now)
+		if err != nil {
+			return 0, err
+		}
+		for _, candle := range page {
+			if candle.OpenTime < first {
+				fetched = append(fetched, candle)
+			}
+		}
+	}
+
+	// Fewer bars than asked for means the series starts there, so don't ask again
+	series.oldest = len(fetched) < missing
+	if len(fetched) == 0 {
+		return 0, nil
+	}
+	// The file is re-sorted when loaded, so older bars can be appended
+	if err := s.appendFile(symbol, interval, fetched); err != nil {
+		return 0, err
+	}
+	series.candles = append(fetched, series.candles...)
+
+	return len(fetched), nil
+}
+
+// load reads a series from disk the first time it is used; the caller must hold the lock
+func (s *KlineStore) load(symbol, interval string) (*klineSeries, error) {
+	key := seriesKey(symbol, interval)
+	if series, ok := s.series[key]; ok {
+		return series, nil
+	}
+
+	duration, err := IntervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+	series := &klineSeries{duration: duration}
+
+	file, err := os.Open(s.path(symbol, interval))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open kline file: %w", err)
+	}
+	if err == nil {
+		defer file.Close()
+
+		byOpenTime := make(map[int64]Candle)
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var candle Candle
+			// A line cut short by a crash is skipped and re-downloaded later
+			if err := json.Unmarshal(scanner.Bytes(), &candle); err != nil {
+				continue
+			}
+			byOpenTime[candle.OpenTime] = candle
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read kline file: %w", err)
+		}
+
+		for _, candle := range byOpenTime {
+			series.candles = append(series.candles, candle)
+		}
+		sort.Slice(series.candles, func(i, j int) bool {
+			return series.candles[i].OpenTime < series.candles[j].OpenTime
+		})
+	}
+
+	s.series[key] = series
+	return series, nil
+}
+
+// appendFile appends candles to the series file
+func (s *KlineStore) appendFile(symbol, interval string, candles []Candle) error {
+	file, err := os.OpenFile(s.path(symbol, interval), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open kline file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, candle := range candles {
+		line, err := json.Marshal(candle)
+		if err != nil {
+			return err
+		}
+		writer.Write(line)
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write kline file: %w", err)
+	}
+	return nil
+}
+
+// path returns the file holding a series
+func (s *KlineStore) path(symbol, interval string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s_%s.jsonl", strings.ToUpper(symbol), interval))
+}
+
+// seriesKey identifies a series in memory
+func seriesKey(symbol, interval string) string {
+	return strings.ToUpper(symbol) + "_" + interval
+}
+
+// parseClosedCandles keeps bars opening at or after from that closed before now
+func parseClosedCandles(rows [][]interface{}, from, now int64) ([]Candle, error) {
+	var candles []Candle
+	for _, row := range rows {
+		candle, err := ParseCandle(row)
+		if err != nil {
+			return nil, err
+		}
+		if candle.OpenTime < from || candle.CloseTime >= now {
+			continue
+		}
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
@@ -0,0 +1,141 @@
+package trading
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeKlineSource serves hourly bars from a fixed list and counts requests
+type fakeKlineSource struct {
+	candles  []Candle
+	requests int
+	limits   []int
+}
+
+func (f *fakeKlineSource) GetKlines(symbol string, interval string, limit int) ([][]interface{}, error) {
+	f.requests++
+	f.limits = append(f.limits, limit)
+	candles := f.candles
+	if limit > 0 && len(candles) > limit {
+		candles = candles[len(candles)-limit:]
+	}
+	rows := make([][]interface{}, len(candles))
+	for i, candle := range candles {
+		rows[i] = candle.Row()
+	}
+	return rows, nil
+}
+
+// hourlyCandles builds count hourly bars starting at hour first
+func hourlyCandles(first, count int) []Candle {
+	candles := make([]Candle, count)
+	for i := range candles {
+		open := int64(first+i) * 3600000
+		candles[i] = Candle{OpenTime: open, Open: 1, High: 2, Low: 0.5, Close: 1.5, Volume: 10, CloseTime: open + 3599999}
+	}
+	return candles
+}
+
+// TestKlineStoreIncrementalSync tests that only newly closed bars are fetched and persisted
+func TestKlineStoreIncrementalSync(t *testing.T) {
+	dir := t.TempDir()
+	source := &fakeKlineSource{candles: hourlyCandles(0, 11)} // Bar 10 is still forming
+
+	store, err := NewKlineStore(dir, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.now = func() time.Time { return time.UnixMilli(10*3600000 + 60000) }
+
+	candles, err := store.Candles("BTCUSDT", "1h", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 5 || candles[4].OpenTime != 9*3600000 {
+		t.Fatalf("Expected the last 5 closed bars, got %+v", candles)
+	}
+
+	// Same bar: no request
+	if _, err := store.Sync("BTCUSDT", "1h"); err != nil || source.requests != 1 {
+		t.Fatalf("Expected no new request, got %d (%v)", source.requests, err)
+	}
+
+	// Three bars later only the missed bars are requested
+	source.candles = hourlyCandles(0, 14)
+	store.now = func() time.Time { return time.UnixMilli(13*3600000 + 60000) }
+	added, err := store.Sync("BTCUSDT", "1h")
+	if err != nil || added != 3 || source.limits[1] != 4 {
+		t.Fatalf("Expected 3 bars with limit 4, got %d with limits %v (%v)", added, source.limits, err)
+	}
+
+	// A new store reads the same series back from disk
+	reopened, err := NewKlineStore(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := reopened.Range("BTCUSDT", "1h", 2*3600000, 4*3600000)
+	if err != nil || len(stored) != 3 {
+		t.Fatalf("Expected 3 bars in range, got %d (%v)", len(stored), err)
+	}
+	all, _ := reopened.Range("BTCUSDT", "1h", 0, 0)
+	if len(all) != 13 {
+		t.Errorf("Expected 13 stored bars, got %d", len(all))
+	}
+}
+
+// TestKlineStoreGaps tests gap detection between stored bars
+func TestKlineStoreGaps(t *testing.T) {
+	store, err := NewKlineStore(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	candles := append(hourlyCandles(0, 3), hourlyCandles(6, 2)...)
+	if err := store.appendFile("ETHUSDT", "1h", candles); err != nil {
+		t.Fatal(err)
+	}
+
+	gaps, err := store.Gaps("ETHUSDT", "1h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gaps) != 1 || gaps[0].From != 3*3600000 || gaps[0].To != 5*3600000 || gaps[0].Missing != 3 {
+		t.Errorf("Expected one gap of 3 bars, got %+v", gaps)
+	}
+}
+
+// TestKlineStoreBackfill tests that asking for more bars than stored downloads the older history once
+func TestKlineStoreBackfill(t *testing.T) {
+	source := &fakeKlineSource{candles: hourlyCandles(0, 31)} // Bar 30 is still forming
+
+	store, err := NewKlineStore(t.TempDir(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.InitialBars = 5
+	store.now = func() time.Time { return time.UnixMilli(30*3600000 + 60000) }
+
+	if candles, err := store.Candles("BTCUSDT", "1h", 5); err != nil || len(candles) != 5 {
+		t.Fatalf("Expected 5 bars, got %d (%v)", len(candles), err)
+	}
+
+	candles, err := store.Candles("BTCUSDT", "1h", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 20 || candles[0].OpenTime != 10*3600000 || candles[19].OpenTime != 29*3600000 {
+		t.Fatalf("Expected bars 10 to 29, got %d bars from %d", len(candles), candles[0].OpenTime)
+	}
+	if store.InitialBars != 5 {
+		t.Errorf("Expected InitialBars left at 5, got %d", store.InitialBars)
+	}
+
+	// Only 30 closed bars exist; once that is known no more requests are made
+	if candles, _ := store.Candles("BTCUSDT", "1h", 100); len(candles) != 30 {
+		t.Errorf("Expected all 30 closed bars, got %d", len(candles))
+	}
+	requests := source.requests
+	if candles, _ := store.Candles("BTCUSDT", "1h", 100); len(candles) != 30 || source.requests != requests {
+		t.Errorf("Expected 30 bars without a request, got %d after %d requests", len(candles), source.requests-requests)
+	}
+}
@@ -0,0 +1,331 @@
+package trading
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// streamsPerConnection keeps combined stream URLs well under Binance's 200 stream limit
+const streamsPerConnection = 100
+
+// CandleCloseEvent is emitted when a kline closes on the stream
+type CandleCloseEvent struct {
+	Symbol     string
+	Interval   string
+	Candle     Candle
+	Backfilled bool // Recovered through REST after a reconnect or missed message
+}
+
+// KlineStream subscribes to Binance futures kline streams and keeps a rolling
+// buffer of closed candles per symbol. Missed bars are backfilled through the
+// REST source after reconnects, so the buffers never have holes.
+type KlineStream struct {
+	source     KlineSource
+	symbols    []string
+	interval   string
+	bufferSize int
+
+	ReconnectDelay    time.Duration // First reconnect delay, doubled on each failure
+	MaxReconnectDelay time.Duration
+	SettleTime        time.Duration // How long NextBatch waits for other symbols closing at the same time
+
+	mu      sync.RWMutex
+	step    int64
+	buffers map[string][]Candle
+	events  chan CandleCloseEvent
+
+	serve func(map[string]string, futures.WsKlineHandler, futures.ErrHandler) (chan struct{}, chan struct{}, error)
+	now   func() time.Time
+}
+
+// NewKlineStream creates a stream for symbols on interval that keeps bufferSize closed bars each
+func NewKlineStream(source KlineSource, symbols []string, interval string, bufferSize int) (*KlineStream, error) {
+	duration, err := IntervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KlineStream{
+		source:            source,
+		symbols:           symbols,
+		interval:          interval,
+		bufferSize:        bufferSize,
+		ReconnectDelay:    time.Second,
+		MaxReconnectDelay: time.Minute,
+		SettleTime:        2 * time.Second,
+		step:              duration.Milliseconds(),
+		buffers:           make(map[string][]Candle),
+		events:            make(chan CandleCloseEvent, len(symbols)*4+64),
+		serve:             futures.WsCombinedKlineServe,
+		now:               time.Now,
+	}, nil
+}
+
+// Start loads the buffers through REST and connects the streams until ctx is cancelled
+func (ks *KlineStream) Start(ctx context.Context) error {
+	for _, symbol := range ks.symbols {
+		if err := ks.backfill(symbol, false); err != nil {
+			log.Printf("⚠️  Failed to load klines for %s: %v", symbol, err)
+		}
+	}
+
+	for start := 0; start < len(ks.symbols); start += streamsPerConnection {
+		end := start + streamsPerConnection
+		if end > len(ks.symbols) {
+			end = len(ks.symbols)
+		}
+		go ks.runConnection(ctx, ks.symbols[start:end])
+	}
+
+	log.Printf("📡 Kline stream started: %d symbols on %s", len(ks.symbols), ks.interval)
+	return nil
+}
+
+// Events returns the channel of candle close events
+func (ks *KlineStream) Events() <-chan CandleCloseEvent {
+	return ks.events
+}
+
+// NextBatch waits for the next candle close and returns every symbol that
+// closed within SettleTime of it
+func (ks *KlineStream) NextBatch(ctx context.Context) ([]string, error) {
+	var symbols []string
+	seen := make(map[string]bool)
+	add := func(event CandleCloseEvent) {
+		if !seen[event.Symbol] {
+			seen[event.Symbol] = true
+			symbols = append(symbols, event.Symbol)
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case event := <-ks.events:
+		add(event)
+	}
+
+	settle := time.NewTimer(ks.SettleTime)
+	defer settle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return symbols, ctx.Err()
+		case event := <-ks.events:
+			add(event)
+		case <-settle.C:
+			return symbols, nil
+		}
+	}
+}
+
+// Candles returns a copy of the closed candles buffered for symbol
+func (ks *KlineStream) Candles(symbol string) []Candle {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return append([]Candle(nil), ks.buffers[symbol]...)
+}
+
+// GetKlines returns the latest limit closed bars, never the forming one. They
+// come from the buffer, or from the REST source for other intervals, when the
+// buffer is too short or for limit <= 0 (the source's default count).
+func (ks *KlineStream) GetKlines(symbol string, interval string, limit int) ([][]interface{}, error) {
+	ks.mu.RLock()
+	candles := ks.buffers[symbol]
+	if interval != ks.interval || limit <= 0 || len(candles) < limit {
+		ks.mu.RUnlock()
+		return ks.closedKlines(symbol, interval, limit)
+	}
+
+	candles = candles[len(candles)-limit:]
+	rows := make([][]interface{}, len(candles))
+	for i, candle := range candles {
+		rows[i] = candle.Row()
+	}
+	ks.mu.RUnlock()
+
+	return rows, nil
+}
+
+// closedKlines fetches limit bars from the REST source, asking for one more
+// to make up for the forming bar that is dropped
+func (ks *KlineStream) closedKlines(symbol string, interval string, limit int) ([][]interface{}, error) {
+	request := limit
+	if limit > 0 {
+		request = min(limit+1, maxKlinesPerRequest)
+	}
+	rows, err := ks.source.GetKlines(symbol, interval, request)
+	if err != nil {
+		return nil, err
+	}
+
+	now := ks.now().UnixMilli()
+	closed := make([][]interface{}, 0, len(rows))
+	for _, row := range rows {
+		candle, err := ParseCandle(row)
+		if err != nil {
+			return nil, err
+		}
+		if candle.CloseTime < now {
+			closed = append(closed, row)
+		}
+	}
+	if limit > 0 && len(closed) > limit {
+		closed = closed[len(closed)-limit:]
+	}
+	return closed, nil
+}
+
+// runConnection keeps one combined stream connected, reconnecting with backoff
+func (ks *KlineStream) runConnection(ctx context.Context, symbols []string) {
+	streams := make(map[string]string, len(symbols))
+	for _, symbol := range symbols {
+		streams[symbol] = ks.interval
+	}
+
+	delay := ks.ReconnectDelay
+	connected := false
+	for {
+		doneC, stopC, err := ks.serve(streams, ks.handleEvent, func(err error) {
+			log.Printf("⚠️  Kline stream error: %v", err)
+		})
+		if err != nil {
+			log.Printf("❌ Failed to connect kline stream: %v (retrying in %s)", err, delay)
+		} else {
+			if connected {
+				// Recover bars that closed while disconnected
+				log.Printf("🔌 Kline stream reconnected, backfilling %d symbols", len(symbols))
+				for _, symbol := range symbols {
+					if err := ks.backfill(symbol, true); err != nil {
+						log.Printf("⚠️  Failed to backfill %s: %v", symbol, err)
+					}
+				}
+			}
+			connected = true
+			delay = ks.ReconnectDelay
+
+			select {
+			case <-ctx.Done():
+				close(stopC)
+				return
+			case <-doneC:
+				log.Printf("🔌 Kline stream disconnected, reconnecting in %s", delay)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > ks.MaxReconnectDelay {
+			delay = ks.MaxReconnectDelay
+		}
+	}
+}
+
+// handleEvent buffers a closed kline, backfilling first if bars were skipped
+func (ks *KlineStream) handleEvent(event *futures.WsKlineEvent) {
+	if !event.Kline.IsFinal {
+		return
+	}
+
+	candle := Candle{
+		OpenTime:  event.Kline.StartTime,
+		Open:      parseFloat(event.Kline.Open),
+		High:      parseFloat(event.Kline.High),
+		Low:       parseFloat(event.Kline.Low),
+		Close:     parseFloat(event.Kline.Close),
+		Volume:    parseFloat(event.Kline.Volume),
+		CloseTime: event.Kline.EndTime,
+	}
+
+	ks.mu.RLock()
+	buffer := ks.buffers[event.Symbol]
+	missed := len(buffer) > 0 && candle.OpenTime > buffer[len(buffer)-1].OpenTime+ks.step
+	ks.mu.RUnlock()
+
+	if missed {
+		if err := ks.backfill(event.Symbol, false); err != nil {
+			log.Printf("⚠️  Failed to backfill %s: %v", event.Symbol, err)
+		}
+	}
+
+	// The backfill may already include this candle
+	if ks.append(event.Symbol, []Candle{candle}) > 0 || missed {
+		ks.emit(CandleCloseEvent{Symbol: event.Symbol, Interval: ks.interval, Candle: candle})
+	}
+}
+
+// backfill fetches the closed bars missing after the buffer through REST.
+// Only the newest recovered bar is emitted, and only when emit is set.
+func (ks *KlineStream) backfill(symbol string, emit bool) error {
+	now := ks.now().UnixMilli()
+	limit := ks.bufferSize + 1
+
+	ks.mu.RLock()
+	buffer := ks.buffers[symbol]
+	if len(buffer) > 0 {
+		last := buffer[len(buffer)-1]
+		if now <= last.CloseTime+ks.step {
+			ks.mu.RUnlock()
+			return nil
+		}
+		// Missed bars plus the forming one
+		limit = int((now-last.OpenTime)/ks.step) + 1
+	}
+	ks.mu.RUnlock()
+
+	if limit > ks.bufferSize+1 {
+		limit = ks.bufferSize + 1
+	}
+	rows, err := ks.source.GetKlines(symbol, ks.interval, limit)
+	if err != nil {
+		return err
+	}
+
+	candles, err := parseClosedCandles(rows, 0, now)
+	if err != nil {
+		return err
+	}
+	if ks.append(symbol, candles) > 0 && emit {
+		ks.emit(CandleCloseEvent{Symbol: symbol, Interval: ks.interval, Candle: candles[len(candles)-1], Backfilled: true})
+	}
+	return nil
+}
+
+// append adds candles newer than the buffer, trims it and returns how many were added
+func (ks *KlineStream) append(symbol string, candles []Candle) int {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	buffer := ks.buffers[symbol]
+	added := 0
+	for _, candle := range candles {
+		if len(buffer) > 0 && candle.OpenTime <= buffer[len(buffer)-1].OpenTime {
+			continue
+		}
+		buffer = append(buffer, candle)
+		added++
+	}
+
+	if len(buffer) > ks.bufferSize {
+		buffer = append([]Candle(nil), buffer[len(buffer)-ks.bufferSize:]...)
+	}
+	ks.buffers[symbol] = buffer
+	return added
+}
+
+// emit queues an event without blocking the websocket reader
+func (ks *KlineStream) emit(event CandleCloseEvent) {
+	select {
+	case ks.events <- event:
+	default:
+		log.Printf("⚠️  Candle close event dropped for %s: consumer is too slow", event.Symbol)
+	}
+}
@@ -0,0 +1,93 @@
+package trading
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// wsKline builds a websocket kline event from a candle
+func wsKline(symbol string, candle Candle, final bool) *futures.WsKlineEvent {
+	price := strconv.FormatFloat(candle.Close, 'f', -1, 64)
+	return &futures.WsKlineEvent{
+		Symbol: symbol,
+		Kline: futures.WsKline{
+			StartTime: candle.OpenTime,
+			EndTime:   candle.CloseTime,
+			Open:      price,
+			High:      price,
+			Low:       price,
+			Close:     price,
+			Volume:    "1",
+			IsFinal:   final,
+		},
+	}
+}
+
+// TestKlineStreamBuffersAndBackfills tests closed candle events, buffer trimming and REST backfill of skipped bars
+func TestKlineStreamBuffersAndBackfills(t *testing.T) {
+	source := &fakeKlineSource{candles: hourlyCandles(0, 11)}
+	now := time.UnixMilli(10*3600000 + 60000)
+
+	stream, err := NewKlineStream(source, []string{"BTCUSDT"}, "1h", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.now = func() time.Time { return now }
+	stream.SettleTime = 10 * time.Millisecond
+
+	handlers := make(chan futures.WsKlineHandler, 1)
+	stream.serve = func(streams map[string]string, h futures.WsKlineHandler, errHandler futures.ErrHandler) (chan struct{}, chan struct{}, error) {
+		handlers <- h
+		return make(chan struct{}), make(chan struct{}), nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream.Start(ctx)
+	handler := <-handlers
+
+	if candles := stream.Candles("BTCUSDT"); len(candles) != 5 || candles[4].OpenTime != 9*3600000 {
+		t.Fatalf("Expected bars 5-9 after initial load, got %+v", candles)
+	}
+
+	// Forming updates are ignored, the close is buffered and emitted
+	bar10 := hourlyCandles(10, 1)[0]
+	handler(wsKline("BTCUSDT", bar10, false))
+	handler(wsKline("BTCUSDT", bar10, true))
+
+	closed, err := stream.NextBatch(ctx)
+	if err != nil || len(closed) != 1 || closed[0] != "BTCUSDT" {
+		t.Fatalf("Expected BTCUSDT close event, got %v (%v)", closed, err)
+	}
+
+	// Bar 11 is never received; bar 12 triggers a REST backfill
+	source.candles = hourlyCandles(0, 14)
+	now = time.UnixMilli(13*3600000 + 1000)
+	handler(wsKline("BTCUSDT", hourlyCandles(12, 1)[0], true))
+
+	candles := stream.Candles("BTCUSDT")
+	if len(candles) != 5 || candles[3].OpenTime != 11*3600000 || candles[4].OpenTime != 12*3600000 {
+		t.Fatalf("Expected backfilled bars 8-12, got %+v", candles)
+	}
+
+	rows, err := stream.GetKlines("BTCUSDT", "1h", 3)
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("Expected 3 buffered rows, got %d (%v)", len(rows), err)
+	}
+	requests := source.requests
+	rows, err = stream.GetKlines("BTCUSDT", "1h", 10)
+	if source.requests != requests+1 {
+		t.Error("Expected REST fallback for a request larger than the buffer")
+	}
+	// The fallback drops the forming bar 13 like the buffer does
+	if last, _ := ParseCandle(rows[len(rows)-1]); err != nil || len(rows) != 10 || last.OpenTime != 12*3600000 {
+		t.Errorf("Expected closed bars 3-12 from REST, got %d rows ending at %d (%v)", len(rows), last.OpenTime, err)
+	}
+	if rows, err := stream.GetKlines("BTCUSDT", "1h", 0); err != nil || len(rows) != 13 {
+		t.Errorf("Expected all 13 closed bars from REST for limit 0, got %d (%v)", len(rows), err)
+	}
+}
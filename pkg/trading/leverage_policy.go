@@ -0,0 +1,193 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+)
+
+// LeverageConfig configures how leverage follows the volatility of a symbol
+type LeverageConfig struct {
+	MinLeverage    int
+	MaxLeverage    int
+	ATRPeriod      int     // Candles in the ATR
+	ATRMultiple    float64 // Adverse move assumed in ATRs, when wider than the stop
+	SafetyMultiple float64 // How many adverse moves away the liquidation price is kept
+	Interval       string  // Kline interval for the ATR
+}
+
+// DefaultLeverageConfig keeps the liquidation price three times the wider of
+// the stop and 2 ATR(14) on the 1h candles away, between 1x and 10x
+func DefaultLeverageConfig() LeverageConfig {
+	return LeverageConfig{
+		MinLeverage:    1,
+		MaxLeverage:    10,
+		ATRPeriod:      14,
+		ATRMultiple:    2.0,
+		SafetyMultiple: 3.0,
+		Interval:       "1h",
+	}
+}
+
+// LeverageConfigFromEnv returns the default config overridden by
+// MIN_LEVERAGE, MAX_LEVERAGE, LEVERAGE_ATR_PERIOD, LEVERAGE_ATR_MULTIPLE,
+// LEVERAGE_SAFETY_MULTIPLE and LEVERAGE_INTERVAL
+func LeverageConfigFromEnv() LeverageConfig {
+	cfg := DefaultLeverageConfig()
+	if leverage, err := strconv.Atoi(os.Getenv("MIN_LEVERAGE")); err == nil && leverage >= 1 && leverage <= 125 {
+		cfg.MinLeverage = leverage
+	}
+	if leverage, err := strconv.Atoi(os.Getenv("MAX_LEVERAGE")); err == nil && leverage >= 1 && leverage <= 125 {
+		cfg.MaxLeverage = leverage
+	}
+	if cfg.MaxLeverage < cfg.MinLeverage {
+		cfg.MaxLeverage = cfg.MinLeverage
+	}
+	if period, err := strconv.Atoi(os.Getenv("LEVERAGE_ATR_PERIOD")); err == nil && period > 0 {
+		cfg.ATRPeriod = period
+	}
+	if multiple, err := strconv.ParseFloat(os.Getenv("LEVERAGE_ATR_MULTIPLE"), 64); err == nil && multiple >= 0 {
+		cfg.ATRMultiple = multiple
+	}
+	if multiple, err := strconv.ParseFloat(os.Getenv("LEVERAGE_SAFETY_MULTIPLE"), 64); err == nil && multiple > 0 {
+		cfg.SafetyMultiple = multiple
+	}
+	if interval := os.Getenv("LEVERAGE_INTERVAL"); interval != "" {
+		cfg.Interval = interval
+	}
+	return cfg
+}
+
+// LeverageRequest describes the entry to choose leverage for
+type LeverageRequest struct {
+	Symbol   string
+	Entry    float64
+	Stop     float64
+	Notional float64 // Intended position notional; 0 to skip the bracket check
+}
+
+// LeverageChoice is the selected leverage and why
+type LeverageChoice struct {
+	Leverage  int
+	ATR       float64 // 0 when the candles were unavailable
+	LimitedBy string  // volatility, min leverage, max leverage or bracket
+}
+
+// LeveragePolicy selects per-symbol leverage from volatility and the stop
+// distance, and applies it only when it changes
+type LeveragePolicy struct {
+	ex     Exchange
+	config LeverageConfig
+}
+
+// NewLeveragePolicy creates a policy for positions on ex
+func NewLeveragePolicy(ex Exchange, config LeverageConfig) *LeveragePolicy {
+	return &LeveragePolicy{ex: ex, config: config}
+}
+
+// Config returns the policy's configuration
+func (p *LeveragePolicy) Config() LeverageConfig {
+	return p.config
+}
+
+// Select returns the leverage whose liquidation distance of about 1/leverage
+// is SafetyMultiple times the wider of the stop distance and ATRMultiple ATRs,
+// clamped to MinLeverage and MaxLeverage and lowered until the leverage
+// bracket allows Notional
+func (p *LeveragePolicy) Select(ctx context.Context, req LeverageRequest) (*LeverageChoice, error) {
+	if req.Entry <= 0 || req.Entry == req.Stop {
+		return nil, fmt.Errorf("invalid entry %s and stop %s for %s", formatFloat(req.Entry), formatFloat(req.Stop), req.Symbol)
+	}
+
+	choice := &LeverageChoice{LimitedBy: "volatility"}
+	move := math.Abs(req.Entry-req.Stop) / req.Entry
+	reason := fmt.Sprintf("%.2f%% stop distance", move*100)
+	if atr, err := p.atr(req.Symbol); err != nil {
+		log.Printf("⚠️  Selecting %s leverage from the stop distance only: %v", req.Symbol, err)
+	} else {
+		choice.ATR = atr
+		if atrMove := p.config.ATRMultiple * atr / req.Entry; atrMove > move {
+			move = atrMove
+			reason = fmt.Sprintf("%.1f ATR of %s (%.2f%%)", p.config.ATRMultiple, formatFloat(atr), move*100)
+		}
+	}
+
+	leverage := int(1 / (p.config.SafetyMultiple * move))
+	if leverage > p.config.MaxLeverage {
+		leverage, choice.LimitedBy = p.config.MaxLeverage, "max leverage"
+	}
+	if leverage < p.config.MinLeverage {
+		leverage, choice.LimitedBy = p.config.MinLeverage, "min leverage"
+	}
+
+	brackets, err := p.ex.GetLeverageBrackets(ctx, req.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leverage brackets: %w", err)
+	}
+	if len(brackets) > 0 {
+		bounded := leverage
+		for bounded > p.config.MinLeverage {
+			if maxNotional, ok := MaxNotional(brackets, bounded); ok && maxNotional >= req.Notional {
+				break
+			}
+			bounded--
+		}
+		if bounded != leverage {
+			leverage, choice.LimitedBy = bounded, "bracket"
+		}
+	}
+	choice.Leverage = leverage
+
+	log.Printf("📐 %s leverage %dx (limited by %s): adverse move %s, liquidation kept %.1fx as far, %.2f USDT notional",
+		req.Symbol, leverage, choice.LimitedBy, reason, p.config.SafetyMultiple, req.Notional)
+	return choice, nil
+}
+
+// Apply sets the leverage of symbol, calling ChangeLeverage only when it
+// differs from the current one
+func (p *LeveragePolicy) Apply(symbol string, leverage int) error {
+	current, getErr := p.ex.GetLeverage(symbol)
+	if getErr == nil && current == leverage {
+		return nil
+	}
+
+	if err := p.ex.ChangeLeverage(symbol, leverage); err != nil {
+		return fmt.Errorf("failed to set leverage to %dx for %s: %w", leverage, symbol, err)
+	}
+	if getErr == nil {
+		log.Printf("📐 Changed %s leverage from %dx to %dx", symbol, current, leverage)
+	} else {
+		log.Printf("📐 Set %s leverage to %dx", symbol, leverage)
+	}
+	return nil
+}
+
+// atr returns the ATR of the closed candles of symbol
+func (p *LeveragePolicy) atr(symbol string) (float64, error) {
+	klines, err := p.ex.GetKlines(symbol, p.config.Interval, p.config.ATRPeriod*3+1)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get klines: %w", err)
+	}
+
+	now := time.Now().UnixMilli()
+	var candles []Candle
+	for _, kline := range klines {
+		candle, err := ParseCandle(kline)
+		if err != nil {
+			return 0, err
+		}
+		if candle.CloseTime < now {
+			candles = append(candles, candle)
+		}
+	}
+
+	atr := ATR(candles, p.config.ATRPeriod)
+	if atr <= 0 {
+		return 0, fmt.Errorf("%d closed candles are too few for ATR(%d)", len(candles), p.config.ATRPeriod)
+	}
+	return atr, nil
+}
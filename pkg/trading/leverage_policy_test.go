@@ -0,0 +1,124 @@
+package trading
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// leverageCountingExchange counts the leverage changes sent to a paper exchange
+type leverageCountingExchange struct {
+	*PaperExchange
+	changes int
+}
+
+func (le *leverageCountingExchange) ChangeLeverage(symbol string, leverage int) error {
+	le.changes++
+	return le.PaperExchange.ChangeLeverage(symbol, leverage)
+}
+
+// TestLeveragePolicySelect tests that leverage follows the wider of the stop and the ATR, within the brackets
+func TestLeveragePolicySelect(t *testing.T) {
+	tiers := []LeverageBracket{
+		{Bracket: 1, InitialLeverage: 125, NotionalCap: 2000},
+		{Bracket: 2, InitialLeverage: 5, NotionalFloor: 2000, NotionalCap: 50000},
+	}
+
+	tests := []struct {
+		name      string
+		symbol    string
+		spread    float64 // High − low of every candle, which is the ATR
+		brackets  []LeverageBracket
+		stop      float64
+		notional  float64
+		leverage  int
+		limitedBy string
+	}{
+		{"calm market with a tight stop", "BTCUSDT", 0.2, nil, 98, 0, 10, "max leverage"},
+		{"wide stop", "BTCUSDT", 0.2, nil, 95, 0, 6, "volatility"},
+		{"volatile market", "BTCUSDT", 4, nil, 98, 0, 4, "volatility"},
+		{"extreme volatility", "BTCUSDT", 40, nil, 98, 0, 1, "min leverage"},
+		{"notional above the bracket", "BTCUSDT", 0.2, tiers, 98, 5000, 5, "bracket"},
+		{"notional within the bracket", "BTCUSDT", 0.2, tiers, 98, 1000, 10, "max leverage"},
+		{"no candles", "ETHUSDT", 0.2, nil, 95, 0, 6, "volatility"},
+	}
+
+	for _, tt := range tests {
+		pe := newTestPaperExchange(100)
+		for i := int64(1); i <= 20; i++ {
+			pe.OnCandle("BTCUSDT", Candle{OpenTime: i * 3600000, Open: 100, High: 100 + tt.spread/2, Low: 100 - tt.spread/2, Close: 100, CloseTime: (i+1)*3600000 - 1})
+		}
+		policy := NewLeveragePolicy(&bracketExchange{PaperExchange: pe, brackets: tt.brackets}, DefaultLeverageConfig())
+
+		choice, err := policy.Select(context.Background(), LeverageRequest{Symbol: tt.symbol, Entry: 100, Stop: tt.stop, Notional: tt.notional})
+		if err != nil {
+			t.Errorf("%s: Select failed: %v", tt.name, err)
+			continue
+		}
+		if choice.Leverage != tt.leverage || choice.LimitedBy != tt.limitedBy {
+			t.Errorf("%s: expected %dx limited by %s, got %dx limited by %s", tt.name, tt.leverage, tt.limitedBy, choice.Leverage, choice.LimitedBy)
+		}
+	}
+}
+
+// TestLeveragePolicyApply tests that leverage is only changed when it differs
+func TestLeveragePolicyApply(t *testing.T) {
+	ex := &leverageCountingExchange{PaperExchange: newTestPaperExchange(100)}
+	policy := NewLeveragePolicy(ex, DefaultLeverageConfig())
+
+	for _, leverage := range []int{5, 5, 3, 3} {
+		if err := policy.Apply("BTCUSDT", leverage); err != nil {
+			t.Fatalf("Apply %dx failed: %v", leverage, err)
+		}
+	}
+	if ex.changes != 2 {
+		t.Errorf("Expected 2 leverage changes, got %d", ex.changes)
+	}
+	if leverage, _ := ex.GetLeverage("BTCUSDT"); leverage != 3 {
+		t.Errorf("Expected 3x, got %dx", leverage)
+	}
+}
+
+// TestChangeLeverageCached tests that the client sends nothing when the cached leverage matches
+func TestChangeLeverageCached(t *testing.T) {
+	tc := &TradingClient{leverages: map[string]cachedLeverage{"BTCUSDT": {leverage: 5, at: time.Now()}}}
+
+	if err := tc.ChangeLeverage("BTCUSDT", 5); err != nil {
+		t.Errorf("Expected no request for an unchanged leverage, got %v", err)
+	}
+	if leverage, err := tc.GetLeverage("BTCUSDT"); err != nil || leverage != 5 {
+		t.Errorf("Expected the cached 5x, got %dx (%v)", leverage, err)
+	}
+}
+
+// TestChangeLeverageRecordsUnloadedCache tests that a leverage change is cached
+// before GetLeverage ever loaded the cache
+func TestChangeLeverageRecordsUnloadedCache(t *testing.T) {
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"leverage":5,"maxNotionalValue":"1000000","symbol":"BTCUSDT"}`))
+	}))
+	defer server.Close()
+
+	client := futures.NewClient("key", "secret")
+	client.BaseURL = server.URL
+	tc := &TradingClient{BinanceClient: client, Retry: RetryConfig{MaxAttempts: 1}}
+
+	for i := 0; i < 2; i++ {
+		if err := tc.ChangeLeverage("BTCUSDT", 5); err != nil {
+			t.Fatalf("ChangeLeverage failed: %v", err)
+		}
+	}
+	if leverage, err := tc.GetLeverage("BTCUSDT"); err != nil || leverage != 5 {
+		t.Errorf("Expected the cached 5x, got %dx (%v)", leverage, err)
+	}
+	if requests["/fapi/v1/leverage"] != 1 || len(requests) != 1 {
+		t.Errorf("Expected one leverage change and nothing else, got %v", requests)
+	}
+}
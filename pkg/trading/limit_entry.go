@@ -0,0 +1,294 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LimitEntryConfig configures entering with a resting LIMIT order at the
+// breakout or retest level instead of a market order
+type LimitEntryConfig struct {
+	Enabled      bool
+	PostOnly     bool          // Send entries as GTX so they are rejected rather than fill as taker
+	ExpiryBars   int           // Bars an entry may rest unfilled before it is cancelled
+	Interval     string        // Kline interval the bars are counted in
+	PollInterval time.Duration // How often Run checks the pending entries
+}
+
+// DefaultLimitEntryConfig cancels GTC entries that are not filled within 3
+// hourly bars. Limit entries are off by default.
+func DefaultLimitEntryConfig() LimitEntryConfig {
+	return LimitEntryConfig{
+		ExpiryBars:   3,
+		Interval:     "1h",
+		PollInterval: 5 * time.Second,
+	}
+}
+
+// LimitEntryConfigFromEnv returns the default config overridden by
+// LIMIT_ENTRY (true to enable), LIMIT_ENTRY_POST_ONLY and LIMIT_ENTRY_EXPIRY_BARS
+func LimitEntryConfigFromEnv() LimitEntryConfig {
+	cfg := DefaultLimitEntryConfig()
+	cfg.Enabled, _ = strconv.ParseBool(os.Getenv("LIMIT_ENTRY"))
+	cfg.PostOnly, _ = strconv.ParseBool(os.Getenv("LIMIT_ENTRY_POST_ONLY"))
+	if bars, err := strconv.Atoi(os.Getenv("LIMIT_ENTRY_EXPIRY_BARS")); err == nil && bars > 0 {
+		cfg.ExpiryBars = bars
+	}
+	return cfg
+}
+
+// PendingEntry is a LIMIT entry waiting to fill. Its protection is built for
+// the filled quantity only once it fills.
+type PendingEntry struct {
+	Entry        *OrderRequest // LIMIT order with a client order ID
+	Invalidation float64       // Price at or beyond which the setup is void: below for BUY, above for SELL; 0 for none
+	Expires      time.Time     // Set from ExpiryBars when zero
+
+	// Protection builds the stop loss and take profits for the filled quantity
+	Protection func(filled float64) (stopLoss *OrderRequest, takeProfits []*OrderRequest, err error)
+
+	// OnFilled is called with the bracket once its protection is attached
+	OnFilled func(*Bracket)
+
+	OrderID int64
+}
+
+// LimitEntryManager places LIMIT entries and follows them until they fill,
+// expire or are invalidated by the price. A filled entry gets its stop loss
+// and take profits with AttachProtection; a partial fill cancels the rest so
+// the position is protected at once.
+type LimitEntryManager struct {
+	ex     Exchange
+	config LimitEntryConfig
+
+	mu      sync.Mutex
+	pending map[string]*PendingEntry // By positionKey of the side the entry opens
+}
+
+// NewLimitEntryManager creates a manager for limit entries on ex
+func NewLimitEntryManager(ex Exchange, config LimitEntryConfig) *LimitEntryManager {
+	return &LimitEntryManager{
+		ex:      ex,
+		config:  config,
+		pending: make(map[string]*PendingEntry),
+	}
+}
+
+// Place sends a pending entry's LIMIT order. An entry already placed under
+// the same client order ID, by the run before a restart for example, is
+// followed again instead of resent and ErrDuplicateSignal is returned.
+func (m *LimitEntryManager) Place(ctx context.Context, entry PendingEntry) error {
+	request := entry.Entry
+	if request.Type != "LIMIT" || request.ClientOrderID == "" {
+		return fmt.Errorf("limit entries need a LIMIT order with a client order ID")
+	}
+
+	side := orderDirection(request.Side)
+	key := positionKey(request.Symbol, side)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.pending[key]; ok {
+		return fmt.Errorf("a limit entry for %s is already pending", sideLabel(request.Symbol, side))
+	}
+	if entry.Expires.IsZero() {
+		bar, err := IntervalDuration(m.config.Interval)
+		if err != nil {
+			return err
+		}
+		entry.Expires = time.Now().Add(time.Duration(m.config.ExpiryBars) * bar)
+	}
+
+	existing, err := findOrder(ctx, m.ex, request)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		entry.OrderID = existing.OrderID
+		m.pending[key] = &entry
+		log.Printf("♻️  Limit entry %s is already placed (order %d), following it", request.ClientOrderID, existing.OrderID)
+		return fmt.Errorf("%w: %s", ErrDuplicateSignal, request.ClientOrderID)
+	}
+
+	if request.TimeInForce == "" {
+		request.TimeInForce = "GTC"
+		if m.config.PostOnly {
+			request.TimeInForce = "GTX"
+		}
+	}
+	response, err := m.ex.CreateOrder(request)
+	if err != nil {
+		return fmt.Errorf("failed to place limit entry: %w", err)
+	}
+	entry.OrderID = parseOrderID(response.OrderID)
+	m.pending[key] = &entry
+	log.Printf("⏳ Limit entry %s %s %s @ %s until %s (order %d)", request.Side, request.Quantity, request.Symbol,
+		request.Price, entry.Expires.Format("2006-01-02 15:04"), entry.OrderID)
+
+	// A marketable GTC entry fills straight away
+	if response.Status == "FILLED" || response.Status == "PARTIALLY_FILLED" {
+		if done, err := m.check(ctx, &entry); done {
+			delete(m.pending, key)
+			return err
+		}
+	}
+	return nil
+}
+
+// Pending returns a copy of the entries waiting to fill
+func (m *LimitEntryManager) Pending() []PendingEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []PendingEntry
+	for _, entry := range m.pending {
+		entries = append(entries, *entry)
+	}
+	return entries
+}
+
+// Attach checks the pending entries as soon as the stream reports one trading
+func (m *LimitEntryManager) Attach(us *UserDataStream) {
+	us.OnOrderUpdate(func(update OrderUpdate) {
+		if update.ExecutionType != "TRADE" {
+			return
+		}
+		m.mu.Lock()
+		pending := false
+		for _, entry := range m.pending {
+			if entry.Entry.Symbol == update.Order.Symbol && entry.OrderID == update.Order.OrderID {
+				pending = true
+			}
+		}
+		m.mu.Unlock()
+		if pending {
+			go func() {
+				if err := m.Poll(context.Background()); err != nil {
+					log.Printf("⚠️  Limit entry check failed: %v", err)
+				}
+			}()
+		}
+	})
+}
+
+// Run checks the pending entries every PollInterval until ctx is cancelled
+func (m *LimitEntryManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Poll(ctx); err != nil {
+				log.Printf("⚠️  Limit entry check failed: %v", err)
+			}
+		}
+	}
+}
+
+// Poll protects filled entries and cancels expired or invalidated ones
+func (m *LimitEntryManager) Poll(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var lastErr error
+	for key, entry := range m.pending {
+		done, err := m.check(ctx, entry)
+		if err != nil {
+			log.Printf("⚠️  Limit entry for %s: %v", sideLabel(entry.Entry.Symbol, orderDirection(entry.Entry.Side)), err)
+			lastErr = err
+		}
+		if done {
+			delete(m.pending, key)
+		}
+	}
+	return lastErr
+}
+
+// check handles one pending entry and reports whether it is finished with
+func (m *LimitEntryManager) check(ctx context.Context, entry *PendingEntry) (bool, error) {
+	request := entry.Entry
+	order, err := m.ex.GetOrderByClientID(ctx, request.Symbol, request.ClientOrderID)
+	if err != nil {
+		return false, err
+	}
+
+	switch order.Status {
+	case "FILLED":
+		return true, m.protect(ctx, entry, order)
+	case "CANCELED", "EXPIRED", "REJECTED":
+		if order.ExecutedQty > 0 {
+			return true, m.protect(ctx, entry, order)
+		}
+		log.Printf("📕 Limit entry for %s ended %s without filling", request.Symbol, order.Status)
+		return true, nil
+	}
+
+	var reason string
+	if order.Status == "PARTIALLY_FILLED" {
+		reason = "partially filled"
+	} else if time.Now().After(entry.Expires) {
+		reason = fmt.Sprintf("not filled within %d bars", m.config.ExpiryBars)
+	} else if entry.Invalidation > 0 {
+		ticker, err := m.ex.GetTicker(request.Symbol)
+		if err != nil {
+			return false, err
+		}
+		price := parseFloat(ticker.Price)
+		if (request.Side == "BUY" && price <= entry.Invalidation) || (request.Side == "SELL" && price >= entry.Invalidation) {
+			reason = fmt.Sprintf("price %s invalidated the setup at %s", ticker.Price, formatFloat(entry.Invalidation))
+		}
+	}
+	if reason == "" {
+		return false, nil
+	}
+
+	cancelErr := m.ex.CancelOrder(ctx, request.Symbol, order.OrderID)
+
+	// The entry may have filled before the cancel arrived
+	if order, err = m.ex.GetOrderByClientID(ctx, request.Symbol, request.ClientOrderID); err != nil {
+		return false, err
+	}
+	if order.Status == "NEW" || order.Status == "PARTIALLY_FILLED" {
+		return false, fmt.Errorf("failed to cancel entry (%s): %v", reason, cancelErr)
+	}
+	if order.ExecutedQty > 0 {
+		return true, m.protect(ctx, entry, order)
+	}
+	log.Printf("🧹 Cancelled limit entry for %s: %s", request.Symbol, reason)
+	return true, nil
+}
+
+// protect attaches the stop loss and take profits for the filled part of an entry
+func (m *LimitEntryManager) protect(ctx context.Context, entry *PendingEntry, order *Order) error {
+	bracket := &Bracket{
+		Symbol:       order.Symbol,
+		Side:         order.Side,
+		Quantity:     formatFloat(order.ExecutedQty),
+		EntryOrderID: order.OrderID,
+		EntryPrice:   order.AvgPrice,
+	}
+
+	stopLoss, takeProfits, err := entry.Protection(order.ExecutedQty)
+	if err != nil {
+		return rollbackBracket(ctx, m.ex, bracket, err)
+	}
+	if err := AttachProtection(ctx, m.ex, bracket, stopLoss, takeProfits...); err != nil {
+		return err
+	}
+
+	log.Printf("✅ Limit entry for %s filled %s @ %s, stop loss %d and take profits %v attached", order.Symbol,
+		bracket.Quantity, formatFloat(order.AvgPrice), bracket.StopOrderID, bracket.TakeProfitOrderIDs)
+	if entry.OnFilled != nil {
+		entry.OnFilled(bracket)
+	}
+	return nil
+}
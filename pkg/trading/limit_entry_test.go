@@ -0,0 +1,87 @@
+package trading
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// placeLimitLong places a BUY limit at 98, invalidated at 99 or below, with a
+// stop at 95 and a take profit at 110 attached on fill
+func placeLimitLong(t *testing.T, m *LimitEntryManager, filled **Bracket) {
+	err := m.Place(context.Background(), PendingEntry{
+		Entry:        &OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: "98", Quantity: "1", ClientOrderID: "test-BTCUSDT-abc-ENTRY"},
+		Invalidation: 95,
+		Protection: func(quantity float64) (*OrderRequest, []*OrderRequest, error) {
+			size := formatFloat(quantity)
+			return &OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "95", Quantity: size, ReduceOnly: true},
+				[]*OrderRequest{{Symbol: "BTCUSDT", Side: "SELL", Type: "LIMIT", Price: "110", Quantity: size, ReduceOnly: true}},
+				nil
+		},
+		OnFilled: func(bracket *Bracket) { *filled = bracket },
+	})
+	if err != nil {
+		t.Fatalf("Place failed: %v", err)
+	}
+}
+
+// TestLimitEntryAttachesProtection tests that the bracket is attached once the entry fills
+func TestLimitEntryAttachesProtection(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	ctx := context.Background()
+	m := NewLimitEntryManager(pe, DefaultLimitEntryConfig())
+
+	var filled *Bracket
+	placeLimitLong(t, m, &filled)
+
+	if err := m.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if orders, _ := pe.GetOpenOrders(ctx); len(orders) != 1 || filled != nil {
+		t.Fatalf("Expected only the resting entry, got %+v", orders)
+	}
+
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 3600000, Open: 100, High: 100, Low: 97.5, Close: 99, CloseTime: 7199999})
+	if err := m.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+
+	if filled == nil || filled.EntryPrice != 98 || filled.StopOrderID == 0 || len(filled.TakeProfitOrderIDs) != 1 {
+		t.Fatalf("Expected a filled bracket at 98 with its protection, got %+v", filled)
+	}
+	if orders, _ := pe.GetOpenOrders(ctx); len(orders) != 2 {
+		t.Errorf("Expected the stop and take profit, got %+v", orders)
+	}
+	if len(m.Pending()) != 0 {
+		t.Errorf("Expected no pending entries, got %+v", m.Pending())
+	}
+}
+
+// TestLimitEntryCancels tests that entries are cancelled on invalidation and expiry
+func TestLimitEntryCancels(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	ctx := context.Background()
+	m := NewLimitEntryManager(pe, DefaultLimitEntryConfig())
+
+	var filled *Bracket
+	placeLimitLong(t, m, &filled)
+	m.pending[positionKey("BTCUSDT", "LONG")].Invalidation = 99
+
+	// Falls to the invalidation level without reaching the limit
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 3600000, Open: 100, High: 100, Low: 98.5, Close: 99, CloseTime: 7199999})
+	if err := m.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if orders, _ := pe.GetOpenOrders(ctx); len(orders) != 0 || len(m.Pending()) != 0 {
+		t.Fatalf("Expected the invalidated entry to be cancelled, got %+v", orders)
+	}
+
+	placeLimitLong(t, m, &filled)
+	m.pending[positionKey("BTCUSDT", "LONG")].Expires = time.Now().Add(-time.Minute)
+	if err := m.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if orders, _ := pe.GetOpenOrders(ctx); len(orders) != 0 || filled != nil {
+		t.Errorf("Expected the expired entry to be cancelled, got %+v", orders)
+	}
+}
@@ -0,0 +1,139 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+// ErrLiquidationRisk is returned when the stop of an entry is not safely inside its liquidation price
+var ErrLiquidationRisk = errors.New("stop too close to liquidation")
+
+// defaultMaintMarginRatio is assumed when a symbol has no leverage brackets,
+// such as on a paper exchange without a market source
+const defaultMaintMarginRatio = 0.01
+
+// LiquidationPrice returns the isolated margin liquidation price of a new
+// position on side (LONG or SHORT) of quantity at entry and leverage, using
+// the maintenance margin rate and amount of the bracket its notional falls
+// into. Cross margin positions are backed by more of the wallet, so this is
+// their worst case. It returns 0 when a LONG cannot be liquidated.
+func LiquidationPrice(side string, entry, quantity float64, leverage int, brackets []LeverageBracket) float64 {
+	if quantity <= 0 || leverage < 1 {
+		return 0
+	}
+	rate, cum := defaultMaintMarginRatio, 0.0
+	if bracket, ok := BracketFor(brackets, entry*quantity); ok {
+		rate, cum = bracket.MaintMarginRatio, bracket.Cum
+	}
+	margin := entry * quantity / float64(leverage)
+
+	// Binance: (margin + cum − direction × quantity × entry) / (quantity × rate − direction × quantity)
+	direction := 1.0
+	if side == PositionSideShort {
+		direction = -1.0
+	}
+	price := (margin + cum - direction*quantity*entry) / (quantity*rate - direction*quantity)
+	if price < 0 {
+		return 0
+	}
+	return price
+}
+
+// LiquidationConfig configures how far inside the liquidation price a stop must be
+type LiquidationConfig struct {
+	BufferPercent float64 // Minimum gap between the stop and the liquidation price, in percent of the entry
+	LowerLeverage bool    // Lower the leverage until the stop is safe instead of rejecting the entry
+}
+
+// DefaultLiquidationConfig keeps stops 1% of the entry price inside the
+// liquidation price, lowering the leverage when they are not
+func DefaultLiquidationConfig() LiquidationConfig {
+	return LiquidationConfig{
+		BufferPercent: 1.0,
+		LowerLeverage: true,
+	}
+}
+
+// LiquidationConfigFromEnv returns the default config overridden by
+// LIQUIDATION_BUFFER_PERCENT and LIQUIDATION_LOWER_LEVERAGE
+func LiquidationConfigFromEnv() LiquidationConfig {
+	cfg := DefaultLiquidationConfig()
+	if percent, err := strconv.ParseFloat(os.Getenv("LIQUIDATION_BUFFER_PERCENT"), 64); err == nil && percent >= 0 {
+		cfg.BufferPercent = percent
+	}
+	if lower, err := strconv.ParseBool(os.Getenv("LIQUIDATION_LOWER_LEVERAGE")); err == nil {
+		cfg.LowerLeverage = lower
+	}
+	return cfg
+}
+
+// LiquidationRequest describes an entry to check
+type LiquidationRequest struct {
+	Symbol   string
+	Side     string // LONG or SHORT
+	Entry    float64
+	Stop     float64
+	Quantity float64
+	Leverage int
+}
+
+// LiquidationCheck is the leverage an entry may use and where it would be liquidated
+type LiquidationCheck struct {
+	Leverage         int // Requested leverage, or the lower one that makes the stop safe
+	LiquidationPrice float64
+}
+
+// LiquidationGuard checks that entries are stopped out well before they are liquidated
+type LiquidationGuard struct {
+	ex     Exchange
+	config LiquidationConfig
+}
+
+// NewLiquidationGuard creates a guard using the leverage brackets of ex
+func NewLiquidationGuard(ex Exchange, config LiquidationConfig) *LiquidationGuard {
+	return &LiquidationGuard{ex: ex, config: config}
+}
+
+// Check returns the leverage at which the stop of req is at least
+// BufferPercent inside the liquidation price: the requested one, or with
+// LowerLeverage the highest lower one. Otherwise it returns ErrLiquidationRisk.
+func (g *LiquidationGuard) Check(ctx context.Context, req LiquidationRequest) (*LiquidationCheck, error) {
+	brackets, err := g.ex.GetLeverageBrackets(ctx, req.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leverage brackets: %w", err)
+	}
+
+	buffer := req.Entry * g.config.BufferPercent / 100
+	lowest := req.Leverage
+	if g.config.LowerLeverage {
+		lowest = 1
+	}
+
+	var requested float64
+	for leverage := req.Leverage; leverage >= lowest; leverage-- {
+		liquidation := LiquidationPrice(req.Side, req.Entry, req.Quantity, leverage, brackets)
+		if leverage == req.Leverage {
+			requested = liquidation
+		}
+
+		safe := req.Stop-liquidation >= buffer
+		if req.Side == PositionSideShort {
+			safe = liquidation-req.Stop >= buffer
+		}
+		if !safe {
+			continue
+		}
+		if leverage != req.Leverage {
+			log.Printf("📐 Lowering %s leverage from %dx to %dx: stop %s was within %.2f%% of liquidation at %.4f",
+				sideLabel(req.Symbol, req.Side), req.Leverage, leverage, formatFloat(req.Stop), g.config.BufferPercent, requested)
+		}
+		return &LiquidationCheck{Leverage: leverage, LiquidationPrice: liquidation}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s stop %s, liquidation %.4f at %dx, %.2f%% buffer required",
+		ErrLiquidationRisk, sideLabel(req.Symbol, req.Side), formatFloat(req.Stop), requested, req.Leverage, g.config.BufferPercent)
+}
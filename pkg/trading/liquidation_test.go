@@ -0,0 +1,76 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+// TestLiquidationPrice tests the isolated liquidation price against the bracket tiers
+func TestLiquidationPrice(t *testing.T) {
+	brackets := []LeverageBracket{
+		{Bracket: 1, InitialLeverage: 125, NotionalCap: 50000, MaintMarginRatio: 0.004},
+		{Bracket: 2, InitialLeverage: 100, NotionalFloor: 50000, NotionalCap: 250000, MaintMarginRatio: 0.005, Cum: 50},
+	}
+
+	tests := []struct {
+		name     string
+		brackets []LeverageBracket
+		side     string
+		entry    float64
+		quantity float64
+		leverage int
+		expected float64
+	}{
+		{"long", brackets, "LONG", 100, 1, 10, 90 / 0.996},
+		{"short", brackets, "SHORT", 100, 1, 10, 110 / 1.004},
+		{"long in the second tier", brackets, "LONG", 100, 1000, 10, (90000 - 50) / 995.0},
+		{"long at 1x", brackets, "LONG", 100, 1, 1, 0},
+		{"no brackets", nil, "LONG", 100, 1, 10, 90 / 0.99},
+	}
+
+	for _, tt := range tests {
+		if price := LiquidationPrice(tt.side, tt.entry, tt.quantity, tt.leverage, tt.brackets); math.Abs(price-tt.expected) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, price)
+		}
+	}
+}
+
+// TestLiquidationGuard tests that unsafe stops lower the leverage or reject the entry
+func TestLiquidationGuard(t *testing.T) {
+	ex := &bracketExchange{PaperExchange: newTestPaperExchange(100), brackets: []LeverageBracket{
+		{Bracket: 1, InitialLeverage: 125, NotionalCap: 50000, MaintMarginRatio: 0.004},
+	}}
+
+	tests := []struct {
+		name     string
+		side     string
+		stop     float64
+		lower    bool
+		leverage int // 0 when rejected
+	}{
+		{"safe long", "LONG", 98, true, 10},
+		{"long stop near liquidation", "LONG", 91, true, 9},
+		{"short stop beyond liquidation", "SHORT", 110, true, 8},
+		{"rejected without lowering", "LONG", 91, false, 0},
+	}
+
+	for _, tt := range tests {
+		guard := NewLiquidationGuard(ex, LiquidationConfig{BufferPercent: 1, LowerLeverage: tt.lower})
+		check, err := guard.Check(context.Background(), LiquidationRequest{Symbol: "BTCUSDT", Side: tt.side, Entry: 100, Stop: tt.stop, Quantity: 1, Leverage: 10})
+		if tt.leverage == 0 {
+			if !errors.Is(err, ErrLiquidationRisk) {
+				t.Errorf("%s: expected ErrLiquidationRisk, got %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Check failed: %v", tt.name, err)
+			continue
+		}
+		if check.Leverage != tt.leverage {
+			t.Errorf("%s: expected %dx, got %dx (liquidation %v)", tt.name, tt.leverage, check.Leverage, check.LiquidationPrice)
+		}
+	}
+}
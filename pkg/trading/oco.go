@@ -0,0 +1,196 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// OCOSupervisor emulates one-cancels-the-other for the stop loss and take
+// profit orders of each position, which Binance Futures lacks. The exit orders
+// of a symbol are linked to the position they close (the LONG or SHORT one in
+// hedge mode): once the position is flat the
+// remaining ones are cancelled, and while it is open any stop larger than the
+// position (after a partial take profit) is resized to what remains.
+//
+// It is driven by a UserDataStream through Attach, or by polling with Run.
+type OCOSupervisor struct {
+	ex Exchange
+
+	PollInterval time.Duration // How often Run checks open orders and positions
+	FlatGrace    time.Duration // Wait after a position closes so the filling order's update arrives first
+
+	mu     sync.Mutex
+	stream *UserDataStream
+}
+
+// NewOCOSupervisor creates a supervisor that cancels and re-places orders on ex
+func NewOCOSupervisor(ex Exchange) *OCOSupervisor {
+	return &OCOSupervisor{
+		ex:           ex,
+		PollInterval: 10 * time.Second,
+		FlatGrace:    time.Second,
+	}
+}
+
+// Attach drives the supervisor from the stream's order and position updates
+func (s *OCOSupervisor) Attach(us *UserDataStream) {
+	s.mu.Lock()
+	s.stream = us
+	s.mu.Unlock()
+
+	us.OnOrderUpdate(func(update OrderUpdate) {
+		if !isExitOrder(update.Order) {
+			return
+		}
+		// A filled exit order leaves its siblings to cancel; a partial take
+		// profit leaves a stop to resize
+		if update.Order.Status == "FILLED" || update.ExecutionType == "TRADE" {
+			go s.checkStream(update.Order.Symbol)
+		}
+	})
+
+	us.OnPositionUpdate(func(pos Position) {
+		go func(symbol string) {
+			time.Sleep(s.FlatGrace)
+			s.checkStream(symbol)
+		}(pos.Symbol)
+	})
+}
+
+// Run polls open orders and positions every PollInterval until ctx is cancelled
+func (s *OCOSupervisor) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Poll(ctx); err != nil {
+				log.Printf("⚠️  OCO supervisor poll failed: %v", err)
+			}
+		}
+	}
+}
+
+// Poll checks every symbol with open exit orders against its position
+func (s *OCOSupervisor) Poll(ctx context.Context) error {
+	orders, err := s.ex.GetOpenOrders(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get open orders: %w", err)
+	}
+	// Positions are read after orders so a bracket placed in between is not seen as flat
+	positions, err := s.ex.GetPositions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	exits := make(map[exitGroup][]Order)
+	for _, order := range orders {
+		if isExitOrder(order) {
+			group := exitGroup{order.Symbol, exitPositionSide(order)}
+			exits[group] = append(exits[group], order)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for group, groupOrders := range exits {
+		s.supervise(ctx, group.symbol, findPosition(positions, group.symbol, group.side), groupOrders)
+	}
+	return nil
+}
+
+// exitGroup is the position that exit orders close
+type exitGroup struct {
+	symbol string
+	side   string // LONG or SHORT
+}
+
+// checkStream checks a symbol using the stream's local account state
+func (s *OCOSupervisor) checkStream(symbol string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exits := make(map[string][]Order)
+	for _, order := range s.stream.OpenOrders(symbol) {
+		if isExitOrder(order) {
+			side := exitPositionSide(order)
+			exits[side] = append(exits[side], order)
+		}
+	}
+
+	for side, sideOrders := range exits {
+		var position *Position
+		if pos, ok := s.stream.PositionBySide(symbol, side); ok {
+			position = &pos
+		}
+		s.supervise(context.Background(), symbol, position, sideOrders)
+	}
+}
+
+// supervise cancels the exit orders of a flat position, or resizes stops that
+// exceed the open position; position is nil when flat. The caller must hold the lock.
+func (s *OCOSupervisor) supervise(ctx context.Context, symbol string, position *Position, exits []Order) {
+	if position == nil {
+		for _, order := range exits {
+			if err := s.ex.CancelOrder(ctx, symbol, order.OrderID); err != nil {
+				log.Printf("⚠️  Failed to cancel sibling %s order %d for %s: %v", order.Type, order.OrderID, symbol, err)
+				continue
+			}
+			log.Printf("🧹 Cancelled sibling %s order %d for %s after position closed", order.Type, order.OrderID, symbol)
+		}
+		return
+	}
+
+	hasStop := false
+	for _, order := range exits {
+		if !isStopOrder(order) {
+			continue
+		}
+		hasStop = true
+		if order.Type == "STOP_MARKET" && !order.ClosePosition && order.OrigQty > position.PositionAmt+1e-9 {
+			s.resizeStop(ctx, order, position.PositionAmt)
+		}
+	}
+	if !hasStop {
+		log.Printf("⚠️  %s position has take profit orders but no stop loss", symbol)
+	}
+}
+
+// resizeStop replaces stop with one for quantity at the same price
+func (s *OCOSupervisor) resizeStop(ctx context.Context, stop Order, quantity float64) {
+	newID, err := replaceStop(ctx, s.ex, stop, stop.StopPrice, quantity)
+	if err != nil {
+		log.Printf("⚠️  Failed to resize stop %d for %s, keeping it: %v", stop.OrderID, stop.Symbol, err)
+		return
+	}
+	log.Printf("📐 Resized %s stop to %s after partial take profit (order %d replaces %d)", stop.Symbol, formatFloat(quantity), newID, stop.OrderID)
+}
+
+// isExitOrder reports whether order is a stop loss or take profit that only
+// reduces a position: reduce-only in one-way mode, against its position side in hedge mode
+func isExitOrder(order Order) bool {
+	if !order.ReduceOnly && !order.ClosePosition && !closesHedgePosition(order) {
+		return false
+	}
+	switch order.Type {
+	case "LIMIT", "STOP", "STOP_MARKET", "TAKE_PROFIT", "TAKE_PROFIT_MARKET", "TRAILING_STOP_MARKET":
+		return true
+	}
+	return false
+}
+
+// isStopOrder reports whether an exit order is a stop loss
+func isStopOrder(order Order) bool {
+	switch order.Type {
+	case "STOP", "STOP_MARKET", "TRAILING_STOP_MARKET":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package trading
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// TestOCOSupervisorPoll tests stop resizing after a partial take profit and
+// sibling cancellation once the position is flat
+func TestOCOSupervisorPoll(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinPrice: 0.1, MaxPrice: 1000000, TickSize: 0.1, MinQty: 0.001, MaxQty: 1000, StepSize: 0.001}}
+	ctx := context.Background()
+	s := NewOCOSupervisor(pe)
+
+	for _, order := range []*OrderRequest{
+		{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"},
+		{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "95", Quantity: "1", ReduceOnly: true},
+		{Symbol: "BTCUSDT", Side: "SELL", Type: "LIMIT", Price: "110", Quantity: "0.4", ReduceOnly: true},
+	} {
+		if _, err := pe.CreateOrder(order); err != nil {
+			t.Fatalf("Failed to place %s: %v", order.Type, err)
+		}
+	}
+
+	// The take profit fills, leaving 0.6 behind a stop for 1
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 3600000, Open: 100, High: 111, Low: 100, Close: 108, CloseTime: 7199999})
+	if err := s.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+
+	orders, _ := pe.GetOpenOrders(ctx)
+	if len(orders) != 1 || orders[0].Type != "STOP_MARKET" || orders[0].OrigQty != 0.6 || orders[0].StopPrice != 95 {
+		t.Fatalf("Expected the stop resized to 0.6, got %+v", orders)
+	}
+
+	// Closed manually: the stop is cancelled
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "MARKET", Quantity: "0.6", ReduceOnly: true}); err != nil {
+		t.Fatalf("Failed to close position: %v", err)
+	}
+	if err := s.Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if orders, _ := pe.GetOpenOrders(ctx); len(orders) != 0 {
+		t.Errorf("Expected the stop to be cancelled, got %+v", orders)
+	}
+}
+
+// cancelRecorder is a paper exchange that reports cancelled order IDs
+type cancelRecorder struct {
+	*PaperExchange
+	cancelled chan int64
+}
+
+func (cr *cancelRecorder) CancelOrder(ctx context.Context, symbol string, orderID int64) error {
+	cr.cancelled <- orderID
+	return nil
+}
+
+// TestOCOSupervisorStream tests that a take profit fill cancels the stop
+func TestOCOSupervisorStream(t *testing.T) {
+	us := NewUserDataStream(nil)
+	ex := &cancelRecorder{PaperExchange: newTestPaperExchange(100), cancelled: make(chan int64, 2)}
+	s := NewOCOSupervisor(ex)
+	s.FlatGrace = time.Millisecond
+	s.Attach(us)
+
+	orderEvent := func(update futures.WsOrderTradeUpdate) {
+		us.handleEvent(&futures.WsUserDataEvent{
+			Event:                      futures.UserDataEventTypeOrderTradeUpdate,
+			WsUserDataOrderTradeUpdate: futures.WsUserDataOrderTradeUpdate{OrderTradeUpdate: update},
+		})
+	}
+	positionEvent := func(amount string) {
+		us.handleEvent(&futures.WsUserDataEvent{
+			Event: futures.UserDataEventTypeAccountUpdate,
+			WsUserDataAccountUpdate: futures.WsUserDataAccountUpdate{AccountUpdate: futures.WsAccountUpdate{
+				Positions: []futures.WsPosition{{Symbol: "BTCUSDT", Amount: amount, EntryPrice: "100"}},
+			}},
+		})
+	}
+
+	positionEvent("0.01")
+	orderEvent(futures.WsOrderTradeUpdate{
+		Symbol: "BTCUSDT", ID: 1, Side: "SELL", Type: "STOP_MARKET", OriginalType: "STOP_MARKET",
+		Status: "NEW", ExecutionType: "NEW", StopPrice: "95", IsClosingPosition: true,
+	})
+	takeProfit := futures.WsOrderTradeUpdate{
+		Symbol: "BTCUSDT", ID: 2, Side: "SELL", Type: "TAKE_PROFIT_MARKET", OriginalType: "TAKE_PROFIT_MARKET",
+		Status: "NEW", ExecutionType: "NEW", StopPrice: "110", IsClosingPosition: true,
+	}
+	orderEvent(takeProfit)
+
+	// The position closes before the take profit's fill is reported
+	positionEvent("0")
+	takeProfit.Type = "MARKET"
+	takeProfit.Status = "FILLED"
+	takeProfit.ExecutionType = "TRADE"
+	orderEvent(takeProfit)
+
+	select {
+	case id := <-ex.cancelled:
+		if id != 1 {
+			t.Errorf("Expected the stop (1) to be cancelled, got %d", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the stop to be cancelled")
+	}
+}
@@ -0,0 +1,1015 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// PaperConfig configures the simulated paper exchange
+type PaperConfig struct {
+	InitialBalance  float64       // Starting USDT wallet balance
+	MakerFee        float64       // Fee rate for resting LIMIT fills (0.0002 = 0.02%)
+	TakerFee        float64       // Fee rate for MARKET and triggered stop/TP fills
+	Slippage        float64       // Adverse price move applied to taker fills (0.0005 = 0.05%)
+	DefaultLeverage int           // Leverage used until ChangeLeverage is called
+	SyncInterval    string        // Kline interval pulled from the live market to simulate fills
+	Pairs           []TradingPair // Symbol filters served when there is no market source
+}
+
+// DefaultPaperConfig returns Binance-like defaults for paper trading
+func DefaultPaperConfig() PaperConfig {
+	return PaperConfig{
+		InitialBalance:  1000,
+		MakerFee:        0.0002,
+		TakerFee:        0.0004,
+		Slippage:        0.0005,
+		DefaultLeverage: 20,
+		SyncInterval:    "1m",
+	}
+}
+
+// PaperConfigFromEnv returns the default paper config overridden by
+// PAPER_BALANCE, PAPER_MAKER_FEE, PAPER_TAKER_FEE and PAPER_SLIPPAGE
+func PaperConfigFromEnv() PaperConfig {
+	cfg := DefaultPaperConfig()
+
+	overrides := map[string]*float64{
+		"PAPER_BALANCE":   &cfg.InitialBalance,
+		"PAPER_MAKER_FEE": &cfg.MakerFee,
+		"PAPER_TAKER_FEE": &cfg.TakerFee,
+		"PAPER_SLIPPAGE":  &cfg.Slippage,
+	}
+	for key, target := range overrides {
+		if value := os.Getenv(key); value != "" {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				*target = parsed
+			}
+		}
+	}
+
+	return cfg
+}
+
+// PaperTradingEnabled reports whether PAPER_TRADING is set to true
+func PaperTradingEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("PAPER_TRADING"))
+	return enabled
+}
+
+// PaperFill records a simulated execution
+type PaperFill struct {
+	Time        int64   `json:"time"`
+	OrderID     int64   `json:"orderId"`
+	Symbol      string  `json:"symbol"`
+	Side        string  `json:"side"`
+	Type        string  `json:"type"`
+	Price       float64 `json:"price"`
+	Quantity    float64 `json:"quantity"`
+	Fee         float64 `json:"fee"`
+	RealizedPnL float64 `json:"realizedPnl"`
+}
+
+// paperPosition is a one-way mode position; amount is signed (>0 long, <0 short)
+type paperPosition struct {
+	amount     float64
+	entryPrice float64
+	margin     float64
+}
+
+// paperTrail is the state of a resting TRAILING_STOP_MARKET order
+type paperTrail struct {
+	active  bool    // Activation price has been reached
+	extreme float64 // Best price since activation: highest for SELL, lowest for BUY
+}
+
+// PaperExchange is an in-memory Exchange that simulates fills from candles.
+//
+// With a market source (usually a TradingClient) market data is read from the
+// live exchange and fills are simulated from its closed klines. Without one,
+// candles must be fed through OnCandle, which makes it usable for replaying
+// recorded data.
+type PaperExchange struct {
+	mu sync.Mutex
+
+	market  Exchange
+	config  PaperConfig
+	symbols *SymbolRegistry
+
+	wallet      float64
+	leverage    map[string]int
+	marginMode  map[string]string
+	positions   map[string]*paperPosition
+	orders      []*Order
+	placed      map[string]*Order // Every order placed, by client order ID
+	trailing    map[int64]*paperTrail
+	nextOrderID int64
+	lastPrice   map[string]float64
+	candles     map[string][]Candle
+	syncedUntil map[string]int64
+	now         int64
+	fills       []PaperFill
+}
+
+// Ensure PaperExchange satisfies the Exchange interface
+var _ Exchange = (*PaperExchange)(nil)
+
+// NewPaperExchange creates a paper exchange. market may be nil for replay use.
+func NewPaperExchange(market Exchange, config PaperConfig) *PaperExchange {
+	if config.DefaultLeverage <= 0 {
+		config.DefaultLeverage = 20
+	}
+	if config.SyncInterval == "" {
+		config.SyncInterval = "1m"
+	}
+
+	pe := &PaperExchange{
+		market:      market,
+		config:      config,
+		wallet:      config.InitialBalance,
+		leverage:    make(map[string]int),
+		marginMode:  make(map[string]string),
+		positions:   make(map[string]*paperPosition),
+		placed:      make(map[string]*Order),
+		trailing:    make(map[int64]*paperTrail),
+		nextOrderID: 1,
+		lastPrice:   make(map[string]float64),
+		candles:     make(map[string][]Candle),
+		syncedUntil: make(map[string]int64),
+	}
+	if market != nil {
+		pe.symbols = market.Symbols()
+	} else {
+		pe.symbols = NewSymbolRegistry(pe)
+	}
+	return pe
+}
+
+// OnCandle feeds a closed candle for symbol, filling any open orders it touches
+func (pe *PaperExchange) OnCandle(symbol string, candle Candle) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	history := pe.candles[symbol]
+	if n := len(history); n > 0 && history[n-1].OpenTime == candle.OpenTime {
+		history[n-1] = candle
+	} else if n == 0 || history[n-1].OpenTime < candle.OpenTime {
+		pe.candles[symbol] = append(history, candle)
+	}
+
+	pe.processCandle(symbol, candle)
+}
+
+// Fills returns a copy of all simulated executions
+func (pe *PaperExchange) Fills() []PaperFill {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	fills := make([]PaperFill, len(pe.fills))
+	copy(fills, pe.fills)
+	return fills
+}
+
+// Equity returns wallet balance plus unrealized profit
+func (pe *PaperExchange) Equity() float64 {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	return pe.wallet + pe.unrealizedProfit()
+}
+
+// GetKlines returns klines from the market source, or the recorded candles fed
+// through OnCandle (regardless of interval) when there is no market source
+func (pe *PaperExchange) GetKlines(symbol string, interval string, limit int) ([][]interface{}, error) {
+	if pe.market != nil {
+		return pe.market.GetKlines(symbol, interval, limit)
+	}
+
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	history := pe.candles[symbol]
+	if len(history) == 0 {
+		return nil, fmt.Errorf("failed to get klines: no recorded candles for %s", symbol)
+	}
+	if limit > 0 && len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+
+	var result [][]interface{}
+	for _, candle := range history {
+		result = append(result, candle.Row())
+	}
+	return result, nil
+}
+
+// GetTicker returns the last simulated price, or the market ticker
+func (pe *PaperExchange) GetTicker(symbol string) (*TickerPrice, error) {
+	if pe.market != nil {
+		ticker, err := pe.market.GetTicker(symbol)
+		if err != nil {
+			return nil, err
+		}
+		pe.mu.Lock()
+		pe.lastPrice[symbol] = parseFloat(ticker.Price)
+		pe.mu.Unlock()
+		return ticker, nil
+	}
+
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	price, ok := pe.lastPrice[symbol]
+	if !ok {
+		return nil, fmt.Errorf("no ticker data for symbol %s", symbol)
+	}
+	return &TickerPrice{Symbol: symbol, Price: formatFloat(price)}, nil
+}
+
+// GetMarkPrice returns the market's mark price, or the last simulated price
+func (pe *PaperExchange) GetMarkPrice(ctx context.Context, symbol string) (float64, error) {
+	if pe.market != nil {
+		return pe.market.GetMarkPrice(ctx, symbol)
+	}
+
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	price, ok := pe.lastPrice[symbol]
+	if !ok {
+		return 0, fmt.Errorf("no mark price for symbol %s", symbol)
+	}
+	return price, nil
+}
+
+// GetExchangeInfo returns exchange info from the market source or the configured pairs
+func (pe *PaperExchange) GetExchangeInfo(ctx context.Context) (*futures.ExchangeInfo, error) {
+	if pe.market != nil {
+		return pe.market.GetExchangeInfo(ctx)
+	}
+
+	info := &futures.ExchangeInfo{Timezone: "UTC", ServerTime: pe.now}
+	for _, pair := range pe.config.Pairs {
+		info.Symbols = append(info.Symbols, futures.Symbol{
+			Symbol:            pair.Symbol,
+			Status:            "TRADING",
+			BaseAsset:         pair.BaseAsset,
+			QuoteAsset:        pair.QuoteAsset,
+			MarginAsset:       pair.QuoteAsset,
+			PricePrecision:    pair.PricePrecision,
+			QuantityPrecision: pair.QuantityPrecision,
+			Filters: []map[string]interface{}{
+				{"filterType": "PRICE_FILTER", "minPrice": formatFloat(pair.MinPrice), "maxPrice": formatFloat(pair.MaxPrice), "tickSize": formatFloat(pair.TickSize)},
+				{"filterType": "LOT_SIZE", "minQty": formatFloat(pair.MinQty), "maxQty": formatFloat(pair.MaxQty), "stepSize": formatFloat(pair.StepSize)},
+				{"filterType": "MIN_NOTIONAL", "notional": formatFloat(pair.MinNotional)},
+			},
+		})
+	}
+	return info, nil
+}
+
+// Symbols returns the market source's symbol filters or ones built from the configured pairs
+func (pe *PaperExchange) Symbols() *SymbolRegistry {
+	return pe.symbols
+}
+
+// GetUSDTPairs returns USDT pairs from the market source or the configured pairs
+func (pe *PaperExchange) GetUSDTPairs(ctx context.Context) ([]TradingPair, error) {
+	if pe.market != nil {
+		return pe.market.GetUSDTPairs(ctx)
+	}
+	return pe.config.Pairs, nil
+}
+
+// GetUSDTSymbols returns USDT symbols from the market source or the configured pairs
+func (pe *PaperExchange) GetUSDTSymbols() ([]string, error) {
+	if pe.market != nil {
+		return pe.market.GetUSDTSymbols()
+	}
+
+	var symbols []string
+	for _, pair := range pe.config.Pairs {
+		symbols = append(symbols, pair.Symbol)
+	}
+	return symbols, nil
+}
+
+// GetAccountInfoSimple returns the simulated USDT wallet
+func (pe *PaperExchange) GetAccountInfoSimple() (*AccountInfo, error) {
+	pe.syncFromMarket()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	return &AccountInfo{
+		Assets: []AccountAsset{{
+			Asset:         "USDT",
+			WalletBalance: formatFloat(pe.wallet),
+			MarginBalance: formatFloat(pe.wallet + pe.unrealizedProfit()),
+		}},
+	}, nil
+}
+
+// GetUSDTBalance returns the simulated USDT balance
+func (pe *PaperExchange) GetUSDTBalance(ctx context.Context) (*AccountBalance, error) {
+	pe.syncFromMarket()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	unrealized := pe.unrealizedProfit()
+	positionMargin := pe.positionMargin()
+	available := pe.availableBalance()
+
+	return &AccountBalance{
+		Asset:                 "USDT",
+		WalletBalance:         pe.wallet,
+		UnrealizedProfit:      unrealized,
+		MarginBalance:         pe.wallet + unrealized,
+		InitialMargin:         positionMargin,
+		PositionInitialMargin: positionMargin,
+		MaxWithdrawAmount:     available,
+		CrossWalletBalance:    pe.wallet,
+		AvailableBalance:      available,
+	}, nil
+}
+
+// GetPositions returns all simulated non-zero positions
+func (pe *PaperExchange) GetPositions(ctx context.Context) ([]Position, error) {
+	pe.syncFromMarket()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	var symbols []string
+	for symbol, pos := range pe.positions {
+		if pos.amount != 0 {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+
+	var result []Position
+	for _, symbol := range symbols {
+		pos := pe.positions[symbol]
+		markPrice := pe.markPrice(symbol, pos)
+
+		side := "LONG"
+		if pos.amount < 0 {
+			side = "SHORT"
+		}
+
+		result = append(result, Position{
+			Symbol:           symbol,
+			PositionAmt:      math.Abs(pos.amount),
+			EntryPrice:       pos.entryPrice,
+			MarkPrice:        markPrice,
+			UnrealizedProfit: (markPrice - pos.entryPrice) * pos.amount,
+			Leverage:         pe.getLeverage(symbol),
+			Side:             side,
+			PositionSide:     PositionSideBoth,
+		})
+	}
+
+	return result, nil
+}
+
+// GetOpenOrders returns all resting simulated orders
+func (pe *PaperExchange) GetOpenOrders(ctx context.Context) ([]Order, error) {
+	pe.syncFromMarket()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	var result []Order
+	for _, order := range pe.orders {
+		result = append(result, *order)
+	}
+	return result, nil
+}
+
+// CreateOrder places a simulated order. MARKET orders fill immediately; LIMIT,
+// STOP_MARKET and TAKE_PROFIT_MARKET orders rest until a candle reaches them.
+// Hedge mode position sides are mapped to one-way orders.
+func (pe *PaperExchange) CreateOrder(request *OrderRequest) (*OrderResponse, error) {
+	normalized := *request
+	applyPositionMode(&normalized, PositionModeOneWay)
+	request = &normalized
+
+	quantity := parseFloat(request.Quantity)
+	if quantity <= 0 && !request.ClosePosition {
+		return nil, fmt.Errorf("failed to create order: invalid quantity %q", request.Quantity)
+	}
+	if request.Side != "BUY" && request.Side != "SELL" {
+		return nil, fmt.Errorf("failed to create order: invalid side %q", request.Side)
+	}
+
+	// Refresh the live price outside the lock
+	if pe.market != nil {
+		if _, err := pe.GetTicker(request.Symbol); err != nil {
+			return nil, fmt.Errorf("failed to create order: %w", err)
+		}
+	}
+
+	pe.syncFromMarket()
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	lastPrice, ok := pe.lastPrice[request.Symbol]
+	if !ok {
+		return nil, fmt.Errorf("failed to create order: no price for %s", request.Symbol)
+	}
+
+	order := &Order{
+		OrderID:         pe.nextOrderID,
+		Symbol:          request.Symbol,
+		Status:          "NEW",
+		Side:            request.Side,
+		Type:            request.Type,
+		OrigQty:         quantity,
+		Price:           parseFloat(request.Price),
+		StopPrice:       parseFloat(request.StopPrice),
+		ReduceOnly:      request.ReduceOnly,
+		ClosePosition:   request.ClosePosition,
+		ClientOrderID:   request.ClientOrderID,
+		PositionSide:    PositionSideBoth,
+		CallbackRate:    parseFloat(request.CallbackRate),
+		ActivationPrice: parseFloat(request.ActivationPrice),
+	}
+	if order.ClientOrderID == "" {
+		order.ClientOrderID = fmt.Sprintf("paper%d", order.OrderID)
+	}
+	if previous, ok := pe.placed[order.ClientOrderID]; ok && pe.isOpen(previous) {
+		return nil, fmt.Errorf("failed to create order: client order ID %s is already used by an open order", order.ClientOrderID)
+	}
+
+	switch request.Type {
+	case "MARKET":
+		if (order.ReduceOnly || order.ClosePosition) && pe.reducibleQty(order) == 0 {
+			return nil, fmt.Errorf("failed to create order: ReduceOnly Order is rejected")
+		}
+		pe.nextOrderID++
+		fill, err := pe.fill(order, pe.withSlippage(order.Side, lastPrice), pe.config.TakerFee)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create order: %w", err)
+		}
+		pe.placed[order.ClientOrderID] = order
+		return pe.filledResponse(order, fill), nil
+
+	case "LIMIT":
+		if order.Price <= 0 {
+			return nil, fmt.Errorf("failed to create order: LIMIT order requires price")
+		}
+		marketable := (order.Side == "BUY" && lastPrice <= order.Price) || (order.Side == "SELL" && lastPrice >= order.Price)
+		if marketable {
+			if request.TimeInForce == "GTX" {
+				return nil, fmt.Errorf("failed to create order: post only order would immediately match")
+			}
+			pe.nextOrderID++
+			fill, err := pe.fill(order, lastPrice, pe.config.TakerFee)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create order: %w", err)
+			}
+			pe.placed[order.ClientOrderID] = order
+			return pe.filledResponse(order, fill), nil
+		}
+
+	case "STOP_MARKET", "TAKE_PROFIT_MARKET":
+		if order.StopPrice <= 0 {
+			return nil, fmt.Errorf("failed to create order: %s order requires stop price", order.Type)
+		}
+		if pe.triggered(order, lastPrice) {
+			return nil, fmt.Errorf("failed to create order: Order would immediately trigger")
+		}
+
+	case "TRAILING_STOP_MARKET":
+		if order.CallbackRate <= 0 {
+			return nil, fmt.Errorf("failed to create order: TRAILING_STOP_MARKET order requires callback rate")
+		}
+		// Without an activation price, or once it is reached, trailing starts at the market
+		trail := &paperTrail{extreme: lastPrice}
+		trail.active = order.ActivationPrice <= 0 ||
+			(order.Side == "SELL" && lastPrice >= order.ActivationPrice) ||
+			(order.Side == "BUY" && lastPrice <= order.ActivationPrice)
+		pe.trailing[order.OrderID] = trail
+
+	default:
+		return nil, fmt.Errorf("failed to create order: unsupported order type %q", request.Type)
+	}
+
+	pe.nextOrderID++
+	pe.orders = append(pe.orders, order)
+	pe.placed[order.ClientOrderID] = order
+	if pe.market != nil && pe.syncedUntil[order.Symbol] == 0 {
+		pe.syncedUntil[order.Symbol] = pe.currentTime()
+	}
+
+	return &OrderResponse{
+		OrderID: fmt.Sprintf("%d", order.OrderID),
+		Symbol:  order.Symbol,
+		Status:  order.Status,
+	}, nil
+}
+
+// GetOrderByClientID returns a simulated order of any status by its client order ID
+func (pe *PaperExchange) GetOrderByClientID(ctx context.Context, symbol string, clientOrderID string) (*Order, error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	order, ok := pe.placed[clientOrderID]
+	if !ok || order.Symbol != symbol {
+		return nil, fmt.Errorf("failed to get order %s for %s: %w", clientOrderID, symbol, ErrOrderNotFound)
+	}
+	copied := *order
+	return &copied, nil
+}
+
+// CancelOrder cancels a resting simulated order
+func (pe *PaperExchange) CancelOrder(ctx context.Context, symbol string, orderID int64) error {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	for i, order := range pe.orders {
+		if order.OrderID == orderID && order.Symbol == symbol {
+			pe.orders = append(pe.orders[:i], pe.orders[i+1:]...)
+			delete(pe.trailing, orderID)
+			order.Status = "CANCELED"
+			return nil
+		}
+	}
+
+	return fmt.Errorf("failed to cancel order %d for %s: unknown order", orderID, symbol)
+}
+
+// GetLeverage returns the simulated leverage for a symbol
+func (pe *PaperExchange) GetLeverage(symbol string) (int, error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	return pe.getLeverage(symbol), nil
+}
+
+// GetLeverageBrackets returns the market source's brackets; without one there are no notional limits
+func (pe *PaperExchange) GetLeverageBrackets(ctx context.Context, symbol string) ([]LeverageBracket, error) {
+	if pe.market != nil {
+		return pe.market.GetLeverageBrackets(ctx, symbol)
+	}
+	return nil, nil
+}
+
+// GetPositionMode returns one-way mode; simulated positions are net per symbol
+func (pe *PaperExchange) GetPositionMode(ctx context.Context) (PositionMode, error) {
+	return PositionModeOneWay, nil
+}
+
+// GetIncomeHistory returns the realized PnL and fees of the simulated fills since the given time
+func (pe *PaperExchange) GetIncomeHistory(ctx context.Context, since time.Time) ([]Income, error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	var incomes []Income
+	for _, fill := range pe.fills {
+		at := time.UnixMilli(fill.Time)
+		if at.Before(since) {
+			continue
+		}
+		if fill.RealizedPnL != 0 {
+			incomes = append(incomes, Income{Symbol: fill.Symbol, Type: IncomeRealizedPnL, Amount: fill.RealizedPnL, Time: at})
+		}
+		if fill.Fee != 0 {
+			incomes = append(incomes, Income{Symbol: fill.Symbol, Type: IncomeCommission, Amount: -fill.Fee, Time: at})
+		}
+	}
+	return incomes, nil
+}
+
+// ChangeLeverage changes the simulated leverage for a symbol
+func (pe *PaperExchange) ChangeLeverage(symbol string, leverage int) error {
+	if leverage < 1 || leverage > 125 {
+		return fmt.Errorf("failed to change leverage: invalid leverage %d", leverage)
+	}
+
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.leverage[symbol] = leverage
+	return nil
+}
+
+// GetMarginMode returns the simulated margin mode for a symbol
+func (pe *PaperExchange) GetMarginMode(symbol string) (string, error) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	if mode, ok := pe.marginMode[symbol]; ok {
+		return mode, nil
+	}
+	return "CROSSED", nil
+}
+
+// ChangeMarginMode changes the simulated margin mode for a symbol
+func (pe *PaperExchange) ChangeMarginMode(symbol string, marginMode string) error {
+	if marginMode != "ISOLATED" && marginMode != "CROSSED" {
+		return fmt.Errorf("invalid margin mode: %s (must be ISOLATED or CROSSED)", marginMode)
+	}
+
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.marginMode[symbol] = marginMode
+	return nil
+}
+
+// processCandle fills resting orders touched by the candle, in the order the
+// price path most likely reached them: open, then the nearer extreme, then the
+// farther extreme, then close
+func (pe *PaperExchange) processCandle(symbol string, candle Candle) {
+	pe.now = candle.CloseTime
+
+	path := []float64{candle.Open, candle.Low, candle.High, candle.Close}
+	if math.Abs(candle.High-candle.Open) < math.Abs(candle.Open-candle.Low) {
+		path = []float64{candle.Open, candle.High, candle.Low, candle.Close}
+	}
+
+	type trigger struct {
+		order *Order
+		at    float64 // position along the path, 0 = open
+		price float64
+	}
+
+	var triggers []trigger
+	for _, order := range pe.orders {
+		if order.Symbol != symbol {
+			continue
+		}
+		if at, price, ok := pe.pathTrigger(order, path); ok {
+			triggers = append(triggers, trigger{order: order, at: at, price: price})
+		}
+	}
+
+	sort.SliceStable(triggers, func(i, j int) bool {
+		return triggers[i].at < triggers[j].at
+	})
+
+	for _, t := range triggers {
+		if !pe.isOpen(t.order) {
+			continue
+		}
+		pe.removeOrder(t.order.OrderID)
+
+		if (t.order.ReduceOnly || t.order.ClosePosition) && pe.reducibleQty(t.order) == 0 {
+			t.order.Status = "EXPIRED"
+			continue
+		}
+
+		price, feeRate := t.price, pe.config.MakerFee
+		if t.order.Type != "LIMIT" {
+			price, feeRate = pe.withSlippage(t.order.Side, t.price), pe.config.TakerFee
+		}
+		if _, err := pe.fill(t.order, price, feeRate); err != nil {
+			t.order.Status = "EXPIRED"
+		}
+	}
+
+	pe.lastPrice[symbol] = candle.Close
+}
+
+// pathTrigger finds where along the price path an order is reached
+func (pe *PaperExchange) pathTrigger(order *Order, path []float64) (float64, float64, bool) {
+	if order.Type == "TRAILING_STOP_MARKET" {
+		return pe.trailingTrigger(order, path)
+	}
+
+	level := order.Price
+	if order.Type != "LIMIT" {
+		level = order.StopPrice
+	}
+
+	// Gapped through the level at the open: fill at the open
+	if pe.triggered(order, path[0]) {
+		return 0, path[0], true
+	}
+
+	for i := 0; i < len(path)-1; i++ {
+		from, to := path[i], path[i+1]
+		if (from <= level && level <= to) || (to <= level && level <= from) {
+			at := float64(i)
+			if to != from {
+				at += (level - from) / (to - from)
+			}
+			return at, level, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+// trailingTrigger follows a trailing stop along the price path, moving its
+// best price, and finds where the price retraces by the callback rate
+func (pe *PaperExchange) trailingTrigger(order *Order, path []float64) (float64, float64, bool) {
+	trail := pe.trailing[order.OrderID]
+	if trail == nil {
+		return 0, 0, false
+	}
+
+	rate := order.CallbackRate / 100
+	favorable := func(from, to float64) bool {
+		if order.Side == "SELL" {
+			return to > from
+		}
+		return to < from
+	}
+
+	for i := 0; i < len(path)-1; i++ {
+		from, to := path[i], path[i+1]
+
+		if !trail.active {
+			reached := (order.Side == "SELL" && to >= order.ActivationPrice) || (order.Side == "BUY" && to <= order.ActivationPrice)
+			if !reached {
+				continue
+			}
+			trail.active = true
+			trail.extreme = order.ActivationPrice
+		}
+
+		// Moving in the position's favour only moves the best price
+		if favorable(from, to) {
+			if favorable(trail.extreme, to) {
+				trail.extreme = to
+			}
+			continue
+		}
+
+		level := trail.extreme * (1 - rate)
+		if order.Side == "BUY" {
+			level = trail.extreme * (1 + rate)
+		}
+		if (order.Side == "SELL" && to <= level) || (order.Side == "BUY" && to >= level) {
+			if (order.Side == "SELL" && from <= level) || (order.Side == "BUY" && from >= level) {
+				return float64(i), from, true
+			}
+			return float64(i) + (level-from)/(to-from), level, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+// triggered reports whether an order is reached at the given price
+func (pe *PaperExchange) triggered(order *Order, price float64) bool {
+	switch order.Type {
+	case "LIMIT":
+		if order.Side == "BUY" {
+			return price <= order.Price
+		}
+		return price >= order.Price
+	case "STOP_MARKET":
+		if order.Side == "BUY" {
+			return price >= order.StopPrice
+		}
+		return price <= order.StopPrice
+	case "TAKE_PROFIT_MARKET":
+		if order.Side == "BUY" {
+			return price <= order.StopPrice
+		}
+		return price >= order.StopPrice
+	}
+	return false
+}
+
+// fill executes an order at price, updating the position and wallet
+func (pe *PaperExchange) fill(order *Order, price float64, feeRate float64) (*PaperFill, error) {
+	pos := pe.positions[order.Symbol]
+	if pos == nil {
+		pos = &paperPosition{}
+		pe.positions[order.Symbol] = pos
+	}
+
+	quantity := order.OrigQty
+	if order.ReduceOnly || order.ClosePosition {
+		quantity = pe.reducibleQty(order)
+	}
+
+	direction := 1.0
+	if order.Side == "SELL" {
+		direction = -1.0
+	}
+
+	// Split into the part that reduces the current position and the part that opens
+	reduceQty := 0.0
+	if pos.amount*direction < 0 {
+		reduceQty = math.Min(quantity, math.Abs(pos.amount))
+	}
+	openQty := quantity - reduceQty
+
+	fee := quantity * price * feeRate
+	leverage := float64(pe.getLeverage(order.Symbol))
+
+	if openQty > 0 {
+		required := openQty*price/leverage + fee
+		if required > pe.availableBalance() {
+			return nil, fmt.Errorf("Margin is insufficient: required %.4f, available %.4f", required, pe.availableBalance())
+		}
+	}
+
+	realized := 0.0
+	if reduceQty > 0 {
+		realized = (price - pos.entryPrice) * reduceQty * -direction
+		pos.margin -= pos.margin * reduceQty / math.Abs(pos.amount)
+		pos.amount += reduceQty * direction
+		if pos.amount == 0 {
+			pos.entryPrice = 0
+			pos.margin = 0
+		}
+	}
+
+	if openQty > 0 {
+		newAmount := pos.amount + openQty*direction
+		pos.entryPrice = (pos.entryPrice*math.Abs(pos.amount) + price*openQty) / math.Abs(newAmount)
+		pos.amount = newAmount
+		pos.margin += openQty * price / leverage
+	}
+
+	pe.wallet += realized - fee
+
+	order.Status = "FILLED"
+	order.ExecutedQty = quantity
+	order.AvgPrice = price
+	fill := PaperFill{
+		Time:        pe.currentTime(),
+		OrderID:     order.OrderID,
+		Symbol:      order.Symbol,
+		Side:        order.Side,
+		Type:        order.Type,
+		Price:       price,
+		Quantity:    quantity,
+		Fee:         fee,
+		RealizedPnL: realized,
+	}
+	pe.fills = append(pe.fills, fill)
+
+	return &fill, nil
+}
+
+// reducibleQty returns how much of the position a reduce-only or close-position order may close
+func (pe *PaperExchange) reducibleQty(order *Order) float64 {
+	pos := pe.positions[order.Symbol]
+	if pos == nil || pos.amount == 0 {
+		return 0
+	}
+	if (order.Side == "SELL" && pos.amount < 0) || (order.Side == "BUY" && pos.amount > 0) {
+		return 0
+	}
+	if order.ClosePosition {
+		return math.Abs(pos.amount)
+	}
+	return math.Min(order.OrigQty, math.Abs(pos.amount))
+}
+
+// withSlippage applies adverse slippage to a taker fill price
+func (pe *PaperExchange) withSlippage(side string, price float64) float64 {
+	if side == "BUY" {
+		return price * (1 + pe.config.Slippage)
+	}
+	return price * (1 - pe.config.Slippage)
+}
+
+// filledResponse builds the response for an immediately filled order
+func (pe *PaperExchange) filledResponse(order *Order, fill *PaperFill) *OrderResponse {
+	return &OrderResponse{
+		OrderID:     fmt.Sprintf("%d", order.OrderID),
+		Symbol:      order.Symbol,
+		Status:      order.Status,
+		ExecutedQty: fill.Quantity,
+		AvgPrice:    fill.Price,
+	}
+}
+
+// isOpen reports whether an order is still resting
+func (pe *PaperExchange) isOpen(order *Order) bool {
+	for _, open := range pe.orders {
+		if open == order {
+			return true
+		}
+	}
+	return false
+}
+
+// removeOrder removes a resting order by ID
+func (pe *PaperExchange) removeOrder(orderID int64) {
+	delete(pe.trailing, orderID)
+	for i, order := range pe.orders {
+		if order.OrderID == orderID {
+			pe.orders = append(pe.orders[:i], pe.orders[i+1:]...)
+			return
+		}
+	}
+}
+
+// getLeverage returns the configured leverage for a symbol
+func (pe *PaperExchange) getLeverage(symbol string) int {
+	if leverage, ok := pe.leverage[symbol]; ok {
+		return leverage
+	}
+	return pe.config.DefaultLeverage
+}
+
+// markPrice returns the last known price for a position
+func (pe *PaperExchange) markPrice(symbol string, pos *paperPosition) float64 {
+	if price, ok := pe.lastPrice[symbol]; ok {
+		return price
+	}
+	return pos.entryPrice
+}
+
+// unrealizedProfit sums unrealized profit across positions
+func (pe *PaperExchange) unrealizedProfit() float64 {
+	total := 0.0
+	for symbol, pos := range pe.positions {
+		total += (pe.markPrice(symbol, pos) - pos.entryPrice) * pos.amount
+	}
+	return total
+}
+
+// positionMargin sums the isolated margin held by positions
+func (pe *PaperExchange) positionMargin() float64 {
+	total := 0.0
+	for _, pos := range pe.positions {
+		total += pos.margin
+	}
+	return total
+}
+
+// availableBalance is the wallet minus position margin and unrealized losses
+func (pe *PaperExchange) availableBalance() float64 {
+	available := pe.wallet - pe.positionMargin() + math.Min(pe.unrealizedProfit(), 0)
+	if available < 0 {
+		return 0
+	}
+	return available
+}
+
+// currentTime returns the simulated clock in milliseconds
+func (pe *PaperExchange) currentTime() int64 {
+	if pe.market != nil {
+		return time.Now().UnixMilli()
+	}
+	return pe.now
+}
+
+// syncFromMarket replays closed live klines for symbols with open orders or
+// positions so resting orders fill as the market moves. A symbol is synced once
+// a full SyncInterval has passed, and the klines are fetched without holding
+// pe.mu so other calls are not held up by the request.
+func (pe *PaperExchange) syncFromMarket() {
+	if pe.market == nil {
+		return
+	}
+	bar, err := IntervalDuration(pe.config.SyncInterval)
+	if err != nil {
+		bar = time.Minute
+	}
+	now := time.Now().UnixMilli()
+
+	pe.mu.Lock()
+	due := make(map[string]int64) // Bars to fetch by symbol
+	active := make(map[string]bool)
+	for _, order := range pe.orders {
+		active[order.Symbol] = true
+	}
+	for symbol, pos := range pe.positions {
+		if pos.amount != 0 {
+			active[symbol] = true
+		}
+	}
+	for symbol := range active {
+		since := pe.syncedUntil[symbol]
+		if since == 0 {
+			pe.syncedUntil[symbol] = now
+			continue
+		}
+		if elapsed := now - since; elapsed >= bar.Milliseconds() {
+			due[symbol] = min(elapsed/bar.Milliseconds()+1, 500)
+		}
+	}
+	pe.mu.Unlock()
+
+	for symbol, bars := range due {
+		rows, err := pe.market.GetKlines(symbol, pe.config.SyncInterval, int(bars))
+		if err != nil {
+			continue
+		}
+
+		pe.mu.Lock()
+		for _, row := range rows {
+			candle, err := ParseCandle(row)
+			// Another call may have replayed some of the candles meanwhile
+			if err != nil || candle.OpenTime < pe.syncedUntil[symbol] || candle.CloseTime >= now {
+				continue
+			}
+			pe.processCandle(symbol, candle)
+			pe.syncedUntil[symbol] = candle.CloseTime + 1
+		}
+		pe.mu.Unlock()
+	}
+}
@@ -0,0 +1,161 @@
+package trading
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// newTestPaperExchange creates a fee-free paper exchange primed at price
+func newTestPaperExchange(price float64) *PaperExchange {
+	cfg := DefaultPaperConfig()
+	cfg.MakerFee = 0
+	cfg.TakerFee = 0
+	cfg.Slippage = 0
+
+	pe := NewPaperExchange(nil, cfg)
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 0, Open: price, High: price, Low: price, Close: price, CloseTime: 3599999})
+	return pe
+}
+
+// TestPaperStopLossFillsBeforeTakeProfit tests that a bracket closes once and the sibling expires
+func TestPaperStopLossFillsBeforeTakeProfit(t *testing.T) {
+	pe := newTestPaperExchange(100)
+
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"}); err != nil {
+		t.Fatalf("Failed to open position: %v", err)
+	}
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "95", ClosePosition: true}); err != nil {
+		t.Fatalf("Failed to place stop loss: %v", err)
+	}
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "TAKE_PROFIT_MARKET", StopPrice: "110", ClosePosition: true}); err != nil {
+		t.Fatalf("Failed to place take profit: %v", err)
+	}
+
+	// Open is closer to the low, so the stop is reached before the target
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 3600000, Open: 99, High: 112, Low: 94, Close: 105, CloseTime: 7199999})
+
+	positions, _ := pe.GetPositions(context.Background())
+	if len(positions) != 0 {
+		t.Fatalf("Expected flat position, got %+v", positions)
+	}
+
+	fills := pe.Fills()
+	if len(fills) != 2 {
+		t.Fatalf("Expected entry and stop fills, got %d", len(fills))
+	}
+	if fills[1].Type != "STOP_MARKET" || math.Abs(fills[1].RealizedPnL+5) > 1e-9 {
+		t.Errorf("Expected stop fill with -5 PnL, got %+v", fills[1])
+	}
+
+	balance, _ := pe.GetUSDTBalance(context.Background())
+	if math.Abs(balance.WalletBalance-995) > 1e-9 {
+		t.Errorf("Expected wallet 995, got %.4f", balance.WalletBalance)
+	}
+}
+
+// TestPaperLimitAndFees tests resting limit fills, maker fees and slippage on market exits
+func TestPaperLimitAndFees(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	pe.config.MakerFee = 0.001
+	pe.config.TakerFee = 0.001
+	pe.config.Slippage = 0.01
+
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: "101", Quantity: "1", TimeInForce: "GTX"}); err == nil {
+		t.Error("Expected post-only limit above market to be rejected")
+	}
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: "98", Quantity: "2"}); err != nil {
+		t.Fatalf("Failed to place limit order: %v", err)
+	}
+
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 3600000, Open: 100, High: 100, Low: 97, Close: 99, CloseTime: 7199999})
+
+	positions, _ := pe.GetPositions(context.Background())
+	if len(positions) != 1 || positions[0].PositionAmt != 2 || positions[0].EntryPrice != 98 {
+		t.Fatalf("Expected 2 @ 98 long, got %+v", positions)
+	}
+
+	resp, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "MARKET", Quantity: "5", ReduceOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to close position: %v", err)
+	}
+	if resp.ExecutedQty != 2 || math.Abs(resp.AvgPrice-98.01) > 1e-9 {
+		t.Errorf("Expected reduce-only fill of 2 @ 98.01, got %+v", resp)
+	}
+
+	// entry fee 0.196 + exit fee 0.19602 + PnL 0.02
+	balance, _ := pe.GetUSDTBalance(context.Background())
+	if math.Abs(balance.WalletBalance-(1000+0.02-0.196-0.19602)) > 1e-9 {
+		t.Errorf("Unexpected wallet balance %.6f", balance.WalletBalance)
+	}
+}
+
+// TestPaperTrailingStop tests that a trailing stop activates, follows the high and fills on the callback
+func TestPaperTrailingStop(t *testing.T) {
+	pe := newTestPaperExchange(100)
+
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"}); err != nil {
+		t.Fatalf("Failed to open position: %v", err)
+	}
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "TRAILING_STOP_MARKET", CallbackRate: "1", ActivationPrice: "105", Quantity: "1", ReduceOnly: true}); err != nil {
+		t.Fatalf("Failed to place trailing stop: %v", err)
+	}
+
+	// Not activated yet: a 1% dip does not trigger it
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 3600000, Open: 100, High: 104, Low: 98, Close: 103, CloseTime: 7199999})
+	if orders, _ := pe.GetOpenOrders(context.Background()); len(orders) != 1 {
+		t.Fatalf("Expected the trailing stop to rest before activation, got %+v", orders)
+	}
+
+	// Activated at 105, trails to 110 and fills 1% below
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 7200000, Open: 103, High: 110, Low: 102, Close: 108, CloseTime: 10799999})
+	fills := pe.Fills()
+	if len(fills) != 2 || math.Abs(fills[1].Price-108.9) > 1e-9 {
+		t.Fatalf("Expected a trailing stop fill at 108.9, got %+v", fills)
+	}
+}
+
+// klineCountingMarket is a live market stand-in that counts kline requests
+type klineCountingMarket struct {
+	*PaperExchange
+	requests int
+}
+
+func (km *klineCountingMarket) GetKlines(symbol, interval string, limit int) ([][]interface{}, error) {
+	km.requests++
+	return km.PaperExchange.GetKlines(symbol, interval, limit)
+}
+
+// TestPaperSyncsOncePerInterval tests that reads only fetch live klines once a full sync interval has passed
+func TestPaperSyncsOncePerInterval(t *testing.T) {
+	market := &klineCountingMarket{PaperExchange: newTestPaperExchange(100)}
+	pe := NewPaperExchange(market, DefaultPaperConfig())
+	ctx := context.Background()
+
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Quantity: "1", Price: "90", TimeInForce: "GTC"}); err != nil {
+		t.Fatalf("Limit order failed: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		pe.GetOpenOrders(ctx)
+		pe.GetPositions(ctx)
+	}
+	if market.requests != 0 {
+		t.Errorf("Expected no kline requests within the interval, got %d", market.requests)
+	}
+
+	// The last closed minute is replayed once two minutes have passed
+	minute := time.Now().Truncate(time.Minute).Add(-time.Minute).UnixMilli()
+	market.OnCandle("BTCUSDT", Candle{OpenTime: minute, Open: 100, High: 100, Low: 89, Close: 95, CloseTime: minute + 59999})
+	pe.mu.Lock()
+	pe.syncedUntil["BTCUSDT"] = minute - 60000
+	pe.mu.Unlock()
+	pe.GetOpenOrders(ctx)
+	positions, _ := pe.GetPositions(ctx)
+	if market.requests != 1 {
+		t.Errorf("Expected one kline request after the interval, got %d", market.requests)
+	}
+	if len(positions) != 1 {
+		t.Errorf("Expected the limit entry filled by the replayed candle, got %+v", positions)
+	}
+}
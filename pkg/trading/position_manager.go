@@ -0,0 +1,371 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TrailMode selects how a managed stop trails the price
+type TrailMode string
+
+const (
+	TrailNone  TrailMode = "NONE"
+	TrailATR   TrailMode = "ATR"   // A multiple of the average true range behind the price
+	TrailSwing TrailMode = "SWING" // Below the recent swing low (long) or above the swing high (short)
+)
+
+// PositionManagerConfig configures how PositionManager moves stops
+type PositionManagerConfig struct {
+	BreakEvenR      float64       // Profit in R multiples at which the stop moves to entry; 0 disables
+	BreakEvenOffset float64       // Fraction past entry for the break-even stop, to cover fees (0.001 = 0.1%)
+	TrailMode       TrailMode     // How the stop trails once TrailAfterR is reached
+	TrailAfterR     float64       // Profit in R multiples at which trailing starts
+	ATRPeriod       int           // Candles in the ATR for TrailATR
+	ATRMultiplier   float64       // ATRs behind the price for TrailATR
+	SwingLookback   int           // Closed candles searched for the swing for TrailSwing
+	Interval        string        // Kline interval for the ATR and swings
+	PollInterval    time.Duration // How often Run checks the positions
+}
+
+// DefaultPositionManagerConfig moves the stop to break-even at 1R and does not trail
+func DefaultPositionManagerConfig() PositionManagerConfig {
+	return PositionManagerConfig{
+		BreakEvenR:      1,
+		BreakEvenOffset: 0.001,
+		TrailMode:       TrailNone,
+		TrailAfterR:     1.5,
+		ATRPeriod:       14,
+		ATRMultiplier:   2,
+		SwingLookback:   5,
+		Interval:        "1h",
+		PollInterval:    time.Minute,
+	}
+}
+
+// PositionManagerConfigFromEnv returns the default config overridden by
+// BREAKEVEN_R, TRAIL_MODE (NONE, ATR or SWING), TRAIL_AFTER_R,
+// TRAIL_ATR_MULTIPLIER and TRAIL_SWING_LOOKBACK
+func PositionManagerConfigFromEnv() PositionManagerConfig {
+	cfg := DefaultPositionManagerConfig()
+
+	overrides := map[string]*float64{
+		"BREAKEVEN_R":          &cfg.BreakEvenR,
+		"TRAIL_AFTER_R":        &cfg.TrailAfterR,
+		"TRAIL_ATR_MULTIPLIER": &cfg.ATRMultiplier,
+	}
+	for key, target := range overrides {
+		if value := os.Getenv(key); value != "" {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				*target = parsed
+			}
+		}
+	}
+	if mode := os.Getenv("TRAIL_MODE"); mode != "" {
+		cfg.TrailMode = TrailMode(strings.ToUpper(mode))
+	}
+	if lookback, err := strconv.Atoi(os.Getenv("TRAIL_SWING_LOOKBACK")); err == nil && lookback > 0 {
+		cfg.SwingLookback = lookback
+	}
+
+	return cfg
+}
+
+// ManagedPosition is a position whose stop loss the PositionManager moves
+type ManagedPosition struct {
+	Symbol      string
+	Side        string  // LONG or SHORT
+	EntryPrice  float64 // Taken from the position when 0
+	InitialStop float64 // Stop price at entry; entry minus it is 1R
+	Stop        float64 // Current stop price
+	StopOrderID int64
+	BreakEven   bool // Stop has been moved to break-even
+}
+
+// PositionManager moves the stop loss of managed positions: to break-even
+// once the profit reaches BreakEvenR, then trailing by ATR or swing points.
+// Stops only ever move in the position's favour.
+type PositionManager struct {
+	ex     Exchange
+	config PositionManagerConfig
+
+	mu        sync.Mutex
+	positions map[string]*ManagedPosition // By positionKey, so hedge mode LONG and SHORT are managed apart
+}
+
+// NewPositionManager creates a position manager that moves stops on ex
+func NewPositionManager(ex Exchange, config PositionManagerConfig) *PositionManager {
+	return &PositionManager{
+		ex:        ex,
+		config:    config,
+		positions: make(map[string]*ManagedPosition),
+	}
+}
+
+// Manage starts managing the stop of a position
+func (pm *PositionManager) Manage(position ManagedPosition) {
+	if position.Stop == 0 {
+		position.Stop = position.InitialStop
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.positions[positionKey(position.Symbol, position.Side)] = &position
+}
+
+// ManageBracket starts managing the stop of a placed bracket
+func (pm *PositionManager) ManageBracket(bracket *Bracket, stopPrice float64) {
+	pm.Manage(ManagedPosition{
+		Symbol:      bracket.Symbol,
+		Side:        orderDirection(bracket.Side),
+		EntryPrice:  bracket.EntryPrice,
+		InitialStop: stopPrice,
+		StopOrderID: bracket.StopOrderID,
+	})
+}
+
+// Positions returns a copy of the managed positions
+func (pm *PositionManager) Positions() []ManagedPosition {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	var positions []ManagedPosition
+	for _, mp := range pm.positions {
+		positions = append(positions, *mp)
+	}
+	return positions
+}
+
+// Run updates the managed stops every PollInterval until ctx is cancelled
+func (pm *PositionManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(pm.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := pm.Update(ctx); err != nil {
+				log.Printf("⚠️  Position manager update failed: %v", err)
+			}
+		}
+	}
+}
+
+// Update forgets closed positions and moves the stops of open ones
+func (pm *PositionManager) Update(ctx context.Context) error {
+	positions, err := pm.ex.GetPositions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get positions: %w", err)
+	}
+	orders, err := pm.ex.GetOpenOrders(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get open orders: %w", err)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	for key, mp := range pm.positions {
+		var position *Position
+		for i := range positions {
+			if positions[i].Symbol == mp.Symbol && positions[i].Side == mp.Side && positions[i].PositionAmt != 0 {
+				position = &positions[i]
+				break
+			}
+		}
+		if position == nil {
+			log.Printf("📕 %s position closed, no longer managing its stop", sideLabel(mp.Symbol, mp.Side))
+			delete(pm.positions, key)
+			continue
+		}
+		if mp.EntryPrice == 0 {
+			mp.EntryPrice = position.EntryPrice
+		}
+
+		if err := pm.updatePosition(ctx, mp, orders); err != nil {
+			log.Printf("⚠️  Failed to manage %s stop: %v", sideLabel(mp.Symbol, mp.Side), err)
+		}
+	}
+	return nil
+}
+
+// updatePosition moves one position's stop if the price has moved far enough
+func (pm *PositionManager) updatePosition(ctx context.Context, mp *ManagedPosition, orders []Order) error {
+	// The stop may have been replaced, by a resize after a partial take profit for example
+	stop, ok := findStopOrder(orders, mp)
+	if !ok {
+		return fmt.Errorf("no open stop loss")
+	}
+	mp.StopOrderID = stop.OrderID
+	mp.Stop = stop.StopPrice
+
+	ticker, err := pm.ex.GetTicker(mp.Symbol)
+	if err != nil {
+		return err
+	}
+	price := parseFloat(ticker.Price)
+
+	var candles []Candle
+	if pm.config.TrailMode == TrailATR || pm.config.TrailMode == TrailSwing {
+		if candles, err = pm.closedCandles(mp.Symbol); err != nil {
+			return err
+		}
+	}
+
+	next, breakEven := pm.nextStop(mp, price, candles)
+	if next == mp.Stop {
+		return nil
+	}
+
+	newID, err := replaceStop(ctx, pm.ex, stop, next, 0)
+	if err != nil {
+		return err
+	}
+
+	reason := "trailed"
+	if breakEven && !mp.BreakEven {
+		reason = "moved to break-even"
+	}
+	log.Printf("🪜 %s stop %s: %s → %s (order %d)", mp.Symbol, reason, formatFloat(mp.Stop), formatFloat(next), newID)
+
+	mp.Stop = next
+	mp.StopOrderID = newID
+	mp.BreakEven = mp.BreakEven || breakEven
+	return nil
+}
+
+// nextStop returns where the stop should be at price, rounded to the tick
+// size, and whether the break-even level was reached. It returns the current
+// stop when no move is due.
+func (pm *PositionManager) nextStop(mp *ManagedPosition, price float64, candles []Candle) (float64, bool) {
+	direction := 1.0
+	if mp.Side == "SHORT" {
+		direction = -1.0
+	}
+	risk := (mp.EntryPrice - mp.InitialStop) * direction
+	if risk <= 0 || mp.EntryPrice <= 0 {
+		return mp.Stop, mp.BreakEven
+	}
+	profitR := (price - mp.EntryPrice) * direction / risk
+
+	// Compare in the position's direction: higher is better for longs, lower for shorts
+	better := func(a, b float64) bool { return a*direction > b*direction }
+
+	candidate := mp.Stop
+	breakEven := mp.BreakEven
+	if pm.config.BreakEvenR > 0 && profitR >= pm.config.BreakEvenR {
+		breakEven = true
+		if level := mp.EntryPrice * (1 + pm.config.BreakEvenOffset*direction); better(level, candidate) {
+			candidate = level
+		}
+	}
+
+	if profitR >= pm.config.TrailAfterR {
+		if level, ok := pm.trailLevel(mp.Side, price, candles); ok && better(level, candidate) {
+			candidate = level
+		}
+	}
+
+	// A stop at or beyond the price would trigger immediately
+	if !better(price, candidate) {
+		return mp.Stop, mp.BreakEven
+	}
+
+	if filters, err := pm.ex.Symbols().Get(context.Background(), mp.Symbol); err == nil {
+		candidate = filters.RoundPrice(candidate).InexactFloat64()
+	}
+	if !better(candidate, mp.Stop) {
+		return mp.Stop, mp.BreakEven
+	}
+	return candidate, breakEven
+}
+
+// trailLevel returns the trailing stop level for the configured mode
+func (pm *PositionManager) trailLevel(side string, price float64, candles []Candle) (float64, bool) {
+	switch pm.config.TrailMode {
+	case TrailATR:
+		atr := ATR(candles, pm.config.ATRPeriod)
+		if atr == 0 {
+			return 0, false
+		}
+		if side == "SHORT" {
+			return price + pm.config.ATRMultiplier*atr, true
+		}
+		return price - pm.config.ATRMultiplier*atr, true
+
+	case TrailSwing:
+		if len(candles) < pm.config.SwingLookback || pm.config.SwingLookback <= 0 {
+			return 0, false
+		}
+		recent := candles[len(candles)-pm.config.SwingLookback:]
+		if side == "SHORT" {
+			high := recent[0].High
+			for _, c := range recent {
+				high = math.Max(high, c.High)
+			}
+			return high, true
+		}
+		low := recent[0].Low
+		for _, c := range recent {
+			low = math.Min(low, c.Low)
+		}
+		return low, true
+	}
+	return 0, false
+}
+
+// closedCandles returns enough closed candles for the ATR and swing lookback
+func (pm *PositionManager) closedCandles(symbol string) ([]Candle, error) {
+	limit := max(pm.config.ATRPeriod*3, pm.config.SwingLookback) + 1
+	klines, err := pm.ex.GetKlines(symbol, pm.config.Interval, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get klines: %w", err)
+	}
+
+	now := time.Now().UnixMilli()
+	var candles []Candle
+	for _, kline := range klines {
+		candle, err := ParseCandle(kline)
+		if err != nil {
+			return nil, err
+		}
+		if candle.CloseTime < now {
+			candles = append(candles, candle)
+		}
+	}
+	return candles, nil
+}
+
+// findStopOrder finds the managed position's STOP_MARKET stop loss, preferring its known order ID
+func findStopOrder(orders []Order, mp *ManagedPosition) (Order, bool) {
+	exitSide := "SELL"
+	if mp.Side == "SHORT" {
+		exitSide = "BUY"
+	}
+
+	var found *Order
+	for i := range orders {
+		order := &orders[i]
+		if order.Symbol != mp.Symbol || order.Type != "STOP_MARKET" || order.Side != exitSide || !isExitOrder(*order) {
+			continue
+		}
+		if order.OrderID == mp.StopOrderID {
+			return *order, true
+		}
+		if found == nil {
+			found = order
+		}
+	}
+	if found == nil {
+		return Order{}, false
+	}
+	return *found, true
+}
@@ -0,0 +1,103 @@
+package trading
+
+import (
+	"context"
+	"testing"
+)
+
+// newManagedLong opens 1 BTC long at 100 with a closePosition stop at 95 (1R = 5)
+func newManagedLong(t *testing.T, config PositionManagerConfig) (*PaperExchange, *PositionManager) {
+	pe := newTestPaperExchange(100)
+	pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinPrice: 0.1, MaxPrice: 1000000, TickSize: 0.1, MinQty: 0.001, MaxQty: 1000, StepSize: 0.001}}
+
+	bracket, err := PlaceBracket(context.Background(), pe,
+		&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"},
+		&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "95", ClosePosition: true},
+		&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "TAKE_PROFIT_MARKET", StopPrice: "150", ClosePosition: true},
+	)
+	if err != nil {
+		t.Fatalf("PlaceBracket failed: %v", err)
+	}
+
+	pm := NewPositionManager(pe, config)
+	pm.ManageBracket(bracket, 95)
+	return pe, pm
+}
+
+// stopPrice returns the price of the only open stop loss
+func stopPrice(t *testing.T, pe *PaperExchange) float64 {
+	orders, _ := pe.GetOpenOrders(context.Background())
+	var stops []Order
+	for _, order := range orders {
+		if order.Type == "STOP_MARKET" {
+			stops = append(stops, order)
+		}
+	}
+	if len(stops) != 1 {
+		t.Fatalf("Expected one stop loss, got %+v", stops)
+	}
+	return stops[0].StopPrice
+}
+
+// TestPositionManagerBreakEven tests that the stop moves to entry after 1R and no further
+func TestPositionManagerBreakEven(t *testing.T) {
+	pe, pm := newManagedLong(t, DefaultPositionManagerConfig())
+	ctx := context.Background()
+
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 3600000, Open: 100, High: 104, Low: 99, Close: 104, CloseTime: 7199999})
+	if err := pm.Update(ctx); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if stop := stopPrice(t, pe); stop != 95 {
+		t.Errorf("Expected the stop to stay at 95 below 1R, got %v", stop)
+	}
+
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 7200000, Open: 104, High: 106, Low: 103, Close: 106, CloseTime: 10799999})
+	if err := pm.Update(ctx); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if stop := stopPrice(t, pe); stop != 100.1 {
+		t.Errorf("Expected a break-even stop at 100.1, got %v", stop)
+	}
+	if positions := pm.Positions(); len(positions) != 1 || !positions[0].BreakEven {
+		t.Errorf("Expected the position to be at break-even, got %+v", positions)
+	}
+
+	// The stop is hit and the position is forgotten
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 10800000, Open: 106, High: 106, Low: 99, Close: 99, CloseTime: 14399999})
+	if err := pm.Update(ctx); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if positions := pm.Positions(); len(positions) != 0 {
+		t.Errorf("Expected the closed position to be dropped, got %+v", positions)
+	}
+}
+
+// TestPositionManagerTrailsByATR tests ATR trailing and that the stop never moves back
+func TestPositionManagerTrailsByATR(t *testing.T) {
+	config := DefaultPositionManagerConfig()
+	config.TrailMode = TrailATR
+	config.TrailAfterR = 1
+	pe, pm := newManagedLong(t, config)
+	ctx := context.Background()
+
+	// Rising candles with a true range of 2
+	for i := int64(1); i <= 20; i++ {
+		c := 100 + float64(i)
+		pe.OnCandle("BTCUSDT", Candle{OpenTime: i * 3600000, Open: c, High: c + 1, Low: c - 1, Close: c, CloseTime: (i+1)*3600000 - 1})
+	}
+	if err := pm.Update(ctx); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if stop := stopPrice(t, pe); stop != 116 {
+		t.Errorf("Expected the stop 2 ATR below 120 at 116, got %v", stop)
+	}
+
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 21 * 3600000, Open: 120, High: 120, Low: 118, Close: 118, CloseTime: 22*3600000 - 1})
+	if err := pm.Update(ctx); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if stop := stopPrice(t, pe); stop != 116 {
+		t.Errorf("Expected the stop to stay at 116 on a pullback, got %v", stop)
+	}
+}
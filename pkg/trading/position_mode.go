@@ -0,0 +1,150 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// PositionMode is the account's position mode on Binance Futures
+type PositionMode string
+
+const (
+	PositionModeOneWay PositionMode = "ONE_WAY" // One net position per symbol
+	PositionModeHedge  PositionMode = "HEDGE"   // Separate LONG and SHORT positions per symbol (dual side)
+)
+
+// Position sides of orders and positions. One-way mode uses BOTH; hedge mode
+// uses LONG and SHORT, and an order must name the position it opens or closes.
+const (
+	PositionSideBoth  = "BOTH"
+	PositionSideLong  = "LONG"
+	PositionSideShort = "SHORT"
+)
+
+// applyPositionMode adapts order to the account's position mode. In hedge
+// mode reduce-only orders are rejected, so a reduce-only or close-position
+// order is sent against the position it closes (SELL closes LONG) and any
+// other order against the position it opens. In one-way mode an order naming
+// the position it closes is sent reduce-only instead.
+func applyPositionMode(order *OrderRequest, mode PositionMode) {
+	if mode == PositionModeHedge {
+		if order.PositionSide == "" || order.PositionSide == PositionSideBoth {
+			order.PositionSide = orderDirection(order.Side)
+			if order.ReduceOnly || order.ClosePosition {
+				order.PositionSide = oppositeSide(order.PositionSide)
+			}
+		}
+		order.ReduceOnly = false
+		return
+	}
+
+	if order.PositionSide == PositionSideLong || order.PositionSide == PositionSideShort {
+		if order.PositionSide != orderDirection(order.Side) && !order.ClosePosition {
+			order.ReduceOnly = true
+		}
+	}
+	order.PositionSide = ""
+}
+
+// exitPositionSide returns the position side, LONG or SHORT, that an exit order closes
+func exitPositionSide(order Order) string {
+	if order.PositionSide == PositionSideLong || order.PositionSide == PositionSideShort {
+		return order.PositionSide
+	}
+	return oppositeSide(orderDirection(order.Side))
+}
+
+// orderPositionSide returns the position side, LONG or SHORT, that an order
+// closes if it is an exit order, or opens otherwise
+func orderPositionSide(order Order) string {
+	if isExitOrder(order) {
+		return exitPositionSide(order)
+	}
+	if order.PositionSide == PositionSideLong || order.PositionSide == PositionSideShort {
+		return order.PositionSide
+	}
+	return orderDirection(order.Side)
+}
+
+// sideLabel names the position of symbol on side, such as "BTCUSDT LONG"
+func sideLabel(symbol, side string) string {
+	return symbol + " " + side
+}
+
+// closesHedgePosition reports whether a hedge mode order closes its position
+// side, such as a SELL against LONG; hedge mode exits are not reduce-only
+func closesHedgePosition(order Order) bool {
+	switch order.PositionSide {
+	case PositionSideLong:
+		return order.Side == "SELL"
+	case PositionSideShort:
+		return order.Side == "BUY"
+	}
+	return false
+}
+
+// reducesPosition reports whether order only closes a position: it is
+// reduce-only, close-position, or names the position side it exits
+func reducesPosition(order *OrderRequest) bool {
+	if order.ReduceOnly || order.ClosePosition {
+		return true
+	}
+	return (order.PositionSide == PositionSideLong || order.PositionSide == PositionSideShort) &&
+		order.PositionSide != orderDirection(order.Side)
+}
+
+// findPosition returns the open position of symbol on side (LONG or SHORT,
+// empty for either), or nil
+func findPosition(positions []Position, symbol, side string) *Position {
+	for i := range positions {
+		if positions[i].Symbol == symbol && (side == "" || positions[i].Side == side) && positions[i].PositionAmt != 0 {
+			return &positions[i]
+		}
+	}
+	return nil
+}
+
+// oppositeSide returns SHORT for LONG and LONG for SHORT
+func oppositeSide(side string) string {
+	if side == PositionSideLong {
+		return PositionSideShort
+	}
+	return PositionSideLong
+}
+
+// GetPositionMode returns the account's position mode, cached after the first call
+func (tc *TradingClient) GetPositionMode(ctx context.Context) (PositionMode, error) {
+	tc.modeMu.Lock()
+	defer tc.modeMu.Unlock()
+
+	if tc.positionMode != "" {
+		return tc.positionMode, nil
+	}
+
+	var result *futures.PositionMode
+	err := tc.retry(ctx, func() error {
+		var err error
+		result, err = tc.BinanceClient.NewGetPositionModeService().Do(ctx)
+		return err
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get position mode: %w", err)
+	}
+
+	tc.positionMode = PositionModeOneWay
+	if result.DualSidePosition {
+		tc.positionMode = PositionModeHedge
+	}
+	log.Printf("📐 Account position mode: %s", tc.positionMode)
+	return tc.positionMode, nil
+}
+
+// resetPositionMode forgets the cached position mode so the next order reloads it
+func (tc *TradingClient) resetPositionMode() {
+	tc.modeMu.Lock()
+	defer tc.modeMu.Unlock()
+	tc.positionMode = ""
+}
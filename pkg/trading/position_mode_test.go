@@ -0,0 +1,147 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestApplyPositionMode tests how orders are adapted to each position mode
+func TestApplyPositionMode(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         PositionMode
+		order        OrderRequest
+		positionSide string
+		reduceOnly   bool
+	}{
+		{"hedge entry", PositionModeHedge, OrderRequest{Side: "BUY", Type: "MARKET"}, PositionSideLong, false},
+		{"hedge reduce-only stop", PositionModeHedge, OrderRequest{Side: "SELL", Type: "STOP_MARKET", ReduceOnly: true}, PositionSideLong, false},
+		{"hedge close-position stop", PositionModeHedge, OrderRequest{Side: "SELL", Type: "STOP_MARKET", ClosePosition: true}, PositionSideLong, false},
+		{"hedge short take profit", PositionModeHedge, OrderRequest{Side: "BUY", Type: "LIMIT", ReduceOnly: true}, PositionSideShort, false},
+		{"hedge explicit side", PositionModeHedge, OrderRequest{Side: "BUY", Type: "MARKET", PositionSide: PositionSideShort}, PositionSideShort, false},
+		{"one-way entry", PositionModeOneWay, OrderRequest{Side: "BUY", Type: "MARKET"}, "", false},
+		{"one-way exit by side", PositionModeOneWay, OrderRequest{Side: "SELL", Type: "MARKET", PositionSide: PositionSideLong}, "", true},
+		{"one-way both", PositionModeOneWay, OrderRequest{Side: "SELL", Type: "LIMIT", ReduceOnly: true, PositionSide: PositionSideBoth}, "", true},
+	}
+
+	for _, tt := range tests {
+		order := tt.order
+		applyPositionMode(&order, tt.mode)
+		if order.PositionSide != tt.positionSide || order.ReduceOnly != tt.reduceOnly {
+			t.Errorf("%s: expected position side %q reduce-only %v, got %q %v", tt.name, tt.positionSide, tt.reduceOnly, order.PositionSide, order.ReduceOnly)
+		}
+	}
+}
+
+// hedgeExchange is a paper exchange reporting a fixed hedge mode account
+type hedgeExchange struct {
+	*PaperExchange
+	positions []Position
+	orders    []Order
+	cancelled []int64
+}
+
+func (he *hedgeExchange) GetPositionMode(ctx context.Context) (PositionMode, error) {
+	return PositionModeHedge, nil
+}
+
+func (he *hedgeExchange) GetPositions(ctx context.Context) ([]Position, error) {
+	return he.positions, nil
+}
+
+func (he *hedgeExchange) GetOpenOrders(ctx context.Context) ([]Order, error) {
+	return he.orders, nil
+}
+
+func (he *hedgeExchange) CancelOrder(ctx context.Context, symbol string, orderID int64) error {
+	he.cancelled = append(he.cancelled, orderID)
+	return nil
+}
+
+// TestHedgeModeExits tests that the exits of a hedge mode position are linked
+// to its own side, not to the opposite position of the same symbol
+func TestHedgeModeExits(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinPrice: 0.1, MaxPrice: 1000000, TickSize: 0.1, MinQty: 0.001, MaxQty: 1000, StepSize: 0.001}}
+	ctx := context.Background()
+
+	he := &hedgeExchange{
+		PaperExchange: pe,
+		positions: []Position{
+			{Symbol: "BTCUSDT", PositionAmt: 1, EntryPrice: 100, Side: "LONG", PositionSide: PositionSideLong},
+		},
+		orders: []Order{
+			{OrderID: 1, Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: 95, OrigQty: 1, PositionSide: PositionSideLong},
+			{OrderID: 2, Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 90, OrigQty: 1, PositionSide: PositionSideShort},
+			{OrderID: 3, Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: 98, OrigQty: 1, PositionSide: PositionSideLong},
+		},
+	}
+
+	if err := NewOCOSupervisor(he).Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	// The SHORT take profit is orphaned; the LONG stop and the LONG entry stay
+	if len(he.cancelled) != 1 || he.cancelled[0] != 2 {
+		t.Errorf("Expected only the SHORT take profit cancelled, got %v", he.cancelled)
+	}
+
+	validator := NewOrderValidator(he)
+	if err := validator.Validate(ctx, &OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "95", Quantity: "1", ReduceOnly: true}); err != nil {
+		t.Errorf("Expected the LONG stop to validate, got %v", err)
+	}
+	if err := validator.Validate(ctx, &OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "STOP_MARKET", StopPrice: "105", Quantity: "1", ReduceOnly: true}); !errors.Is(err, ErrReduceOnly) {
+		t.Errorf("Expected a SHORT stop without a SHORT position to be rejected, got %v", err)
+	}
+}
+
+// TestHedgeModeBothSides tests that a LONG and a SHORT on one symbol are managed apart
+func TestHedgeModeBothSides(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	ctx := context.Background()
+
+	he := &hedgeExchange{
+		PaperExchange: pe,
+		positions: []Position{
+			{Symbol: "BTCUSDT", PositionAmt: 1, EntryPrice: 100, Side: "LONG", PositionSide: PositionSideLong},
+			{Symbol: "BTCUSDT", PositionAmt: 1, EntryPrice: 100, Side: "SHORT", PositionSide: PositionSideShort},
+		},
+		orders: []Order{
+			{OrderID: 1, Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: 95, ClosePosition: true, PositionSide: PositionSideLong},
+			{OrderID: 2, Symbol: "BTCUSDT", Side: "BUY", Type: "STOP_MARKET", StopPrice: 105, ClosePosition: true, PositionSide: PositionSideShort},
+		},
+	}
+
+	pm := NewPositionManager(he, DefaultPositionManagerConfig())
+	pm.Manage(ManagedPosition{Symbol: "BTCUSDT", Side: "LONG", EntryPrice: 100, InitialStop: 95, StopOrderID: 1})
+	pm.Manage(ManagedPosition{Symbol: "BTCUSDT", Side: "SHORT", EntryPrice: 100, InitialStop: 105, StopOrderID: 2})
+	if err := pm.Update(ctx); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if managed := pm.Positions(); len(managed) != 2 {
+		t.Fatalf("Expected both sides managed, got %+v", managed)
+	}
+
+	// Closing the SHORT leaves the LONG managed
+	he.positions = he.positions[:1]
+	if err := pm.Update(ctx); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if managed := pm.Positions(); len(managed) != 1 || managed[0].Side != "LONG" {
+		t.Errorf("Expected only the LONG managed, got %+v", managed)
+	}
+
+	// Limit entries for both sides of a symbol are pending at once
+	m := NewLimitEntryManager(he, DefaultLimitEntryConfig())
+	for _, entry := range []*OrderRequest{
+		{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: "98", Quantity: "1", ClientOrderID: "test-BTCUSDT-abc-ENTRY", PositionSide: PositionSideLong},
+		{Symbol: "BTCUSDT", Side: "SELL", Type: "LIMIT", Price: "102", Quantity: "1", ClientOrderID: "test-BTCUSDT-def-ENTRY", PositionSide: PositionSideShort},
+	} {
+		if err := m.Place(ctx, PendingEntry{Entry: entry}); err != nil {
+			t.Fatalf("Place %s failed: %v", entry.Side, err)
+		}
+	}
+	if pending := m.Pending(); len(pending) != 2 {
+		t.Errorf("Expected a LONG and a SHORT entry pending, got %+v", pending)
+	}
+}
@@ -0,0 +1,284 @@
+package trading
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimits are the Binance Futures REST limits, which apply per IP (weight)
+// and per account (orders)
+type RateLimits struct {
+	WeightPerMinute int
+	OrdersPer10s    int
+	OrdersPerMinute int
+	Threshold       float64 // Fraction of a limit at which requests pause until the window resets
+}
+
+// DefaultRateLimits returns the published Binance Futures limits
+func DefaultRateLimits() RateLimits {
+	return RateLimits{
+		WeightPerMinute: 2400,
+		OrdersPer10s:    300,
+		OrdersPerMinute: 1200,
+		Threshold:       0.9,
+	}
+}
+
+// RateLimiter paces REST requests with token buckets for request weight and
+// order count. The buckets are corrected from the X-MBX-USED-WEIGHT-1M and
+// X-MBX-ORDER-COUNT-* response headers, and every request pauses after a
+// 429 or 418 until Retry-After has passed.
+type RateLimiter struct {
+	limits RateLimits
+
+	mu          sync.Mutex
+	weight      float64 // Available weight tokens
+	orders      float64 // Available order tokens (10 second window)
+	refilledAt  time.Time
+	pausedUntil time.Time
+	usedWeight  int // Last weight reported by Binance
+
+	now func() time.Time
+}
+
+// sharedRateLimiter is used by every TradingClient, since weight limits are per IP
+var sharedRateLimiter = NewRateLimiter(DefaultRateLimits())
+
+// SharedRateLimiter returns the limiter all trading clients in the process share
+func SharedRateLimiter() *RateLimiter {
+	return sharedRateLimiter
+}
+
+// NewRateLimiter creates a limiter with full buckets
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	return &RateLimiter{
+		limits:     limits,
+		weight:     float64(limits.WeightPerMinute),
+		orders:     float64(limits.OrdersPer10s),
+		refilledAt: time.Now(),
+		now:        time.Now,
+	}
+}
+
+// Wait blocks until a request of weight (and one order, if order is set) may be sent
+func (rl *RateLimiter) Wait(ctx context.Context, weight int, order bool) error {
+	for {
+		rl.mu.Lock()
+		now := rl.now()
+		rl.refill(now)
+
+		var wait time.Duration
+		if now.Before(rl.pausedUntil) {
+			wait = rl.pausedUntil.Sub(now)
+		} else {
+			if missing := float64(weight) - rl.weight; missing > 0 {
+				wait = time.Duration(missing / rl.weightRate() * float64(time.Second))
+			}
+			if missing := 1 - rl.orders; order && missing > 0 {
+				if orderWait := time.Duration(missing / rl.orderRate() * float64(time.Second)); orderWait > wait {
+					wait = orderWait
+				}
+			}
+		}
+
+		if wait <= 0 {
+			rl.weight -= float64(weight)
+			if order {
+				rl.orders--
+			}
+			rl.mu.Unlock()
+			return nil
+		}
+		rl.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Update corrects the buckets from a response's headers and pauses all
+// requests after a 429 (rate limited) or 418 (IP banned)
+func (rl *RateLimiter) Update(header http.Header, status int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := rl.now()
+
+	if status == http.StatusTooManyRequests || status == http.StatusTeapot {
+		retryAfter := time.Minute
+		if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		rl.pause(now.Add(retryAfter))
+		rl.weight = 0
+		log.Printf("🚦 Binance returned HTTP %d, pausing all requests for %s", status, retryAfter)
+		return
+	}
+
+	if used, err := strconv.Atoi(header.Get("X-Mbx-Used-Weight-1m")); err == nil {
+		rl.usedWeight = used
+		rl.weight = math.Min(rl.weight, float64(rl.limits.WeightPerMinute-used))
+		if float64(used) >= rl.limits.Threshold*float64(rl.limits.WeightPerMinute) {
+			rl.pause(now.Truncate(time.Minute).Add(time.Minute))
+		}
+	}
+	if count, err := strconv.Atoi(header.Get("X-Mbx-Order-Count-10s")); err == nil {
+		rl.orders = math.Min(rl.orders, float64(rl.limits.OrdersPer10s-count))
+		if float64(count) >= rl.limits.Threshold*float64(rl.limits.OrdersPer10s) {
+			rl.pause(now.Truncate(10 * time.Second).Add(10 * time.Second))
+		}
+	}
+	if count, err := strconv.Atoi(header.Get("X-Mbx-Order-Count-1m")); err == nil {
+		if float64(count) >= rl.limits.Threshold*float64(rl.limits.OrdersPerMinute) {
+			rl.pause(now.Truncate(time.Minute).Add(time.Minute))
+		}
+	}
+}
+
+// UsedWeight returns the request weight Binance last reported for the current minute
+func (rl *RateLimiter) UsedWeight() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.usedWeight
+}
+
+// PausedUntil returns when requests resume, or the zero time when not paused
+func (rl *RateLimiter) PausedUntil() time.Time {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.now().After(rl.pausedUntil) {
+		return time.Time{}
+	}
+	return rl.pausedUntil
+}
+
+// Transport wraps base so every request waits for the limiter and reports its headers
+func (rl *RateLimiter) Transport(base http.RoundTripper) http.RoundTripper {
+	return &rateLimitedTransport{limiter: rl, base: base}
+}
+
+// refill adds the tokens earned since the last refill; the caller must hold the lock
+func (rl *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(rl.refilledAt).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	rl.weight = math.Min(float64(rl.limits.WeightPerMinute), rl.weight+elapsed*rl.weightRate())
+	rl.orders = math.Min(float64(rl.limits.OrdersPer10s), rl.orders+elapsed*rl.orderRate())
+	rl.refilledAt = now
+}
+
+// pause extends the global pause to until; the caller must hold the lock
+func (rl *RateLimiter) pause(until time.Time) {
+	if until.After(rl.pausedUntil) {
+		rl.pausedUntil = until
+	}
+}
+
+// weightRate returns the weight tokens earned per second
+func (rl *RateLimiter) weightRate() float64 {
+	return float64(rl.limits.WeightPerMinute) / 60
+}
+
+// orderRate returns the order tokens earned per second
+func (rl *RateLimiter) orderRate() float64 {
+	return float64(rl.limits.OrdersPer10s) / 10
+}
+
+// rateLimitedTransport is an http.RoundTripper that paces requests through a RateLimiter
+type rateLimitedTransport struct {
+	limiter *RateLimiter
+	base    http.RoundTripper
+}
+
+// RoundTrip waits for the request's weight, sends it and records the response headers
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	order := isOrderEndpoint(req.Method, req.URL.Path)
+	if err := t.limiter.Wait(req.Context(), EndpointWeight(req.Method, req.URL.Path, req.URL.Query()), order); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.limiter.Update(resp.Header, resp.StatusCode)
+	return resp, nil
+}
+
+// isOrderEndpoint reports whether a request counts toward the order rate limit
+func isOrderEndpoint(method, path string) bool {
+	return method == http.MethodPost && (path == "/fapi/v1/order" || path == "/fapi/v1/batchOrders")
+}
+
+// EndpointWeight returns the request weight Binance charges for a futures REST call
+func EndpointWeight(method, path string, query url.Values) int {
+	hasSymbol := query.Get("symbol") != ""
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	switch strings.TrimSuffix(path, "/") {
+	case "/fapi/v1/klines", "/fapi/v1/continuousKlines", "/fapi/v1/markPriceKlines":
+		if limit == 0 {
+			limit = 500
+		}
+		switch {
+		case limit < 100:
+			return 1
+		case limit < 500:
+			return 2
+		case limit <= 1000:
+			return 5
+		default:
+			return 10
+		}
+	case "/fapi/v1/depth":
+		if limit == 0 {
+			limit = 500
+		}
+		switch {
+		case limit <= 50:
+			return 2
+		case limit <= 100:
+			return 5
+		case limit <= 500:
+			return 10
+		default:
+			return 20
+		}
+	case "/fapi/v1/ticker/price", "/fapi/v2/ticker/price", "/fapi/v1/ticker/bookTicker":
+		if hasSymbol {
+			return 1
+		}
+		return 2
+	case "/fapi/v1/ticker/24hr":
+		if hasSymbol {
+			return 1
+		}
+		return 40
+	case "/fapi/v1/openOrders":
+		if hasSymbol {
+			return 1
+		}
+		return 40
+	case "/fapi/v2/account", "/fapi/v2/balance", "/fapi/v2/positionRisk", "/fapi/v1/userTrades", "/fapi/v1/allOrders":
+		return 5
+	case "/fapi/v1/income":
+		return 30
+	case "/fapi/v1/positionSide/dual":
+		if method == http.MethodGet {
+			return 30
+		}
+	case "/fapi/v1/batchOrders":
+		return 5
+	}
+	return 1
+}
@@ -0,0 +1,89 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// TestEndpointWeight tests weights that depend on parameters
+func TestEndpointWeight(t *testing.T) {
+	tests := []struct {
+		method, path, query string
+		expected            int
+	}{
+		{"GET", "/fapi/v1/klines", "symbol=BTCUSDT&limit=50", 1},
+		{"GET", "/fapi/v1/klines", "symbol=BTCUSDT&limit=200", 2},
+		{"GET", "/fapi/v1/klines", "symbol=BTCUSDT", 5},
+		{"GET", "/fapi/v1/klines", "symbol=BTCUSDT&limit=1500", 10},
+		{"GET", "/fapi/v1/openOrders", "", 40},
+		{"GET", "/fapi/v1/openOrders", "symbol=BTCUSDT", 1},
+		{"GET", "/fapi/v2/positionRisk", "", 5},
+		{"POST", "/fapi/v1/order", "symbol=BTCUSDT", 1},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		if got := EndpointWeight(tt.method, tt.path, query); got != tt.expected {
+			t.Errorf("%s %s?%s: expected weight %d, got %d", tt.method, tt.path, tt.query, tt.expected, got)
+		}
+	}
+}
+
+// TestRateLimiterPausesOnRetryAfter tests the global pause after a 429
+func TestRateLimiterPausesOnRetryAfter(t *testing.T) {
+	rl := NewRateLimiter(DefaultRateLimits())
+	rl.Update(http.Header{"Retry-After": []string{"30"}}, http.StatusTooManyRequests)
+
+	if until := rl.PausedUntil(); time.Until(until) < 29*time.Second {
+		t.Fatalf("Expected a 30s pause, paused until %v", until)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := rl.Wait(ctx, 1, false); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Wait to block during the pause, got %v", err)
+	}
+}
+
+// TestRateLimiterReadsHeaders tests that the transport records used weight and
+// pauses once the threshold is reached
+func TestRateLimiterReadsHeaders(t *testing.T) {
+	used := "100"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-MBX-USED-WEIGHT-1M", used)
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	rl := NewRateLimiter(DefaultRateLimits())
+	client := &http.Client{Transport: rl.Transport(http.DefaultTransport)}
+
+	resp, err := client.Get(server.URL + "/fapi/v1/klines?symbol=BTCUSDT&limit=200")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if rl.UsedWeight() != 100 {
+		t.Errorf("Expected used weight 100, got %d", rl.UsedWeight())
+	}
+	if !rl.PausedUntil().IsZero() {
+		t.Errorf("Expected no pause below the threshold")
+	}
+
+	used = "2200"
+	resp, err = client.Get(server.URL + "/fapi/v1/time")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if rl.PausedUntil().IsZero() {
+		t.Errorf("Expected a pause above 90%% of the weight limit")
+	}
+}
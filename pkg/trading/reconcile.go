@@ -0,0 +1,394 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OrderRole is what an open order does for its position
+type OrderRole string
+
+const (
+	RoleStopLoss   OrderRole = "STOP_LOSS"
+	RoleTakeProfit OrderRole = "TAKE_PROFIT"
+	RoleEntry      OrderRole = "ENTRY" // Opens or adds to a position
+)
+
+// ClassifyOrder returns the role of an open order from its type and flags
+func ClassifyOrder(order Order) OrderRole {
+	if !isExitOrder(order) {
+		return RoleEntry
+	}
+	if isStopOrder(order) {
+		return RoleStopLoss
+	}
+	return RoleTakeProfit
+}
+
+// ReconcileActionType is a repair the reconciler makes
+type ReconcileActionType string
+
+const (
+	ActionCancel          ReconcileActionType = "CANCEL"
+	ActionPlaceStop       ReconcileActionType = "PLACE_STOP"
+	ActionPlaceTakeProfit ReconcileActionType = "PLACE_TAKE_PROFIT"
+	ActionResizeStop      ReconcileActionType = "RESIZE_STOP"
+)
+
+// ReconcileAction is one step of a reconcile plan
+type ReconcileAction struct {
+	Type     ReconcileActionType
+	Symbol   string
+	Side     string        // Position side, LONG or SHORT
+	Order    *Order        // Order to cancel or resize
+	Request  *OrderRequest // Order to place
+	Quantity float64       // New stop quantity for RESIZE_STOP
+	Reason   string
+}
+
+// String describes the action, such as "CANCEL BTCUSDT LONG TAKE_PROFIT order 12: no LONG position"
+func (a ReconcileAction) String() string {
+	switch a.Type {
+	case ActionCancel:
+		return fmt.Sprintf("%s %s %s %s order %d: %s", a.Type, a.Symbol, a.Side, ClassifyOrder(*a.Order), a.Order.OrderID, a.Reason)
+	case ActionResizeStop:
+		return fmt.Sprintf("%s %s %s order %d %s → %s: %s", a.Type, a.Symbol, a.Side, a.Order.OrderID,
+			formatFloat(a.Order.OrigQty), formatFloat(a.Quantity), a.Reason)
+	}
+	price := a.Request.Price
+	if price == "" {
+		price = a.Request.StopPrice
+	}
+	return fmt.Sprintf("%s %s %s %s %s @ %s: %s", a.Type, a.Symbol, a.Side, a.Request.Side, a.Request.Quantity, price, a.Reason)
+}
+
+// ReconcilePlan is the set of repairs that brings open orders in line with positions
+type ReconcilePlan struct {
+	Positions int
+	Orders    int
+	Healthy   []string // Positions whose orders need no repair, such as "BTCUSDT LONG"
+	Actions   []ReconcileAction
+}
+
+// Print writes the plan to w
+func (p *ReconcilePlan) Print(w io.Writer) {
+	fmt.Fprintf(w, "📊 Reconcile: %d positions, %d orders\n", p.Positions, p.Orders)
+	for _, label := range p.Healthy {
+		fmt.Fprintf(w, "   ✅ %s: orders match the position\n", label)
+	}
+	for _, action := range p.Actions {
+		fmt.Fprintf(w, "   🔧 %s\n", action)
+	}
+	if len(p.Actions) == 0 {
+		fmt.Fprintln(w, "🎉 All positions and orders are balanced")
+	}
+}
+
+// ReconcileConfig configures the reconciler
+type ReconcileConfig struct {
+	DryRun            bool    // Only print the plan
+	StopLossPercent   float64 // Distance of a repaired stop loss from the entry price
+	TakeProfitPercent float64 // Distance of a repaired take profit from the entry price; 0 leaves it missing
+}
+
+// DefaultReconcileConfig repairs a missing stop 2% and a missing take profit 4% from the entry
+func DefaultReconcileConfig() ReconcileConfig {
+	return ReconcileConfig{
+		StopLossPercent:   2.0,
+		TakeProfitPercent: 4.0,
+	}
+}
+
+// ReconcileConfigFromEnv returns the default config overridden by
+// RECONCILE_DRY_RUN, RECONCILE_STOP_LOSS_PERCENT and RECONCILE_TAKE_PROFIT_PERCENT
+func ReconcileConfigFromEnv() ReconcileConfig {
+	cfg := DefaultReconcileConfig()
+	cfg.DryRun, _ = strconv.ParseBool(os.Getenv("RECONCILE_DRY_RUN"))
+	if percent, err := strconv.ParseFloat(os.Getenv("RECONCILE_STOP_LOSS_PERCENT"), 64); err == nil && percent > 0 {
+		cfg.StopLossPercent = percent
+	}
+	if percent, err := strconv.ParseFloat(os.Getenv("RECONCILE_TAKE_PROFIT_PERCENT"), 64); err == nil && percent >= 0 {
+		cfg.TakeProfitPercent = percent
+	}
+	return cfg
+}
+
+// Reconciler checks every position against its open orders by role. A
+// position keeps one stop loss sized to it and take profits that do not
+// exceed it; a missing leg is placed rather than the position closed. Exit
+// orders of a flat position are cancelled, and entry orders are left alone
+// since they may be resting limit entries.
+type Reconciler struct {
+	ex     Exchange
+	config ReconcileConfig
+}
+
+// NewReconciler creates a reconciler for the account on ex
+func NewReconciler(ex Exchange, config ReconcileConfig) *Reconciler {
+	return &Reconciler{ex: ex, config: config}
+}
+
+// positionOrders is a position with the open orders that open or close it
+type positionOrders struct {
+	symbol      string
+	side        string
+	position    *Position
+	stops       []Order
+	takeProfits []Order
+}
+
+// Plan compares positions with open orders and returns the repairs needed
+func (r *Reconciler) Plan(ctx context.Context) (*ReconcilePlan, error) {
+	orders, err := r.ex.GetOpenOrders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open orders: %w", err)
+	}
+	// Positions are read after orders so a bracket placed in between is not seen as orphaned
+	positions, err := r.ex.GetPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	groups := make(map[string]*positionOrders)
+	group := func(symbol, side string) *positionOrders {
+		label := sideLabel(symbol, side)
+		if groups[label] == nil {
+			groups[label] = &positionOrders{symbol: symbol, side: side}
+		}
+		return groups[label]
+	}
+
+	plan := &ReconcilePlan{Orders: len(orders)}
+	for i := range positions {
+		if positions[i].PositionAmt != 0 {
+			group(positions[i].Symbol, positions[i].Side).position = &positions[i]
+			plan.Positions++
+		}
+	}
+	for _, order := range orders {
+		g := group(order.Symbol, orderPositionSide(order))
+		switch ClassifyOrder(order) {
+		case RoleStopLoss:
+			g.stops = append(g.stops, order)
+		case RoleTakeProfit:
+			g.takeProfits = append(g.takeProfits, order)
+		}
+	}
+
+	labels := make([]string, 0, len(groups))
+	for label := range groups {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	for _, label := range labels {
+		actions, err := r.planPosition(ctx, groups[label])
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan %s: %w", label, err)
+		}
+		if len(actions) == 0 && groups[label].position != nil {
+			plan.Healthy = append(plan.Healthy, label)
+		}
+		plan.Actions = append(plan.Actions, actions...)
+	}
+	return plan, nil
+}
+
+// planPosition returns the repairs for one position side
+func (r *Reconciler) planPosition(ctx context.Context, g *positionOrders) ([]ReconcileAction, error) {
+	var actions []ReconcileAction
+	cancel := func(order Order, reason string) {
+		actions = append(actions, ReconcileAction{Type: ActionCancel, Symbol: g.symbol, Side: g.side, Order: &order, Reason: reason})
+	}
+
+	position := g.position
+	if position == nil {
+		for _, order := range append(g.stops, g.takeProfits...) {
+			cancel(order, fmt.Sprintf("no %s position", g.side))
+		}
+		return actions, nil
+	}
+
+	if len(g.stops) == 0 {
+		request, err := r.repairOrder(ctx, position, RoleStopLoss)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, ReconcileAction{Type: ActionPlaceStop, Symbol: g.symbol, Side: g.side, Request: request, Reason: "no stop loss"})
+	} else {
+		// Keep the stop that protects the most, then the tightest
+		sort.SliceStable(g.stops, func(i, j int) bool {
+			qi, qj := exitCoverage(g.stops[i]), exitCoverage(g.stops[j])
+			if qi != qj {
+				return qi > qj
+			}
+			if g.side == "SHORT" {
+				return g.stops[i].StopPrice < g.stops[j].StopPrice
+			}
+			return g.stops[i].StopPrice > g.stops[j].StopPrice
+		})
+		for _, order := range g.stops[1:] {
+			cancel(order, fmt.Sprintf("duplicate stop loss, keeping order %d", g.stops[0].OrderID))
+		}
+
+		stop := g.stops[0]
+		if stop.Type == "STOP_MARKET" && !stop.ClosePosition && math.Abs(stop.OrigQty-position.PositionAmt) > 1e-9 {
+			actions = append(actions, ReconcileAction{
+				Type:     ActionResizeStop,
+				Symbol:   g.symbol,
+				Side:     g.side,
+				Order:    &stop,
+				Quantity: position.PositionAmt,
+				Reason:   fmt.Sprintf("stop covers %s of a %s position", formatFloat(stop.OrigQty), formatFloat(position.PositionAmt)),
+			})
+		}
+	}
+
+	if len(g.takeProfits) == 0 {
+		if r.config.TakeProfitPercent > 0 {
+			request, err := r.repairOrder(ctx, position, RoleTakeProfit)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, ReconcileAction{Type: ActionPlaceTakeProfit, Symbol: g.symbol, Side: g.side, Request: request, Reason: "no take profit"})
+		}
+		return actions, nil
+	}
+
+	// Nearest targets first; those beyond a fully covered position can never fill
+	sort.SliceStable(g.takeProfits, func(i, j int) bool {
+		return math.Abs(exitPrice(g.takeProfits[i])-position.EntryPrice) < math.Abs(exitPrice(g.takeProfits[j])-position.EntryPrice)
+	})
+	covered := 0.0
+	for _, order := range g.takeProfits {
+		if covered >= position.PositionAmt-1e-9 {
+			cancel(order, fmt.Sprintf("position of %s already covered by nearer take profits", formatFloat(position.PositionAmt)))
+			continue
+		}
+		covered += exitCoverage(order)
+	}
+	return actions, nil
+}
+
+// repairOrder builds the missing stop loss or take profit of position at the
+// configured distance from its entry, or from the mark price once the price
+// is already beyond that level. The order is tagged with the position side and
+// the time of the plan, so a retry of Apply finds the first send.
+func (r *Reconciler) repairOrder(ctx context.Context, position *Position, role OrderRole) (*OrderRequest, error) {
+	markPrice := position.MarkPrice
+	if markPrice <= 0 {
+		ticker, err := r.ex.GetTicker(position.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		markPrice = parseFloat(ticker.Price)
+	}
+
+	direction, side := 1.0, "SELL"
+	if position.Side == "SHORT" {
+		direction, side = -1.0, "BUY"
+	}
+	percent := r.config.StopLossPercent
+	if role == RoleTakeProfit {
+		percent = -r.config.TakeProfitPercent
+	}
+
+	// A stop must stay below a long's mark price and a take profit above it
+	price := position.EntryPrice * (1 - direction*percent/100)
+	if (price-markPrice)*direction*percent >= 0 {
+		price = markPrice * (1 - direction*percent/100)
+	}
+
+	tag := ClientOrderTag{
+		Strategy: "reconcile",
+		Symbol:   position.Symbol,
+		SignalID: position.Side[:1] + strconv.FormatInt(time.Now().UnixMilli(), 36),
+		Leg:      LegStopLoss,
+	}
+	if role == RoleTakeProfit {
+		tag.Leg = LegTakeProfit
+	}
+	request := &OrderRequest{
+		Symbol:        position.Symbol,
+		Side:          side,
+		ReduceOnly:    true,
+		PositionSide:  position.Side,
+		ClientOrderID: tag.ClientOrderID(),
+	}
+	var err error
+	if request.Quantity, err = FormatMarketQuantityFor(ctx, r.ex, position.Symbol, position.PositionAmt); err != nil {
+		return nil, err
+	}
+	if role == RoleStopLoss {
+		request.Type = "STOP_MARKET"
+		request.StopPrice, err = FormatPriceFor(ctx, r.ex, position.Symbol, price)
+	} else {
+		request.Type = "LIMIT"
+		request.TimeInForce = "GTC"
+		request.Price, err = FormatPriceFor(ctx, r.ex, position.Symbol, price)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// Apply carries out the plan's actions, continuing past failures
+func (r *Reconciler) Apply(ctx context.Context, plan *ReconcilePlan) error {
+	var errs []error
+	for _, action := range plan.Actions {
+		var err error
+		switch action.Type {
+		case ActionCancel:
+			err = r.ex.CancelOrder(ctx, action.Symbol, action.Order.OrderID)
+		case ActionResizeStop:
+			_, err = replaceStop(ctx, r.ex, *action.Order, action.Order.StopPrice, action.Quantity)
+		case ActionPlaceStop, ActionPlaceTakeProfit:
+			_, err = placeWithRetry(ctx, r.ex, action.Request)
+		}
+		if err != nil {
+			log.Printf("⚠️  Reconcile %s failed: %v", action, err)
+			errs = append(errs, fmt.Errorf("%s %s %s: %w", action.Type, action.Symbol, action.Side, err))
+			continue
+		}
+		log.Printf("🔧 %s", action)
+	}
+	return errors.Join(errs...)
+}
+
+// Reconcile plans the repairs, prints the plan and applies it unless DryRun is set
+func (r *Reconciler) Reconcile(ctx context.Context) (*ReconcilePlan, error) {
+	plan, err := r.Plan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan.Print(os.Stdout)
+	if r.config.DryRun || len(plan.Actions) == 0 {
+		return plan, nil
+	}
+	return plan, r.Apply(ctx, plan)
+}
+
+// exitCoverage returns the quantity an exit order closes; a close-position order closes all of it
+func exitCoverage(order Order) float64 {
+	if order.ClosePosition {
+		return math.Inf(1)
+	}
+	return order.OrigQty
+}
+
+// exitPrice returns the price an exit order triggers or fills at
+func exitPrice(order Order) float64 {
+	if order.Price > 0 {
+		return order.Price
+	}
+	return order.StopPrice
+}
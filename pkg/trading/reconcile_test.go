@@ -0,0 +1,114 @@
+package trading
+
+import (
+	"context"
+	"testing"
+)
+
+// TestReconcileRepairsPosition tests that a position keeps one stop sized to
+// it and gets a missing take profit, in dry-run and when applied
+func TestReconcileRepairsPosition(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinPrice: 0.1, MaxPrice: 1000000, TickSize: 0.1, MinQty: 0.001, MaxQty: 1000, StepSize: 0.001}}
+	ctx := context.Background()
+
+	for _, order := range []*OrderRequest{
+		{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"},
+		{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "95", Quantity: "0.5", ReduceOnly: true},
+		{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "96", Quantity: "0.4", ReduceOnly: true},
+		{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Price: "90", Quantity: "1"},
+	} {
+		if _, err := pe.CreateOrder(order); err != nil {
+			t.Fatalf("Failed to place %s: %v", order.Type, err)
+		}
+	}
+
+	config := DefaultReconcileConfig()
+	config.DryRun = true
+	plan, err := NewReconciler(pe, config).Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	expected := []ReconcileActionType{ActionCancel, ActionResizeStop, ActionPlaceTakeProfit}
+	if len(plan.Actions) != len(expected) {
+		t.Fatalf("Expected %v, got %+v", expected, plan.Actions)
+	}
+	for i, action := range plan.Actions {
+		if action.Type != expected[i] {
+			t.Errorf("Action %d: expected %s, got %s", i+1, expected[i], action)
+		}
+	}
+	if plan.Actions[0].Order.StopPrice != 96 || plan.Actions[2].Request.Price != "104.0" {
+		t.Errorf("Expected the smaller stop cancelled and a take profit at 104, got %s and %s", plan.Actions[0], plan.Actions[2])
+	}
+	if orders, _ := pe.GetOpenOrders(ctx); len(orders) != 3 {
+		t.Fatalf("Expected a dry run to leave the orders, got %+v", orders)
+	}
+
+	if err := NewReconciler(pe, DefaultReconcileConfig()).Apply(ctx, plan); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	roles := make(map[OrderRole]int)
+	orders, _ := pe.GetOpenOrders(ctx)
+	for _, order := range orders {
+		roles[ClassifyOrder(order)]++
+		if ClassifyOrder(order) == RoleStopLoss && (order.OrigQty != 1 || order.StopPrice != 95) {
+			t.Errorf("Expected the stop resized to 1 at 95, got %+v", order)
+		}
+	}
+	if roles[RoleStopLoss] != 1 || roles[RoleTakeProfit] != 1 || roles[RoleEntry] != 1 {
+		t.Errorf("Expected a stop, a take profit and the entry, got %v", roles)
+	}
+
+	// The repair is tagged, so resending it finds the order already placed
+	if tag, ok := ParseClientOrderID(plan.Actions[2].Request.ClientOrderID); !ok || tag.Strategy != "reconcile" || tag.Leg != LegTakeProfit {
+		t.Errorf("Expected a tagged take profit, got %q", plan.Actions[2].Request.ClientOrderID)
+	}
+	if _, err := placeWithRetry(ctx, pe, plan.Actions[2].Request); err != nil {
+		t.Fatalf("Resending the take profit failed: %v", err)
+	}
+	if orders, _ := pe.GetOpenOrders(ctx); len(orders) != 3 {
+		t.Errorf("Expected the resent take profit found rather than placed, got %+v", orders)
+	}
+
+	plan, err = NewReconciler(pe, config).Plan(ctx)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan.Actions) != 0 || len(plan.Healthy) != 1 {
+		t.Errorf("Expected a balanced account, got %+v", plan.Actions)
+	}
+}
+
+// TestReconcileCancelsOrphans tests that exits of a flat side and take
+// profits beyond the position are cancelled while entries are kept
+func TestReconcileCancelsOrphans(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinPrice: 0.1, MaxPrice: 1000000, TickSize: 0.1, MinQty: 0.001, MaxQty: 1000, StepSize: 0.001}}
+	he := &hedgeExchange{
+		PaperExchange: pe,
+		positions: []Position{
+			{Symbol: "BTCUSDT", PositionAmt: 1, EntryPrice: 100, MarkPrice: 100, Side: "LONG", PositionSide: PositionSideLong},
+		},
+		orders: []Order{
+			{OrderID: 1, Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: 95, OrigQty: 1, PositionSide: PositionSideLong},
+			{OrderID: 2, Symbol: "BTCUSDT", Side: "SELL", Type: "LIMIT", Price: 120, OrigQty: 0.5, PositionSide: PositionSideLong},
+			{OrderID: 3, Symbol: "BTCUSDT", Side: "SELL", Type: "LIMIT", Price: 105, OrigQty: 0.6, PositionSide: PositionSideLong},
+			{OrderID: 4, Symbol: "BTCUSDT", Side: "SELL", Type: "LIMIT", Price: 110, OrigQty: 0.4, PositionSide: PositionSideLong},
+			{OrderID: 5, Symbol: "BTCUSDT", Side: "BUY", Type: "STOP_MARKET", StopPrice: 105, ClosePosition: true, PositionSide: PositionSideShort},
+			{OrderID: 6, Symbol: "BTCUSDT", Side: "SELL", Type: "LIMIT", Price: 102, OrigQty: 1, PositionSide: PositionSideShort},
+		},
+	}
+	ctx := context.Background()
+
+	plan, err := NewReconciler(he, DefaultReconcileConfig()).Plan(ctx)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if err := NewReconciler(he, DefaultReconcileConfig()).Apply(ctx, plan); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(he.cancelled) != 2 || he.cancelled[0] != 2 || he.cancelled[1] != 5 {
+		t.Errorf("Expected the 120 take profit and the SHORT stop cancelled, got %v (%+v)", he.cancelled, plan.Actions)
+	}
+}
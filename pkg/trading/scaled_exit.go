@@ -0,0 +1,201 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ScaledExitConfig configures scaling out of a position at several take
+// profits placed on Fibonacci extensions of the recent swing range
+type ScaledExitConfig struct {
+	Enabled  bool
+	Ratios   []float64 // Extensions of the swing range beyond the breakout side, one target each
+	Percents []float64 // Share of the position closed at each target, summing to 100
+	Lookback int       // Candles in the swing range
+}
+
+// DefaultScaledExitConfig closes 50%, 30% and 20% at the 38.2%, 61.8% and
+// 100% extensions of the last 20 candles. Scaling out is off by default.
+func DefaultScaledExitConfig() ScaledExitConfig {
+	return ScaledExitConfig{
+		Ratios:   []float64{0.382, 0.618, 1.0},
+		Percents: []float64{50, 30, 20},
+		Lookback: 20,
+	}
+}
+
+// ScaledExitConfigFromEnv returns the default config overridden by SCALED_TP
+// (true to enable), SCALED_TP_PERCENTS ("50,30,20"), SCALED_TP_RATIOS
+// ("0.382,0.618,1") and SCALED_TP_LOOKBACK. Percents and ratios that do not
+// pair up or do not sum to 100 are ignored with a warning.
+func ScaledExitConfigFromEnv() ScaledExitConfig {
+	cfg := DefaultScaledExitConfig()
+	cfg.Enabled, _ = strconv.ParseBool(os.Getenv("SCALED_TP"))
+
+	percents, ratios := cfg.Percents, cfg.Ratios
+	if value := os.Getenv("SCALED_TP_PERCENTS"); value != "" {
+		percents = parseFloatList(value)
+	}
+	if value := os.Getenv("SCALED_TP_RATIOS"); value != "" {
+		ratios = parseFloatList(value)
+	}
+	if err := validateScaledExit(percents, ratios); err != nil {
+		log.Printf("⚠️  Ignoring scaled take profit settings: %v", err)
+	} else {
+		cfg.Percents, cfg.Ratios = percents, ratios
+	}
+
+	if lookback, err := strconv.Atoi(os.Getenv("SCALED_TP_LOOKBACK")); err == nil && lookback > 1 {
+		cfg.Lookback = lookback
+	}
+	return cfg
+}
+
+// TakeProfitTarget is one take profit of a scaled exit
+type TakeProfitTarget struct {
+	Price   float64
+	Percent float64 // Share of the position closed at Price
+}
+
+// FibonacciTargets returns a target per ratio at the Fibonacci extension of the
+// high-low range above high for LONG or below low for SHORT, nearest first
+func (c ScaledExitConfig) FibonacciTargets(high, low float64, side string) []TakeProfitTarget {
+	diff := high - low
+	targets := make([]TakeProfitTarget, 0, len(c.Ratios))
+	for i, ratio := range c.Ratios {
+		price := high + diff*ratio
+		if side == "SHORT" {
+			price = low - diff*ratio
+		}
+		targets = append(targets, TakeProfitTarget{Price: price, Percent: c.Percents[i]})
+	}
+	return targets
+}
+
+// TakeProfitLeg returns the client order ID leg of the nth take profit: TP1, TP2, ...
+func TakeProfitLeg(n int) OrderLeg {
+	return LegTakeProfit + OrderLeg(strconv.Itoa(n))
+}
+
+// ScaledTakeProfits builds a reduce-only LIMIT order per target closing its
+// share of quantity, for a position entered with side (BUY or SELL) at price.
+// Quantities are rounded down to the step size and the last target takes the
+// remainder, so the orders add up to the position. Targets already passed by
+// price, or whose share is below the minimum quantity, are merged into the
+// next one. When tag has a strategy the orders are tagged TP1, TP2, ...
+func ScaledTakeProfits(ctx context.Context, ex Exchange, symbol, side string, quantity, price float64, targets []TakeProfitTarget, tag ClientOrderTag) ([]*OrderRequest, error) {
+	filters, err := ex.Symbols().Get(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	direction, exitSide := 1.0, "SELL"
+	if side == "SELL" {
+		direction, exitSide = -1.0, "BUY"
+	}
+
+	// A limit on the wrong side of the price would fill at once
+	var reachable []TakeProfitTarget
+	carried := 0.0
+	for _, target := range targets {
+		if (target.Price-price)*direction <= 0 {
+			carried += target.Percent
+			continue
+		}
+		target.Percent += carried
+		carried = 0
+		reachable = append(reachable, target)
+	}
+	if len(reachable) == 0 {
+		return nil, fmt.Errorf("no take profit target beyond %s for %s", formatFloat(price), symbol)
+	}
+	if carried > 0 {
+		reachable[len(reachable)-1].Percent += carried
+	}
+
+	step, minQty, _ := filters.LotSize("LIMIT")
+	total := filters.RoundQuantity(quantity, "LIMIT")
+
+	// Round cumulative quantities so rounding never adds up to more than the position
+	var legs []TakeProfitTarget
+	var sizes []decimal.Decimal
+	placed, cumulative := decimal.Zero, 0.0
+	for i, target := range reachable {
+		cumulative += target.Percent
+		upTo := total
+		if i < len(reachable)-1 {
+			upTo = floorToStep(total.Mul(decimal.NewFromFloat(math.Min(cumulative, 100)/100)), step)
+		}
+		size := upTo.Sub(placed)
+		if size.LessThan(minQty) || !size.IsPositive() {
+			if i == len(reachable)-1 && len(sizes) > 0 {
+				sizes[len(sizes)-1] = sizes[len(sizes)-1].Add(size)
+			}
+			continue
+		}
+		legs = append(legs, target)
+		sizes = append(sizes, size)
+		placed = upTo
+	}
+	if len(legs) == 0 {
+		return nil, fmt.Errorf("quantity %s of %s is too small to scale out", formatFloat(quantity), symbol)
+	}
+
+	orders := make([]*OrderRequest, 0, len(legs))
+	for i, target := range legs {
+		order := &OrderRequest{
+			Symbol:      symbol,
+			Side:        exitSide,
+			Type:        "LIMIT",
+			Quantity:    sizes[i].StringFixed(stepDecimals(step)),
+			Price:       filters.FormatPrice(target.Price),
+			TimeInForce: "GTC",
+			ReduceOnly:  true,
+		}
+		if tag.Strategy != "" {
+			tag.Leg = TakeProfitLeg(i + 1)
+			order.ClientOrderID = tag.ClientOrderID()
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// validateScaledExit checks that each target has a ratio and the percents sum to 100
+func validateScaledExit(percents, ratios []float64) error {
+	if len(percents) == 0 || len(percents) != len(ratios) {
+		return fmt.Errorf("%d percents for %d ratios", len(percents), len(ratios))
+	}
+	sum := 0.0
+	for i, percent := range percents {
+		if percent <= 0 || ratios[i] <= 0 {
+			return fmt.Errorf("percents and ratios must be positive")
+		}
+		sum += percent
+	}
+	if math.Abs(sum-100) > 1e-6 {
+		return fmt.Errorf("percents sum to %s, not 100", formatFloat(sum))
+	}
+	return nil
+}
+
+// parseFloatList parses a comma separated list such as "50,30,20"; it returns
+// nil if any entry is not a number
+func parseFloatList(value string) []float64 {
+	var values []float64
+	for _, part := range strings.Split(value, ",") {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil
+		}
+		values = append(values, parsed)
+	}
+	return values
+}
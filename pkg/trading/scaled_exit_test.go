@@ -0,0 +1,99 @@
+package trading
+
+import (
+	"context"
+	"testing"
+)
+
+// TestScaledTakeProfits tests splitting a position over Fibonacci extension targets
+func TestScaledTakeProfits(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinPrice: 0.1, MaxPrice: 1000000, TickSize: 0.1, MinQty: 0.001, MaxQty: 1000, StepSize: 0.001}}
+	ctx := context.Background()
+
+	targets := DefaultScaledExitConfig().FibonacciTargets(100, 90, "LONG")
+	tag := ClientOrderTag{Strategy: "test", Symbol: "BTCUSDT", SignalID: "abc"}
+
+	orders, err := ScaledTakeProfits(ctx, pe, "BTCUSDT", "BUY", 1, 100, targets, tag)
+	if err != nil {
+		t.Fatalf("ScaledTakeProfits failed: %v", err)
+	}
+	expected := []struct{ price, quantity, id string }{
+		{"103.8", "0.500", "test-BTCUSDT-abc-TP1"},
+		{"106.2", "0.300", "test-BTCUSDT-abc-TP2"},
+		{"110.0", "0.200", "test-BTCUSDT-abc-TP3"},
+	}
+	if len(orders) != len(expected) {
+		t.Fatalf("Expected %d take profits, got %d", len(expected), len(orders))
+	}
+	for i, order := range orders {
+		if order.Side != "SELL" || !order.ReduceOnly || order.Price != expected[i].price || order.Quantity != expected[i].quantity || order.ClientOrderID != expected[i].id {
+			t.Errorf("Take profit %d: expected %+v, got %+v", i+1, expected[i], order)
+		}
+	}
+
+	// Too small to split three ways: the middle share merges into the last
+	orders, err = ScaledTakeProfits(ctx, pe, "BTCUSDT", "BUY", 0.002, 100, targets, ClientOrderTag{})
+	if err != nil {
+		t.Fatalf("ScaledTakeProfits failed: %v", err)
+	}
+	if len(orders) != 2 || orders[0].Quantity != "0.001" || orders[1].Quantity != "0.001" || orders[1].Price != "110.0" {
+		t.Errorf("Expected 0.001 at TP1 and TP3, got %+v %+v", orders[0], orders[len(orders)-1])
+	}
+
+	// The price is already past the first target
+	orders, err = ScaledTakeProfits(ctx, pe, "BTCUSDT", "BUY", 1, 105, targets, ClientOrderTag{})
+	if err != nil {
+		t.Fatalf("ScaledTakeProfits failed: %v", err)
+	}
+	if len(orders) != 2 || orders[0].Price != "106.2" || orders[0].Quantity != "0.800" {
+		t.Errorf("Expected 0.8 at 106.2, got %+v", orders[0])
+	}
+
+	short := DefaultScaledExitConfig().FibonacciTargets(100, 90, "SHORT")
+	if short[2].Price != 80 {
+		t.Errorf("Expected the short 100%% extension at 80, got %v", short[2].Price)
+	}
+}
+
+// TestScaledBracketResizesStop tests that the stop follows the remaining
+// position once the first target fills
+func TestScaledBracketResizesStop(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinPrice: 0.1, MaxPrice: 1000000, TickSize: 0.1, MinQty: 0.001, MaxQty: 1000, StepSize: 0.001}}
+	ctx := context.Background()
+
+	takeProfits, err := ScaledTakeProfits(ctx, pe, "BTCUSDT", "BUY", 1, 100, DefaultScaledExitConfig().FibonacciTargets(100, 90, "LONG"), ClientOrderTag{})
+	if err != nil {
+		t.Fatalf("ScaledTakeProfits failed: %v", err)
+	}
+	bracket, err := PlaceBracket(ctx, pe,
+		&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"},
+		&OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", StopPrice: "95", Quantity: "1", ReduceOnly: true},
+		takeProfits...)
+	if err != nil {
+		t.Fatalf("PlaceBracket failed: %v", err)
+	}
+	if len(bracket.TakeProfitOrderIDs) != 3 {
+		t.Fatalf("Expected 3 take profits, got %v", bracket.TakeProfitOrderIDs)
+	}
+
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 3600000, Open: 100, High: 104, Low: 100, Close: 103, CloseTime: 7199999})
+	if err := NewOCOSupervisor(pe).Poll(ctx); err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+
+	orders, _ := pe.GetOpenOrders(ctx)
+	stops := 0
+	for _, order := range orders {
+		if order.Type == "STOP_MARKET" {
+			stops++
+			if order.OrigQty != 0.5 {
+				t.Errorf("Expected the stop resized to 0.5, got %v", order.OrigQty)
+			}
+		}
+	}
+	if stops != 1 || len(orders) != 3 {
+		t.Errorf("Expected the stop and two take profits, got %+v", orders)
+	}
+}
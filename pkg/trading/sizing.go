@@ -0,0 +1,176 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrPositionTooSmall is returned when the sized quantity is below the symbol's minimum
+var ErrPositionTooSmall = errors.New("position size too small")
+
+// SizingConfig configures risk-based position sizing
+type SizingConfig struct {
+	RiskPercent        float64 // Equity lost if the stop is hit, in percent
+	MaxNotional        float64 // Largest position notional in USDT; 0 for no limit
+	MarginUsagePercent float64 // Share of the available balance a new position may use as margin
+}
+
+// DefaultSizingConfig risks 1% of equity per trade using at most 90% of the available margin
+func DefaultSizingConfig() SizingConfig {
+	return SizingConfig{
+		RiskPercent:        1.0,
+		MarginUsagePercent: 90.0,
+	}
+}
+
+// SizingConfigFromEnv returns the default config overridden by RISK_PERCENT,
+// MAX_NOTIONAL and MARGIN_USAGE_PERCENT
+func SizingConfigFromEnv() SizingConfig {
+	cfg := DefaultSizingConfig()
+	if percent, err := strconv.ParseFloat(os.Getenv("RISK_PERCENT"), 64); err == nil && percent > 0 {
+		cfg.RiskPercent = percent
+	}
+	if notional, err := strconv.ParseFloat(os.Getenv("MAX_NOTIONAL"), 64); err == nil && notional >= 0 {
+		cfg.MaxNotional = notional
+	}
+	if percent, err := strconv.ParseFloat(os.Getenv("MARGIN_USAGE_PERCENT"), 64); err == nil && percent > 0 && percent <= 100 {
+		cfg.MarginUsagePercent = percent
+	}
+	return cfg
+}
+
+// SizeRequest describes the trade to size
+type SizeRequest struct {
+	Symbol    string
+	OrderType string // Entry order type, for the lot size
+	Entry     float64
+	Stop      float64
+	Leverage  int // Leverage the position is opened at
+}
+
+// PositionSize is a sized position and why it has that size
+type PositionSize struct {
+	Quantity    float64 // Rounded to the lot size step
+	Notional    float64
+	Margin      float64 // Initial margin at the leverage
+	Risk        float64 // USDT lost if the stop is hit
+	Equity      float64
+	Leverage    int
+	LimitedBy   string // risk, leverage, bracket, max notional or margin
+	Explanation string
+}
+
+// PositionSizer sizes positions so that a stop loss costs a fixed share of equity
+type PositionSizer struct {
+	ex     Exchange
+	config SizingConfig
+}
+
+// NewPositionSizer creates a sizer for positions on ex
+func NewPositionSizer(ex Exchange, config SizingConfig) *PositionSizer {
+	return &PositionSizer{ex: ex, config: config}
+}
+
+// Size returns the quantity for which (entry − stop) × quantity is
+// RiskPercent of equity, reduced to fit the leverage on equity, the leverage
+// bracket, MaxNotional and the available margin, then rounded down to the
+// lot size step
+func (s *PositionSizer) Size(ctx context.Context, req SizeRequest) (*PositionSize, error) {
+	distance := math.Abs(req.Entry - req.Stop)
+	if req.Entry <= 0 || distance == 0 {
+		return nil, fmt.Errorf("invalid entry %s and stop %s for %s", formatFloat(req.Entry), formatFloat(req.Stop), req.Symbol)
+	}
+	leverage := req.Leverage
+	if leverage < 1 {
+		leverage = 1
+	}
+
+	balance, err := s.ex.GetUSDTBalance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+	equity := accountEquity(balance)
+
+	riskBudget := equity * s.config.RiskPercent / 100
+	notional := riskBudget / distance * req.Entry
+	limitedBy := "risk"
+	reasons := []string{fmt.Sprintf("%.2f%% of %.2f USDT equity = %.2f USDT over a %s stop distance (%.2f%%)",
+		s.config.RiskPercent, equity, riskBudget, formatFloat(distance), distance/req.Entry*100)}
+
+	limit := func(name string, maxNotional float64, detail string) {
+		if maxNotional < notional {
+			notional, limitedBy = maxNotional, name
+			reasons = append(reasons, fmt.Sprintf("capped at %.2f USDT notional by %s (%s)", maxNotional, name, detail))
+		}
+	}
+	limit("leverage", equity*float64(leverage), fmt.Sprintf("%dx on equity", leverage))
+	if brackets, err := s.ex.GetLeverageBrackets(ctx, req.Symbol); err != nil {
+		log.Printf("⚠️  Sizing %s without leverage brackets: %v", req.Symbol, err)
+	} else if maxNotional, ok := MaxNotional(brackets, leverage); ok {
+		limit("bracket", maxNotional, fmt.Sprintf("largest position at %dx", leverage))
+	}
+	if s.config.MaxNotional > 0 {
+		limit("max notional", s.config.MaxNotional, "MAX_NOTIONAL")
+	}
+	limit("margin", balance.AvailableBalance*s.config.MarginUsagePercent/100*float64(leverage),
+		fmt.Sprintf("%.0f%% of %.2f USDT available at %dx", s.config.MarginUsagePercent, balance.AvailableBalance, leverage))
+
+	filters, err := s.ex.Symbols().Get(ctx, req.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get symbol filters: %w", err)
+	}
+	quantity := filters.RoundQuantity(notional/req.Entry, req.OrderType).InexactFloat64()
+
+	size := &PositionSize{
+		Quantity:  quantity,
+		Notional:  quantity * req.Entry,
+		Margin:    quantity * req.Entry / float64(leverage),
+		Risk:      quantity * distance,
+		Equity:    equity,
+		Leverage:  leverage,
+		LimitedBy: limitedBy,
+	}
+	reasons = append(reasons, fmt.Sprintf("%s %s = %.2f USDT notional, %.2f USDT margin, %.2f USDT at risk",
+		filters.FormatQuantity(quantity, req.OrderType), req.Symbol, size.Notional, size.Margin, size.Risk))
+	size.Explanation = strings.Join(reasons, "; ")
+
+	_, minQty, _ := filters.LotSize(req.OrderType)
+	if quantity <= 0 || quantity < minQty.InexactFloat64() {
+		return size, fmt.Errorf("%w: %s", ErrPositionTooSmall, size.Explanation)
+	}
+	if minNotional := filters.MinNotional.InexactFloat64(); size.Notional < minNotional {
+		return size, fmt.Errorf("%w: %.2f USDT notional below the %.2f minimum for %s", ErrPositionTooSmall, size.Notional, minNotional, req.Symbol)
+	}
+
+	log.Printf("📏 Sized %s (limited by %s): %s", req.Symbol, limitedBy, size.Explanation)
+	return size, nil
+}
+
+// RiskNotional returns the notional at which the stop of req costs
+// RiskPercent of equity, before any leverage, bracket or margin cap
+func (s *PositionSizer) RiskNotional(ctx context.Context, req SizeRequest) (float64, error) {
+	distance := math.Abs(req.Entry - req.Stop)
+	if req.Entry <= 0 || distance == 0 {
+		return 0, fmt.Errorf("invalid entry %s and stop %s for %s", formatFloat(req.Entry), formatFloat(req.Stop), req.Symbol)
+	}
+
+	balance, err := s.ex.GetUSDTBalance(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get balance: %w", err)
+	}
+	return accountEquity(balance) * s.config.RiskPercent / 100 / distance * req.Entry, nil
+}
+
+// accountEquity returns the margin balance, or the wallet balance when it is not reported
+func accountEquity(balance *AccountBalance) float64 {
+	if balance.MarginBalance > 0 {
+		return balance.MarginBalance
+	}
+	return balance.WalletBalance
+}
@@ -0,0 +1,59 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestPositionSizer tests that the stop distance sets the size until a cap is reached
+func TestPositionSizer(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    SizingConfig
+		brackets  []LeverageBracket
+		stop      float64
+		leverage  int
+		quantity  float64
+		limitedBy string
+	}{
+		{"risk", SizingConfig{RiskPercent: 1, MarginUsagePercent: 90}, nil, 98, 10, 5, "risk"},
+		{"short stop", SizingConfig{RiskPercent: 1, MarginUsagePercent: 90}, nil, 104, 10, 2.5, "risk"},
+		{"leverage", SizingConfig{RiskPercent: 1, MarginUsagePercent: 100}, nil, 99.9, 3, 30, "leverage"},
+		{"bracket", SizingConfig{RiskPercent: 1, MarginUsagePercent: 100}, []LeverageBracket{
+			{Bracket: 1, InitialLeverage: 20, NotionalCap: 2000},
+			{Bracket: 2, InitialLeverage: 5, NotionalFloor: 2000, NotionalCap: 50000},
+		}, 99.9, 10, 20, "bracket"},
+		{"max notional", SizingConfig{RiskPercent: 1, MaxNotional: 1000, MarginUsagePercent: 100}, nil, 99.9, 10, 10, "max notional"},
+		{"margin", SizingConfig{RiskPercent: 1, MarginUsagePercent: 50}, nil, 99.9, 1, 5, "margin"},
+	}
+
+	for _, tt := range tests {
+		pe := newTestPaperExchange(100)
+		pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinPrice: 0.1, MaxPrice: 1000000, TickSize: 0.1, MinQty: 0.001, MaxQty: 1000, StepSize: 0.001}}
+		sizer := NewPositionSizer(&bracketExchange{PaperExchange: pe, brackets: tt.brackets}, tt.config)
+
+		size, err := sizer.Size(context.Background(), SizeRequest{Symbol: "BTCUSDT", OrderType: "MARKET", Entry: 100, Stop: tt.stop, Leverage: tt.leverage})
+		if err != nil {
+			t.Errorf("%s: Size failed: %v", tt.name, err)
+			continue
+		}
+		if size.Quantity != tt.quantity || size.LimitedBy != tt.limitedBy {
+			t.Errorf("%s: expected %v limited by %s, got %v limited by %s (%s)", tt.name, tt.quantity, tt.limitedBy, size.Quantity, size.LimitedBy, size.Explanation)
+		}
+	}
+}
+
+// TestPositionSizerTooSmall tests that a size rounding to nothing is rejected
+func TestPositionSizerTooSmall(t *testing.T) {
+	pe := newTestPaperExchange(100)
+	pe.config.Pairs = []TradingPair{{Symbol: "BTCUSDT", MinPrice: 0.1, MaxPrice: 1000000, TickSize: 0.1, MinQty: 0.001, MaxQty: 1000, StepSize: 0.001}}
+	sizer := NewPositionSizer(pe, SizingConfig{RiskPercent: 0.0001, MarginUsagePercent: 90})
+
+	if _, err := sizer.Size(context.Background(), SizeRequest{Symbol: "BTCUSDT", OrderType: "MARKET", Entry: 100, Stop: 98, Leverage: 10}); !errors.Is(err, ErrPositionTooSmall) {
+		t.Errorf("Expected ErrPositionTooSmall, got %v", err)
+	}
+	if _, err := sizer.Size(context.Background(), SizeRequest{Symbol: "BTCUSDT", OrderType: "MARKET", Entry: 100, Stop: 100, Leverage: 10}); err == nil {
+		t.Error("Expected a stop at the entry to be rejected")
+	}
+}
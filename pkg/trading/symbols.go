@@ -0,0 +1,265 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/shopspring/decimal"
+)
+
+// SymbolFilters holds the trading rules of one symbol from exchange info
+type SymbolFilters struct {
+	Symbol            string
+	Status            string
+	PricePrecision    int
+	QuantityPrecision int
+
+	// PRICE_FILTER
+	TickSize decimal.Decimal
+	MinPrice decimal.Decimal
+	MaxPrice decimal.Decimal
+
+	// LOT_SIZE (limit and stop orders)
+	StepSize decimal.Decimal
+	MinQty   decimal.Decimal
+	MaxQty   decimal.Decimal
+
+	// MARKET_LOT_SIZE (market orders)
+	MarketStepSize decimal.Decimal
+	MarketMinQty   decimal.Decimal
+	MarketMaxQty   decimal.Decimal
+
+	// MIN_NOTIONAL
+	MinNotional decimal.Decimal
+
+	// PERCENT_PRICE: limit prices must stay within mark price × [down, up]
+	MultiplierUp   decimal.Decimal
+	MultiplierDown decimal.Decimal
+}
+
+// isMarketOrder reports whether an order type fills at market and uses MARKET_LOT_SIZE
+func isMarketOrder(orderType string) bool {
+	switch orderType {
+	case "MARKET", "STOP_MARKET", "TAKE_PROFIT_MARKET", "TRAILING_STOP_MARKET":
+		return true
+	}
+	return false
+}
+
+// LotSize returns the step, minimum and maximum quantity for an order type
+func (f *SymbolFilters) LotSize(orderType string) (step, min, max decimal.Decimal) {
+	if isMarketOrder(orderType) && f.MarketStepSize.IsPositive() {
+		return f.MarketStepSize, f.MarketMinQty, f.MarketMaxQty
+	}
+	return f.StepSize, f.MinQty, f.MaxQty
+}
+
+// RoundQuantity rounds quantity down to the step size of the order type
+func (f *SymbolFilters) RoundQuantity(quantity float64, orderType string) decimal.Decimal {
+	step, _, _ := f.LotSize(orderType)
+	return floorToStep(decimal.NewFromFloat(quantity), step)
+}
+
+// RoundPrice rounds price to the nearest tick
+func (f *SymbolFilters) RoundPrice(price float64) decimal.Decimal {
+	value := decimal.NewFromFloat(price)
+	if !f.TickSize.IsPositive() {
+		return value
+	}
+	return value.Div(f.TickSize).Round(0).Mul(f.TickSize)
+}
+
+// FormatQuantity returns quantity rounded down to the step size as an order string
+func (f *SymbolFilters) FormatQuantity(quantity float64, orderType string) string {
+	step, _, _ := f.LotSize(orderType)
+	return f.RoundQuantity(quantity, orderType).StringFixed(stepDecimals(step))
+}
+
+// FormatPrice returns price rounded to the tick size as an order string
+func (f *SymbolFilters) FormatPrice(price float64) string {
+	return f.RoundPrice(price).StringFixed(stepDecimals(f.TickSize))
+}
+
+// floorToStep rounds value down to a multiple of step
+func floorToStep(value, step decimal.Decimal) decimal.Decimal {
+	if !step.IsPositive() {
+		return value
+	}
+	return value.Div(step).Floor().Mul(step)
+}
+
+// stepDecimals returns the decimal places of a step such as 0.00100000 (3)
+func stepDecimals(step decimal.Decimal) int32 {
+	if !step.IsPositive() {
+		return 8
+	}
+
+	places := int32(0)
+	for !step.Equal(step.Truncate(places)) {
+		places++
+	}
+	return places
+}
+
+// exchangeInfoSource provides exchange info; every Exchange satisfies it
+type exchangeInfoSource interface {
+	GetExchangeInfo(ctx context.Context) (*futures.ExchangeInfo, error)
+}
+
+// SymbolRegistry caches symbol filters from exchange info and reloads them
+// once they are older than RefreshInterval
+type SymbolRegistry struct {
+	source          exchangeInfoSource
+	RefreshInterval time.Duration
+
+	mu       sync.RWMutex
+	symbols  map[string]*SymbolFilters
+	loadedAt time.Time
+}
+
+// NewSymbolRegistry creates a registry that loads filters from source on first use
+func NewSymbolRegistry(source exchangeInfoSource) *SymbolRegistry {
+	return &SymbolRegistry{
+		source:          source,
+		RefreshInterval: time.Hour,
+		symbols:         make(map[string]*SymbolFilters),
+	}
+}
+
+// Refresh downloads exchange info and replaces the cached filters
+func (r *SymbolRegistry) Refresh(ctx context.Context) error {
+	info, err := r.source.GetExchangeInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to refresh symbol filters: %w", err)
+	}
+
+	symbols := make(map[string]*SymbolFilters, len(info.Symbols))
+	for _, symbol := range info.Symbols {
+		symbols[symbol.Symbol] = parseSymbolFilters(symbol)
+	}
+
+	r.mu.Lock()
+	r.symbols = symbols
+	r.loadedAt = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// Start loads the filters and refreshes them every RefreshInterval until ctx is cancelled
+func (r *SymbolRegistry) Start(ctx context.Context) error {
+	if err := r.Refresh(ctx); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(r.RefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Refresh(ctx); err != nil {
+					log.Printf("⚠️  %v", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Get returns the filters for symbol, loading the cache on first use and
+// reloading it once stale. A failed reload keeps serving the previous filters.
+func (r *SymbolRegistry) Get(ctx context.Context, symbol string) (*SymbolFilters, error) {
+	r.mu.RLock()
+	loaded := !r.loadedAt.IsZero()
+	stale := !loaded || time.Since(r.loadedAt) > r.RefreshInterval
+	r.mu.RUnlock()
+
+	if stale {
+		if err := r.Refresh(ctx); err != nil {
+			if !loaded {
+				return nil, err
+			}
+			log.Printf("⚠️  %v (using cached filters)", err)
+		}
+	}
+
+	r.mu.RLock()
+	filters, ok := r.symbols[symbol]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	return filters, nil
+}
+
+// FormatQuantity rounds quantity down to the symbol's step size for the order type
+func (r *SymbolRegistry) FormatQuantity(ctx context.Context, symbol string, quantity float64, orderType string) (string, error) {
+	filters, err := r.Get(ctx, symbol)
+	if err != nil {
+		return "", err
+	}
+
+	_, minQty, _ := filters.LotSize(orderType)
+	if rounded := filters.RoundQuantity(quantity, orderType); rounded.LessThan(minQty) {
+		return "", fmt.Errorf("quantity %s is below minimum %s for %s", rounded, minQty, symbol)
+	}
+	return filters.FormatQuantity(quantity, orderType), nil
+}
+
+// FormatPrice rounds price to the symbol's tick size
+func (r *SymbolRegistry) FormatPrice(ctx context.Context, symbol string, price float64) (string, error) {
+	filters, err := r.Get(ctx, symbol)
+	if err != nil {
+		return "", err
+	}
+	return filters.FormatPrice(price), nil
+}
+
+// parseSymbolFilters extracts the filters used for order rounding and validation
+func parseSymbolFilters(symbol futures.Symbol) *SymbolFilters {
+	filters := &SymbolFilters{
+		Symbol:            symbol.Symbol,
+		Status:            symbol.Status,
+		PricePrecision:    symbol.PricePrecision,
+		QuantityPrecision: symbol.QuantityPrecision,
+	}
+
+	for _, filter := range symbol.Filters {
+		value := func(key string) decimal.Decimal {
+			str, _ := filter[key].(string)
+			d, err := decimal.NewFromString(str)
+			if err != nil {
+				return decimal.Zero
+			}
+			return d
+		}
+
+		switch filter["filterType"] {
+		case "PRICE_FILTER":
+			filters.TickSize = value("tickSize")
+			filters.MinPrice = value("minPrice")
+			filters.MaxPrice = value("maxPrice")
+		case "LOT_SIZE":
+			filters.StepSize = value("stepSize")
+			filters.MinQty = value("minQty")
+			filters.MaxQty = value("maxQty")
+		case "MARKET_LOT_SIZE":
+			filters.MarketStepSize = value("stepSize")
+			filters.MarketMinQty = value("minQty")
+			filters.MarketMaxQty = value("maxQty")
+		case "MIN_NOTIONAL":
+			filters.MinNotional = value("notional")
+		case "PERCENT_PRICE":
+			filters.MultiplierUp = value("multiplierUp")
+			filters.MultiplierDown = value("multiplierDown")
+		}
+	}
+
+	return filters
+}
@@ -0,0 +1,111 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// fakeExchangeInfo serves fixed exchange info and counts downloads
+type fakeExchangeInfo struct {
+	info  *futures.ExchangeInfo
+	calls int
+	err   error
+}
+
+func (f *fakeExchangeInfo) GetExchangeInfo(ctx context.Context) (*futures.ExchangeInfo, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.info, nil
+}
+
+// newFakeExchangeInfo returns BTCUSDT with a coarser market step than its limit step
+func newFakeExchangeInfo() *fakeExchangeInfo {
+	return &fakeExchangeInfo{info: &futures.ExchangeInfo{Symbols: []futures.Symbol{{
+		Symbol: "BTCUSDT",
+		Status: "TRADING",
+		Filters: []map[string]interface{}{
+			{"filterType": "PRICE_FILTER", "minPrice": "556.80", "maxPrice": "4529764", "tickSize": "0.10"},
+			{"filterType": "LOT_SIZE", "minQty": "0.001", "maxQty": "1000", "stepSize": "0.001"},
+			{"filterType": "MARKET_LOT_SIZE", "minQty": "0.01", "maxQty": "120", "stepSize": "0.01"},
+			{"filterType": "MIN_NOTIONAL", "notional": "100"},
+			{"filterType": "PERCENT_PRICE", "multiplierUp": "1.0500", "multiplierDown": "0.9500"},
+		},
+	}}}}
+}
+
+// TestSymbolRegistryRounding tests step and tick rounding per order type
+func TestSymbolRegistryRounding(t *testing.T) {
+	registry := NewSymbolRegistry(newFakeExchangeInfo())
+	ctx := context.Background()
+
+	tests := []struct {
+		quantity  float64
+		orderType string
+		expected  string
+	}{
+		{0.0129, "LIMIT", "0.012"},
+		{0.0129, "STOP_MARKET", "0.01"},
+		{0.3, "MARKET", "0.30"},
+		{1.0 / 3, "LIMIT", "0.333"},
+	}
+	for _, tt := range tests {
+		got, err := registry.FormatQuantity(ctx, "BTCUSDT", tt.quantity, tt.orderType)
+		if err != nil {
+			t.Fatalf("FormatQuantity(%v, %s) failed: %v", tt.quantity, tt.orderType, err)
+		}
+		if got != tt.expected {
+			t.Errorf("FormatQuantity(%v, %s) = %s, expected %s", tt.quantity, tt.orderType, got, tt.expected)
+		}
+	}
+
+	if _, err := registry.FormatQuantity(ctx, "BTCUSDT", 0.009, "MARKET"); err == nil {
+		t.Errorf("Expected error below market minimum quantity")
+	}
+
+	price, err := registry.FormatPrice(ctx, "BTCUSDT", 64123.456)
+	if err != nil {
+		t.Fatalf("FormatPrice failed: %v", err)
+	}
+	if price != "64123.5" {
+		t.Errorf("FormatPrice = %s, expected 64123.5", price)
+	}
+
+	filters, _ := registry.Get(ctx, "BTCUSDT")
+	if filters.MinNotional.String() != "100" || filters.MultiplierUp.String() != "1.05" {
+		t.Errorf("Unexpected filters: min notional %s, multiplier up %s", filters.MinNotional, filters.MultiplierUp)
+	}
+}
+
+// TestSymbolRegistryCaches tests that exchange info is only downloaded when stale
+func TestSymbolRegistryCaches(t *testing.T) {
+	source := newFakeExchangeInfo()
+	registry := NewSymbolRegistry(source)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := registry.Get(ctx, "BTCUSDT"); err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+	}
+	if _, err := registry.Get(ctx, "DOGEUSDT"); err == nil {
+		t.Errorf("Expected error for unknown symbol")
+	}
+	if source.calls != 1 {
+		t.Errorf("Expected 1 exchange info download, got %d", source.calls)
+	}
+
+	// A failed reload keeps serving the cached filters
+	registry.RefreshInterval = 0
+	source.err = fmt.Errorf("network down")
+	if _, err := registry.Get(ctx, "BTCUSDT"); err != nil {
+		t.Errorf("Expected cached filters after failed refresh, got %v", err)
+	}
+	if source.calls != 2 {
+		t.Errorf("Expected a reload once stale, got %d downloads", source.calls)
+	}
+}
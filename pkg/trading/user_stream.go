@@ -0,0 +1,368 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// OrderUpdate is an order status change pushed by the user data stream
+type OrderUpdate struct {
+	Order           Order
+	ClientOrderID   string
+	ExecutionType   string  // NEW, TRADE, CANCELED, EXPIRED, ...
+	LastFilledQty   float64 // Quantity filled by this event
+	LastFilledPrice float64
+	FilledQty       float64 // Accumulated filled quantity
+	AvgPrice        float64
+	RealizedPnL     float64
+	Commission      float64
+	TradeTime       int64
+}
+
+// UserDataStream keeps a local copy of balances, positions and open orders
+// current from the listenKey user data stream, and notifies callbacks on changes
+type UserDataStream struct {
+	client *TradingClient
+
+	KeepaliveInterval time.Duration // How often the listenKey is extended (expires after 60 minutes)
+	ReconnectDelay    time.Duration
+
+	mu        sync.RWMutex
+	balances  map[string]float64  // Wallet balance by asset
+	positions map[string]Position // Open positions by positionKey
+	orders    map[int64]Order     // Open orders by ID
+
+	callbackMu       sync.Mutex
+	orderCallbacks   []func(OrderUpdate)
+	positionCallback []func(Position)
+
+	serve func(string, futures.WsUserDataHandler, futures.ErrHandler) (chan struct{}, chan struct{}, error)
+}
+
+// NewUserDataStream creates a user data stream for the client's account
+func NewUserDataStream(client *TradingClient) *UserDataStream {
+	return &UserDataStream{
+		client:            client,
+		KeepaliveInterval: 30 * time.Minute,
+		ReconnectDelay:    5 * time.Second,
+		balances:          make(map[string]float64),
+		positions:         make(map[string]Position),
+		orders:            make(map[int64]Order),
+		serve:             futures.WsUserDataServe,
+	}
+}
+
+// OnOrderUpdate registers a callback for every order update
+func (us *UserDataStream) OnOrderUpdate(callback func(OrderUpdate)) {
+	us.callbackMu.Lock()
+	defer us.callbackMu.Unlock()
+	us.orderCallbacks = append(us.orderCallbacks, callback)
+}
+
+// OnPositionUpdate registers a callback for every position change; a closed
+// position is reported with PositionAmt 0
+func (us *UserDataStream) OnPositionUpdate(callback func(Position)) {
+	us.callbackMu.Lock()
+	defer us.callbackMu.Unlock()
+	us.positionCallback = append(us.positionCallback, callback)
+}
+
+// Start opens the stream, loads a REST snapshot and keeps the stream alive until ctx is cancelled
+func (us *UserDataStream) Start(ctx context.Context) error {
+	listenKey, doneC, stopC, err := us.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Snapshot after connecting so no event falls between the two
+	if err := us.snapshot(ctx); err != nil {
+		close(stopC)
+		return err
+	}
+
+	go us.run(ctx, listenKey, doneC, stopC)
+	log.Printf("📡 User data stream started")
+	return nil
+}
+
+// Positions returns the open positions
+func (us *UserDataStream) Positions() []Position {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+
+	var positions []Position
+	for _, pos := range us.positions {
+		positions = append(positions, pos)
+	}
+	return positions
+}
+
+// Position returns the open position for symbol, if any. In hedge mode a
+// symbol can have a LONG and a SHORT position; see PositionBySide.
+func (us *UserDataStream) Position(symbol string) (Position, bool) {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+
+	for _, pos := range us.positions {
+		if pos.Symbol == symbol {
+			return pos, true
+		}
+	}
+	return Position{}, false
+}
+
+// PositionBySide returns the open position for symbol on side (LONG or SHORT), if any
+func (us *UserDataStream) PositionBySide(symbol, side string) (Position, bool) {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+
+	for _, pos := range us.positions {
+		if pos.Symbol == symbol && pos.Side == side {
+			return pos, true
+		}
+	}
+	return Position{}, false
+}
+
+// OpenOrders returns the open orders for symbol, or all symbols when symbol is empty
+func (us *UserDataStream) OpenOrders(symbol string) []Order {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+
+	var orders []Order
+	for _, order := range us.orders {
+		if symbol == "" || order.Symbol == symbol {
+			orders = append(orders, order)
+		}
+	}
+	return orders
+}
+
+// WalletBalance returns the wallet balance of asset
+func (us *UserDataStream) WalletBalance(asset string) float64 {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+
+	return us.balances[asset]
+}
+
+// snapshot replaces the local state with the account as reported by REST
+func (us *UserDataStream) snapshot(ctx context.Context) error {
+	balance, err := us.client.GetUSDTBalance(ctx)
+	if err != nil {
+		return err
+	}
+	positions, err := us.client.GetPositions(ctx)
+	if err != nil {
+		return err
+	}
+	orders, err := us.client.GetOpenOrders(ctx)
+	if err != nil {
+		return err
+	}
+
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	us.balances = map[string]float64{balance.Asset: balance.WalletBalance}
+	us.positions = make(map[string]Position)
+	for _, pos := range positions {
+		us.positions[positionKey(pos.Symbol, pos.PositionSide)] = pos
+	}
+	us.orders = make(map[int64]Order)
+	for _, order := range orders {
+		us.orders[order.OrderID] = order
+	}
+	return nil
+}
+
+// connect obtains a listenKey and opens the websocket
+func (us *UserDataStream) connect(ctx context.Context) (string, chan struct{}, chan struct{}, error) {
+	listenKey, err := us.client.BinanceClient.NewStartUserStreamService().Do(ctx)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to start user stream: %w", err)
+	}
+
+	doneC, stopC, err := us.serve(listenKey, us.handleEvent, func(err error) {
+		log.Printf("⚠️  User data stream error: %v", err)
+	})
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to connect user stream: %w", err)
+	}
+	return listenKey, doneC, stopC, nil
+}
+
+// run keeps the listenKey alive and reconnects with a fresh snapshot when the stream drops
+func (us *UserDataStream) run(ctx context.Context, listenKey string, doneC, stopC chan struct{}) {
+	keepalive := time.NewTicker(us.KeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			close(stopC)
+			us.client.BinanceClient.NewCloseUserStreamService().ListenKey(listenKey).Do(context.Background())
+			return
+
+		case <-keepalive.C:
+			if err := us.client.BinanceClient.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(ctx); err != nil {
+				log.Printf("⚠️  Failed to keep user stream alive: %v", err)
+			}
+
+		case <-doneC:
+			log.Printf("🔌 User data stream disconnected, reconnecting...")
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(us.ReconnectDelay):
+				}
+
+				var err error
+				if listenKey, doneC, stopC, err = us.connect(ctx); err != nil {
+					log.Printf("⚠️  %v", err)
+					continue
+				}
+
+				// Events may have been missed while disconnected
+				if err := us.snapshot(ctx); err != nil {
+					log.Printf("⚠️  Failed to refresh account snapshot: %v", err)
+				}
+				log.Printf("🔌 User data stream reconnected")
+				break
+			}
+		}
+	}
+}
+
+// handleEvent applies an event to the local state and notifies callbacks
+func (us *UserDataStream) handleEvent(event *futures.WsUserDataEvent) {
+	switch event.Event {
+	case futures.UserDataEventTypeAccountUpdate:
+		us.applyAccountUpdate(event.AccountUpdate)
+	case futures.UserDataEventTypeOrderTradeUpdate:
+		us.applyOrderUpdate(event.OrderTradeUpdate)
+	case futures.UserDataEventTypeListenKeyExpired:
+		log.Printf("⚠️  User data stream listenKey expired")
+	}
+}
+
+// applyAccountUpdate updates balances and positions from ACCOUNT_UPDATE
+func (us *UserDataStream) applyAccountUpdate(update futures.WsAccountUpdate) {
+	var changed []Position
+
+	us.mu.Lock()
+	for _, balance := range update.Balances {
+		us.balances[balance.Asset] = parseFloat(balance.Balance)
+	}
+	for _, wsPos := range update.Positions {
+		amount := parseFloat(wsPos.Amount)
+		key := positionKey(wsPos.Symbol, string(wsPos.Side))
+		pos := Position{
+			Symbol:           wsPos.Symbol,
+			PositionAmt:      amount,
+			EntryPrice:       parseFloat(wsPos.EntryPrice),
+			MarkPrice:        parseFloat(wsPos.MarkPrice),
+			UnrealizedProfit: parseFloat(wsPos.UnrealizedPnL),
+			Leverage:         us.positions[key].Leverage,
+			Side:             "LONG",
+			PositionSide:     string(wsPos.Side),
+		}
+		if amount < 0 {
+			pos.Side = "SHORT"
+			pos.PositionAmt = -amount
+		}
+		// A closed hedge mode position keeps its side
+		if pos.PositionSide == PositionSideLong || pos.PositionSide == PositionSideShort {
+			pos.Side = pos.PositionSide
+		}
+
+		if pos.PositionAmt == 0 {
+			delete(us.positions, key)
+		} else {
+			us.positions[key] = pos
+		}
+		changed = append(changed, pos)
+	}
+	us.mu.Unlock()
+
+	us.callbackMu.Lock()
+	callbacks := us.positionCallback
+	us.callbackMu.Unlock()
+	for _, pos := range changed {
+		for _, callback := range callbacks {
+			callback(pos)
+		}
+	}
+}
+
+// applyOrderUpdate tracks open orders from ORDER_TRADE_UPDATE
+func (us *UserDataStream) applyOrderUpdate(update futures.WsOrderTradeUpdate) {
+	// Triggered stop orders are reported as MARKET; keep the original type
+	orderType := string(update.OriginalType)
+	if orderType == "" {
+		orderType = string(update.Type)
+	}
+
+	order := Order{
+		OrderID:         update.ID,
+		Symbol:          update.Symbol,
+		Status:          string(update.Status),
+		Side:            string(update.Side),
+		Type:            orderType,
+		OrigQty:         parseFloat(update.OriginalQty),
+		Price:           parseFloat(update.OriginalPrice),
+		StopPrice:       parseFloat(update.StopPrice),
+		ReduceOnly:      update.IsReduceOnly,
+		ClosePosition:   update.IsClosingPosition,
+		ClientOrderID:   update.ClientOrderID,
+		ExecutedQty:     parseFloat(update.AccumulatedFilledQty),
+		AvgPrice:        parseFloat(update.AveragePrice),
+		PositionSide:    string(update.PositionSide),
+		CallbackRate:    parseFloat(update.CallbackRate),
+		ActivationPrice: parseFloat(update.ActivationPrice),
+	}
+
+	us.mu.Lock()
+	switch order.Status {
+	case "NEW", "PARTIALLY_FILLED":
+		us.orders[order.OrderID] = order
+	default:
+		delete(us.orders, order.OrderID)
+	}
+	us.mu.Unlock()
+
+	orderUpdate := OrderUpdate{
+		Order:           order,
+		ClientOrderID:   update.ClientOrderID,
+		ExecutionType:   string(update.ExecutionType),
+		LastFilledQty:   parseFloat(update.LastFilledQty),
+		LastFilledPrice: parseFloat(update.LastFilledPrice),
+		FilledQty:       parseFloat(update.AccumulatedFilledQty),
+		AvgPrice:        parseFloat(update.AveragePrice),
+		RealizedPnL:     parseFloat(update.RealizedPnL),
+		Commission:      parseFloat(update.Commission),
+		TradeTime:       update.TradeTime,
+	}
+
+	us.callbackMu.Lock()
+	callbacks := us.orderCallbacks
+	us.callbackMu.Unlock()
+	for _, callback := range callbacks {
+		callback(orderUpdate)
+	}
+}
+
+// positionKey keys a position by symbol and position side, so the LONG and
+// SHORT positions of a symbol in hedge mode are kept apart
+func positionKey(symbol, positionSide string) string {
+	if positionSide == "" {
+		positionSide = PositionSideBoth
+	}
+	return symbol + "/" + positionSide
+}
@@ -0,0 +1,71 @@
+package trading
+
+import (
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// TestUserDataStreamState tests that order and account events keep the local state current
+func TestUserDataStreamState(t *testing.T) {
+	us := NewUserDataStream(nil)
+
+	var updates []OrderUpdate
+	var positions []Position
+	us.OnOrderUpdate(func(update OrderUpdate) { updates = append(updates, update) })
+	us.OnPositionUpdate(func(pos Position) { positions = append(positions, pos) })
+
+	stopLoss := futures.WsOrderTradeUpdate{
+		Symbol: "BTCUSDT", ID: 1, Side: "SELL", Type: "STOP_MARKET", OriginalType: "STOP_MARKET",
+		Status: "NEW", ExecutionType: "NEW", StopPrice: "95", IsClosingPosition: true,
+	}
+	us.handleEvent(&futures.WsUserDataEvent{
+		Event:                      futures.UserDataEventTypeOrderTradeUpdate,
+		WsUserDataOrderTradeUpdate: futures.WsUserDataOrderTradeUpdate{OrderTradeUpdate: stopLoss},
+	})
+
+	us.handleEvent(&futures.WsUserDataEvent{
+		Event: futures.UserDataEventTypeAccountUpdate,
+		WsUserDataAccountUpdate: futures.WsUserDataAccountUpdate{AccountUpdate: futures.WsAccountUpdate{
+			Balances:  []futures.WsBalance{{Asset: "USDT", Balance: "990.5"}},
+			Positions: []futures.WsPosition{{Symbol: "BTCUSDT", Amount: "-0.01", EntryPrice: "100"}},
+		}},
+	})
+
+	if orders := us.OpenOrders("BTCUSDT"); len(orders) != 1 || orders[0].StopPrice != 95 || !orders[0].ClosePosition {
+		t.Fatalf("Expected open stop order, got %+v", orders)
+	}
+	if pos, ok := us.Position("BTCUSDT"); !ok || pos.Side != "SHORT" || pos.PositionAmt != 0.01 {
+		t.Fatalf("Expected 0.01 short, got %+v", pos)
+	}
+	if us.WalletBalance("USDT") != 990.5 {
+		t.Errorf("Expected wallet 990.5, got %.2f", us.WalletBalance("USDT"))
+	}
+
+	// The stop triggers: reported as MARKET with the original type kept
+	stopLoss.Type = "MARKET"
+	stopLoss.Status = "FILLED"
+	stopLoss.ExecutionType = "TRADE"
+	stopLoss.LastFilledQty = "0.01"
+	stopLoss.LastFilledPrice = "95.1"
+	us.handleEvent(&futures.WsUserDataEvent{
+		Event:                      futures.UserDataEventTypeOrderTradeUpdate,
+		WsUserDataOrderTradeUpdate: futures.WsUserDataOrderTradeUpdate{OrderTradeUpdate: stopLoss},
+	})
+	us.handleEvent(&futures.WsUserDataEvent{
+		Event: futures.UserDataEventTypeAccountUpdate,
+		WsUserDataAccountUpdate: futures.WsUserDataAccountUpdate{AccountUpdate: futures.WsAccountUpdate{
+			Positions: []futures.WsPosition{{Symbol: "BTCUSDT", Amount: "0"}},
+		}},
+	})
+
+	if len(us.OpenOrders("")) != 0 || len(us.Positions()) != 0 {
+		t.Fatalf("Expected no open orders or positions, got %+v %+v", us.OpenOrders(""), us.Positions())
+	}
+	if len(updates) != 2 || updates[1].Order.Type != "STOP_MARKET" || updates[1].LastFilledPrice != 95.1 {
+		t.Errorf("Unexpected order updates %+v", updates)
+	}
+	if len(positions) != 2 || positions[1].PositionAmt != 0 {
+		t.Errorf("Expected a closed position callback, got %+v", positions)
+	}
+}
@@ -0,0 +1,320 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Order rejection reasons returned by OrderValidator. Errors wrap one of
+// these, so callers can test them with errors.Is.
+var (
+	ErrInvalidOrder       = errors.New("invalid order")
+	ErrSymbolNotTrading   = errors.New("symbol is not trading")
+	ErrPrecision          = errors.New("precision does not match step or tick size")
+	ErrBelowMinQty        = errors.New("quantity below minimum")
+	ErrAboveMaxQty        = errors.New("quantity above maximum")
+	ErrPriceOutOfRange    = errors.New("price out of range")
+	ErrBelowMinNotional   = errors.New("notional below minimum")
+	ErrAboveMaxNotional   = errors.New("notional above leverage bracket maximum")
+	ErrInsufficientMargin = errors.New("insufficient margin")
+	ErrReduceOnly         = errors.New("reduce-only order does not reduce a position")
+)
+
+// OrderValidator checks orders against symbol filters and account state
+// before they are sent, so invalid orders are rejected locally with a typed reason
+type OrderValidator struct {
+	exchange Exchange
+}
+
+// NewOrderValidator creates a validator that reads filters and account state from ex
+func NewOrderValidator(ex Exchange) *OrderValidator {
+	return &OrderValidator{exchange: ex}
+}
+
+// Validate returns nil if order passes the symbol filters, reduce-only
+// consistency, the leverage bracket limit and the available margin. Symbol
+// filters, the position mode, leverage and brackets come from the exchange's
+// caches, so only the positions, and when needed the mark price and balance,
+// are requested per order.
+func (v *OrderValidator) Validate(ctx context.Context, order *OrderRequest) error {
+	if order.Side != "BUY" && order.Side != "SELL" {
+		return fmt.Errorf("%w: side %q", ErrInvalidOrder, order.Side)
+	}
+
+	if order.ClosePosition && order.Quantity != "" {
+		return fmt.Errorf("%w: quantity cannot be sent with closePosition", ErrInvalidOrder)
+	}
+
+	filters, err := v.exchange.Symbols().Get(ctx, order.Symbol)
+	if err != nil {
+		return err
+	}
+	if filters.Status != "" && filters.Status != "TRADING" {
+		return fmt.Errorf("%w: %s is %s", ErrSymbolNotTrading, order.Symbol, filters.Status)
+	}
+
+	quantity, err := parseOrderDecimal(order.Quantity, "quantity", order.ClosePosition)
+	if err != nil {
+		return err
+	}
+	if !order.ClosePosition {
+		if err := checkQuantity(filters, order.Type, quantity); err != nil {
+			return fmt.Errorf("%w for %s", err, order.Symbol)
+		}
+	}
+
+	price, err := parseOrderDecimal(order.Price, "price", order.Type != "LIMIT")
+	if err != nil {
+		return err
+	}
+	stopPrice, err := parseOrderDecimal(order.StopPrice, "stop price", order.Type == "MARKET" || order.Type == "LIMIT" || order.Type == "TRAILING_STOP_MARKET")
+	if err != nil {
+		return err
+	}
+	activationPrice, err := parseOrderDecimal(order.ActivationPrice, "activation price", true)
+	if err != nil {
+		return err
+	}
+	if order.Type == "TRAILING_STOP_MARKET" {
+		if err := checkCallbackRate(order.CallbackRate); err != nil {
+			return fmt.Errorf("%w for %s", err, order.Symbol)
+		}
+	}
+	for _, p := range []decimal.Decimal{price, stopPrice, activationPrice} {
+		if err := checkPrice(filters, p); err != nil {
+			return fmt.Errorf("%w for %s", err, order.Symbol)
+		}
+	}
+
+	positions, err := v.exchange.GetPositions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get positions for validation: %w", err)
+	}
+	mode, err := v.exchange.GetPositionMode(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get position mode for validation: %w", err)
+	}
+
+	// In hedge mode the order is matched to the position side it opens or closes
+	reduces := reducesPosition(order)
+	side := ""
+	if mode == PositionModeHedge {
+		side = orderDirection(order.Side)
+		if reduces {
+			side = oppositeSide(side)
+		}
+	}
+	position := findPosition(positions, order.Symbol, side)
+
+	// Limit prices are checked and market orders valued at the mark price, as Binance does
+	markPrice := decimal.Zero
+	if order.Type == "LIMIT" || (!reduces && !price.IsPositive() && !stopPrice.IsPositive()) {
+		if markPrice, err = v.markPrice(ctx, order.Symbol, positions); err != nil {
+			return err
+		}
+	}
+
+	// Limit prices must stay within PERCENT_PRICE of the mark price
+	if order.Type == "LIMIT" && markPrice.IsPositive() {
+		if order.Side == "BUY" && filters.MultiplierUp.IsPositive() && price.GreaterThan(markPrice.Mul(filters.MultiplierUp)) {
+			return fmt.Errorf("%w: buy price %s is above %s × %s for %s", ErrPriceOutOfRange, price, markPrice, filters.MultiplierUp, order.Symbol)
+		}
+		if order.Side == "SELL" && filters.MultiplierDown.IsPositive() && price.LessThan(markPrice.Mul(filters.MultiplierDown)) {
+			return fmt.Errorf("%w: sell price %s is below %s × %s for %s", ErrPriceOutOfRange, price, markPrice, filters.MultiplierDown, order.Symbol)
+		}
+	}
+
+	if reduces {
+		return checkReduceOnly(order, quantity, position)
+	}
+
+	// Value the order at the price it is expected to fill at
+	fillPrice := markPrice
+	if price.IsPositive() {
+		fillPrice = price
+	} else if stopPrice.IsPositive() {
+		fillPrice = stopPrice
+	}
+	notional := quantity.Mul(fillPrice)
+
+	if notional.LessThan(filters.MinNotional) {
+		return fmt.Errorf("%w: %s < %s for %s", ErrBelowMinNotional, notional.StringFixed(2), filters.MinNotional, order.Symbol)
+	}
+
+	return v.checkExposure(ctx, order, quantity, fillPrice, position)
+}
+
+// markPrice returns the mark price of symbol from its open positions, which
+// carry it, or from the exchange
+func (v *OrderValidator) markPrice(ctx context.Context, symbol string, positions []Position) (decimal.Decimal, error) {
+	for _, position := range positions {
+		if position.Symbol == symbol && position.PositionAmt != 0 && position.MarkPrice > 0 {
+			return decimal.NewFromFloat(position.MarkPrice), nil
+		}
+	}
+
+	price, err := v.exchange.GetMarkPrice(ctx, symbol)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get mark price for validation: %w", err)
+	}
+	return decimal.NewFromFloat(price), nil
+}
+
+// checkExposure checks the position after the order against the leverage
+// bracket and the margin it needs against the available balance
+func (v *OrderValidator) checkExposure(ctx context.Context, order *OrderRequest, quantity, fillPrice decimal.Decimal, position *Position) error {
+	// In one-way mode an opposite order first offsets the open position
+	opening := quantity
+	existing := decimal.Zero
+	if position != nil {
+		amount := decimal.NewFromFloat(position.PositionAmt)
+		if orderDirection(order.Side) == position.Side {
+			existing = amount
+		} else {
+			opening = decimal.Max(quantity.Sub(amount), decimal.Zero)
+		}
+	}
+	if !opening.IsPositive() {
+		return nil
+	}
+
+	leverage, err := v.exchange.GetLeverage(order.Symbol)
+	if err != nil {
+		return fmt.Errorf("failed to get leverage for validation: %w", err)
+	}
+	if leverage < 1 {
+		leverage = 1
+	}
+
+	brackets, err := v.exchange.GetLeverageBrackets(ctx, order.Symbol)
+	if err != nil {
+		return fmt.Errorf("failed to get leverage brackets for validation: %w", err)
+	}
+	if len(brackets) > 0 {
+		maxNotional, ok := MaxNotional(brackets, leverage)
+		if !ok {
+			return fmt.Errorf("%w: leverage %dx exceeds every bracket for %s", ErrAboveMaxNotional, leverage, order.Symbol)
+		}
+		total := existing.Add(opening).Mul(fillPrice)
+		if total.GreaterThan(decimal.NewFromFloat(maxNotional)) {
+			return fmt.Errorf("%w: position notional %s > %.0f at %dx for %s", ErrAboveMaxNotional, total.StringFixed(2), maxNotional, leverage, order.Symbol)
+		}
+	}
+
+	balance, err := v.exchange.GetUSDTBalance(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get balance for validation: %w", err)
+	}
+	required := opening.Mul(fillPrice).Div(decimal.NewFromInt(int64(leverage)))
+	if available := decimal.NewFromFloat(balance.AvailableBalance); required.GreaterThan(available) {
+		return fmt.Errorf("%w: %s USDT needed at %dx, %s available for %s", ErrInsufficientMargin, required.StringFixed(2), leverage, available.StringFixed(2), order.Symbol)
+	}
+	return nil
+}
+
+// checkQuantity checks quantity against the lot size of the order type
+func checkQuantity(filters *SymbolFilters, orderType string, quantity decimal.Decimal) error {
+	step, minQty, maxQty := filters.LotSize(orderType)
+	if step.IsPositive() && !quantity.Mod(step).IsZero() {
+		return fmt.Errorf("%w: quantity %s is not a multiple of %s", ErrPrecision, quantity, step)
+	}
+	if quantity.LessThan(minQty) || !quantity.IsPositive() {
+		return fmt.Errorf("%w: %s < %s", ErrBelowMinQty, quantity, minQty)
+	}
+	if maxQty.IsPositive() && quantity.GreaterThan(maxQty) {
+		return fmt.Errorf("%w: %s > %s", ErrAboveMaxQty, quantity, maxQty)
+	}
+	return nil
+}
+
+// checkCallbackRate checks a trailing stop callback rate, which Binance
+// accepts from 0.1 to 10 percent in steps of 0.1
+func checkCallbackRate(value string) error {
+	rate, err := parseOrderDecimal(value, "callback rate", false)
+	if err != nil {
+		return err
+	}
+	if rate.LessThan(decimal.RequireFromString("0.1")) || rate.GreaterThan(decimal.NewFromInt(10)) {
+		return fmt.Errorf("%w: callback rate %s%% outside 0.1-10%%", ErrInvalidOrder, rate)
+	}
+	if !rate.Mod(decimal.RequireFromString("0.1")).IsZero() {
+		return fmt.Errorf("%w: callback rate %s is not a multiple of 0.1", ErrPrecision, rate)
+	}
+	return nil
+}
+
+// checkPrice checks a price against PRICE_FILTER; zero means no price
+func checkPrice(filters *SymbolFilters, price decimal.Decimal) error {
+	if price.IsZero() {
+		return nil
+	}
+	if filters.TickSize.IsPositive() && !price.Mod(filters.TickSize).IsZero() {
+		return fmt.Errorf("%w: price %s is not a multiple of %s", ErrPrecision, price, filters.TickSize)
+	}
+	if price.LessThan(filters.MinPrice) || (filters.MaxPrice.IsPositive() && price.GreaterThan(filters.MaxPrice)) {
+		return fmt.Errorf("%w: %s outside [%s, %s]", ErrPriceOutOfRange, price, filters.MinPrice, filters.MaxPrice)
+	}
+	return nil
+}
+
+// checkReduceOnly checks that a reduce-only, close-position or hedge mode exit order closes
+// part of an open position without reversing it
+func checkReduceOnly(order *OrderRequest, quantity decimal.Decimal, position *Position) error {
+	if position == nil {
+		return fmt.Errorf("%w: no open position for %s", ErrReduceOnly, order.Symbol)
+	}
+	if orderDirection(order.Side) == position.Side {
+		return fmt.Errorf("%w: %s order would increase the %s position for %s", ErrReduceOnly, order.Side, position.Side, order.Symbol)
+	}
+	if !order.ClosePosition && quantity.GreaterThan(decimal.NewFromFloat(position.PositionAmt)) {
+		return fmt.Errorf("%w: quantity %s exceeds position %.8f for %s", ErrReduceOnly, quantity, position.PositionAmt, order.Symbol)
+	}
+	return nil
+}
+
+// parseOrderDecimal parses an order field, which may only be empty when optional
+func parseOrderDecimal(value, name string, optional bool) (decimal.Decimal, error) {
+	if value == "" {
+		if optional {
+			return decimal.Zero, nil
+		}
+		return decimal.Zero, fmt.Errorf("%w: missing %s", ErrInvalidOrder, name)
+	}
+
+	d, err := decimal.NewFromString(value)
+	if err != nil || d.IsNegative() {
+		return decimal.Zero, fmt.Errorf("%w: %s %q", ErrInvalidOrder, name, value)
+	}
+	return d, nil
+}
+
+// orderDirection returns the position side an order side opens
+func orderDirection(side string) string {
+	if side == "BUY" {
+		return "LONG"
+	}
+	return "SHORT"
+}
+
+// validatingExchange validates every order before passing it to the exchange
+type validatingExchange struct {
+	Exchange
+	validator *OrderValidator
+}
+
+// WithOrderValidation returns ex with CreateOrder rejecting invalid orders
+// before they are sent
+func WithOrderValidation(ex Exchange) Exchange {
+	return &validatingExchange{Exchange: ex, validator: NewOrderValidator(ex)}
+}
+
+// CreateOrder validates order and sends it to the wrapped exchange
+func (ve *validatingExchange) CreateOrder(order *OrderRequest) (*OrderResponse, error) {
+	if err := ve.validator.Validate(context.Background(), order); err != nil {
+		return nil, fmt.Errorf("order rejected: %w", err)
+	}
+	return ve.Exchange.CreateOrder(order)
+}
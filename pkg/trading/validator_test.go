@@ -0,0 +1,136 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// bracketExchange adds fixed leverage brackets to a paper exchange
+type bracketExchange struct {
+	*PaperExchange
+	brackets []LeverageBracket
+}
+
+func (be *bracketExchange) GetLeverageBrackets(ctx context.Context, symbol string) ([]LeverageBracket, error) {
+	return be.brackets, nil
+}
+
+// newTestValidator returns a validator over a 1000 USDT paper account with
+// BTCUSDT at 100, step 0.001, tick 0.1 and a 5 USDT minimum notional
+func newTestValidator() (*OrderValidator, *PaperExchange) {
+	cfg := DefaultPaperConfig()
+	cfg.TakerFee = 0
+	cfg.Slippage = 0
+	cfg.DefaultLeverage = 10
+	cfg.Pairs = []TradingPair{{
+		Symbol:      "BTCUSDT",
+		QuoteAsset:  "USDT",
+		MinPrice:    0.1,
+		MaxPrice:    1000000,
+		TickSize:    0.1,
+		MinQty:      0.001,
+		MaxQty:      1000,
+		StepSize:    0.001,
+		MinNotional: 5,
+	}}
+
+	pe := NewPaperExchange(nil, cfg)
+	pe.OnCandle("BTCUSDT", Candle{OpenTime: 0, Open: 100, High: 100, Low: 100, Close: 100, CloseTime: 3599999})
+
+	ex := &bracketExchange{PaperExchange: pe, brackets: []LeverageBracket{
+		{Bracket: 1, InitialLeverage: 20, NotionalFloor: 0, NotionalCap: 5000, MaintMarginRatio: 0.01},
+		{Bracket: 2, InitialLeverage: 10, NotionalFloor: 5000, NotionalCap: 20000, MaintMarginRatio: 0.025},
+	}}
+	return NewOrderValidator(ex), pe
+}
+
+// TestValidateRejections tests the typed reason for each kind of invalid order
+func TestValidateRejections(t *testing.T) {
+	validator, _ := newTestValidator()
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		order    OrderRequest
+		expected error
+	}{
+		{"valid", OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "0.5"}, nil},
+		{"step", OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "0.0015"}, ErrPrecision},
+		{"tick", OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "LIMIT", Quantity: "0.5", Price: "99.55"}, ErrPrecision},
+		{"min notional", OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "0.01"}, ErrBelowMinNotional},
+		{"margin", OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "150"}, ErrInsufficientMargin},
+		{"bracket", OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "250"}, ErrAboveMaxNotional},
+		{"reduce only without position", OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "MARKET", Quantity: "0.5", ReduceOnly: true}, ErrReduceOnly},
+		{"close position with quantity", OrderRequest{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", Quantity: "1", StopPrice: "95", ClosePosition: true}, ErrInvalidOrder},
+	}
+
+	for _, tt := range tests {
+		err := validator.Validate(ctx, &tt.order)
+		if tt.expected == nil && err != nil {
+			t.Errorf("%s: expected valid order, got %v", tt.name, err)
+		}
+		if tt.expected != nil && !errors.Is(err, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+// TestValidateReduceOnly tests reduce-only orders against the open position
+func TestValidateReduceOnly(t *testing.T) {
+	validator, pe := newTestValidator()
+	ctx := context.Background()
+
+	if _, err := pe.CreateOrder(&OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "1"}); err != nil {
+		t.Fatalf("Failed to open position: %v", err)
+	}
+
+	valid := []OrderRequest{
+		{Symbol: "BTCUSDT", Side: "SELL", Type: "STOP_MARKET", Quantity: "1", StopPrice: "95", ReduceOnly: true},
+		{Symbol: "BTCUSDT", Side: "SELL", Type: "TAKE_PROFIT_MARKET", StopPrice: "110", ClosePosition: true},
+		// Reduce-only orders are exempt from the minimum notional
+		{Symbol: "BTCUSDT", Side: "SELL", Type: "MARKET", Quantity: "0.001", ReduceOnly: true},
+	}
+	for _, order := range valid {
+		if err := validator.Validate(ctx, &order); err != nil {
+			t.Errorf("Expected %s %s to be valid, got %v", order.Side, order.Type, err)
+		}
+	}
+
+	invalid := []OrderRequest{
+		{Symbol: "BTCUSDT", Side: "BUY", Type: "STOP_MARKET", Quantity: "1", StopPrice: "95", ReduceOnly: true},
+		{Symbol: "BTCUSDT", Side: "SELL", Type: "MARKET", Quantity: "2", ReduceOnly: true},
+	}
+	for _, order := range invalid {
+		if err := validator.Validate(ctx, &order); !errors.Is(err, ErrReduceOnly) {
+			t.Errorf("Expected ErrReduceOnly for %s %s %s, got %v", order.Side, order.Quantity, order.Type, err)
+		}
+	}
+}
+
+// markPriceExchange reports a mark price apart from the last traded price
+type markPriceExchange struct {
+	*bracketExchange
+	mark float64
+}
+
+func (me *markPriceExchange) GetMarkPrice(ctx context.Context, symbol string) (float64, error) {
+	return me.mark, nil
+}
+
+// TestValidateMarkPrice tests that market orders are valued at the mark price rather than the last price
+func TestValidateMarkPrice(t *testing.T) {
+	validator, _ := newTestValidator()
+	validator = NewOrderValidator(&markPriceExchange{bracketExchange: validator.exchange.(*bracketExchange), mark: 5})
+	ctx := context.Background()
+
+	// 0.5 at the last price of 100 would be 50 USDT, at the mark price of 5 it is below the minimum
+	order := OrderRequest{Symbol: "BTCUSDT", Side: "BUY", Type: "MARKET", Quantity: "0.5"}
+	if err := validator.Validate(ctx, &order); !errors.Is(err, ErrBelowMinNotional) {
+		t.Errorf("Expected ErrBelowMinNotional at the mark price, got %v", err)
+	}
+	order.Quantity = "2"
+	if err := validator.Validate(ctx, &order); err != nil {
+		t.Errorf("Expected 10 USDT at the mark price to be valid, got %v", err)
+	}
+}
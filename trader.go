@@ -4,20 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
 	config "tread2/internal"
+	"tread2/pkg/analysis"
 	"tread2/pkg/trading"
 
 	"github.com/joho/godotenv"
-	"github.com/adshao/go-binance/v2/futures"
 )
 
 // AI API structures
@@ -63,6 +63,37 @@ type TradingSignal struct {
 	Analysis     string  `json:"analysis"`
 }
 
+// breakoutStrategy tags the client order IDs of breakout trades
+const breakoutStrategy = "breakout"
+
+// breakoutLeverage is the conservative, highest leverage breakout trades are opened at
+const breakoutLeverage = 3
+
+// breakoutLeverageConfig selects breakout leverage from volatility, loaded by StartTrading
+var breakoutLeverageConfig = capBreakoutLeverage(trading.DefaultLeverageConfig())
+
+// capBreakoutLeverage keeps the leverage range of cfg at or below breakoutLeverage
+func capBreakoutLeverage(cfg trading.LeverageConfig) trading.LeverageConfig {
+	cfg.MaxLeverage = min(cfg.MaxLeverage, breakoutLeverage)
+	cfg.MinLeverage = min(cfg.MinLeverage, cfg.MaxLeverage)
+	return cfg
+}
+
+// breakoutExits configures scaling out of breakout trades, loaded by StartTrading
+var breakoutExits = trading.DefaultScaledExitConfig()
+
+// breakoutSizing configures the risk taken per breakout trade, loaded by StartTrading
+var breakoutSizing = trading.DefaultSizingConfig()
+
+// breakoutExposure limits the open positions across the portfolio, loaded by StartTrading
+var breakoutExposure = trading.DefaultExposureConfig()
+
+// breakoutLiquidation keeps breakout stops inside the liquidation price, loaded by StartTrading
+var breakoutLiquidation = trading.DefaultLiquidationConfig()
+
+// breakoutJournal records breakout signals, orders and trades when JOURNAL_DIR is set
+var breakoutJournal *trading.Journal
+
 // BreakoutSignal represents support/resistance breakout analysis
 type BreakoutSignal struct {
 	Symbol          string      `json:"symbol"`
@@ -77,6 +108,8 @@ type BreakoutSignal struct {
 	TakeProfit      float64     `json:"take_profit"`      // AI-enhanced target
 	Confidence      float64     `json:"confidence"`       // 0-100
 	Analysis        string      `json:"analysis"`
+	SwingHigh       float64     `json:"swing_high"`       // Range the scaled take profits extend
+	SwingLow        float64     `json:"swing_low"`
 }
 
 // SupportResistanceLevel represents a support or resistance level
@@ -100,7 +133,7 @@ type FibonacciLevels struct {
 }
 
 // scanForBreakouts scans for breakout signals
-func scanForBreakouts(tradingClient *trading.TradingClient, symbols []string) ([]*BreakoutSignal, error) {
+func scanForBreakouts(tradingClient trading.Exchange, symbols []string) ([]*BreakoutSignal, error) {
 	var breakoutSignals []*BreakoutSignal
 
 	fmt.Printf("🔍 Scanning %d symbols for breakout signals...\n", len(symbols))
@@ -131,30 +164,47 @@ func scanForBreakouts(tradingClient *trading.TradingClient, symbols []string) ([
 }
 
 // main trading loop with breakout logic
-func startBreakoutTrading(tradingClient *trading.TradingClient, symbols []string) {
+func startBreakoutTrading(tradingClient trading.Exchange, positions *trading.PositionManager, entries *trading.LimitEntryManager, breaker *trading.CircuitBreaker, symbols []string) {
 	fmt.Printf("🚀 Starting Professional Breakout Trading System...\n")
 	fmt.Printf("📊 Monitoring %d symbols for breakout opportunities\n", len(symbols))
 
-	// Run initial scan immediately
-	runBreakoutScan(tradingClient, symbols)
+	ctx := context.Background()
 
-	ticker := time.NewTicker(5 * time.Minute) // Check every 5 minutes
-	defer ticker.Stop()
+	// Keep hourly candles current over websocket instead of polling
+	stream, err := trading.NewKlineStream(tradingClient, symbols, "1h", 200)
+	if err != nil {
+		log.Fatalf("Failed to create kline stream: %v", err)
+	}
+	if err := stream.Start(ctx); err != nil {
+		log.Fatalf("Failed to start kline stream: %v", err)
+	}
+	tradingClient = trading.WithKlineSource(tradingClient, stream)
+
+	// Run initial scan immediately
+	runBreakoutScan(tradingClient, positions, entries, breaker, symbols)
 
+	// Re-scan symbols as soon as their hourly candle closes
 	for {
-		select {
-		case <-ticker.C:
-			runBreakoutScan(tradingClient, symbols)
+		closed, err := stream.NextBatch(ctx)
+		if err != nil {
+			log.Fatalf("Kline stream stopped: %v", err)
 		}
+		runBreakoutScan(tradingClient, positions, entries, breaker, closed)
 	}
 }
 
-// runBreakoutScan performs a single breakout scan
-func runBreakoutScan(tradingClient *trading.TradingClient, symbols []string) {
+// runBreakoutScan performs a single breakout scan; entries is nil for market entries
+func runBreakoutScan(tradingClient trading.Exchange, positions *trading.PositionManager, entries *trading.LimitEntryManager, breaker *trading.CircuitBreaker, symbols []string) {
 	fmt.Print("\n" + strings.Repeat("=", 80) + "\n")
 	fmt.Printf("🔍 Scanning for breakout signals - %s\n", time.Now().Format("2006-01-02 15:04:05"))
 	fmt.Print(strings.Repeat("=", 80) + "\n")
 
+	// Open nothing while the circuit breaker is tripped
+	if err := breaker.Check(context.Background()); err != nil {
+		fmt.Printf("🛑 %v\n", err)
+		return
+	}
+
 	// Check current positions and cleanup if needed
 	// err := tradingClient.CleanupOldPositions()
 	// if err != nil {
@@ -162,20 +212,12 @@ func runBreakoutScan(tradingClient *trading.TradingClient, symbols []string) {
 	// }
 
 	// Get current balance
-	balance, err := tradingClient.GetBalance(context.Background())
+	balance, err := tradingClient.GetUSDTBalance(context.Background())
 	if err != nil {
 		log.Printf("Failed to get balance: %v", err)
 		return
 	}
-
-	// Calculate USDT balance
-	var balanceUSDT float64
-	for _, asset := range balance {
-		if asset.Asset == "USDT" {
-			balanceUSDT, _ = strconv.ParseFloat(asset.WalletBalance, 64)
-			break
-		}
-	}
+	balanceUSDT := balance.WalletBalance
 
 	fmt.Printf("💰 Current Balance: %.2f USDT\n", balanceUSDT)
 
@@ -221,8 +263,10 @@ func runBreakoutScan(tradingClient *trading.TradingClient, symbols []string) {
 			// Update breakout signal with AI-enhanced targets
 			breakoutSignal.StopLoss = aiSignal.StopLoss
 			breakoutSignal.TakeProfit = aiSignal.TakeProfit
+			breakoutSignal.SwingHigh, breakoutSignal.SwingLow = swingRange(candleData, breakoutExits.Lookback)
+			breakoutJournal.RecordSignal(breakoutTag(breakoutSignal), breakoutSignal, aiSignal.Analysis)
 
-			success, err := executeBreakoutTrade(context.Background(), tradingClient, breakoutSignal, balanceUSDT)
+			success, err := executeBreakoutTrade(context.Background(), tradingClient, positions, entries, breakoutSignal)
 			if err != nil {
 				log.Printf("Failed to execute breakout trade: %v", err)
 				continue
@@ -295,32 +339,27 @@ func displayAIRecommendation(signal *TradingSignal) {
 }
 
 // getBreakoutCandlestickData gets hourly candlestick data for breakout analysis
-func getBreakoutCandlestickData(tradingClient *trading.TradingClient, symbol string, limit int) ([]*CandleData, error) {
-	klines, err := tradingClient.BinanceClient.NewKlinesService().
-		Symbol(symbol).
-		Interval("1h"). // 1-hour timeframe for breakout detection
-		Limit(limit).
-		Do(context.Background())
-
+func getBreakoutCandlestickData(tradingClient trading.Exchange, symbol string, limit int) ([]*CandleData, error) {
+	// 1-hour timeframe for breakout detection
+	klines, err := tradingClient.GetKlines(symbol, "1h", limit)
 	if err != nil {
 		return nil, err
 	}
 
 	var candleData []*CandleData
-	for _, kline := range klines {
-		open, _ := strconv.ParseFloat(kline.Open, 64)
-		high, _ := strconv.ParseFloat(kline.High, 64)
-		low, _ := strconv.ParseFloat(kline.Low, 64)
-		close, _ := strconv.ParseFloat(kline.Close, 64)
-		volume, _ := strconv.ParseFloat(kline.Volume, 64)
+	for _, row := range klines {
+		candle, err := trading.ParseCandle(row)
+		if err != nil {
+			return nil, err
+		}
 
 		candleData = append(candleData, &CandleData{
-			Timestamp: kline.OpenTime,
-			Open:      open,
-			High:      high,
-			Low:       low,
-			Close:     close,
-			Volume:    volume,
+			Timestamp: candle.OpenTime,
+			Open:      candle.Open,
+			High:      candle.High,
+			Low:       candle.Low,
+			Close:     candle.Close,
+			Volume:    candle.Volume,
 		})
 	}
 
@@ -329,226 +368,149 @@ func getBreakoutCandlestickData(tradingClient *trading.TradingClient, symbol str
 
 // analyzeBreakoutSignal analyzes support/resistance breakout signals
 func analyzeBreakoutSignal(candleData []*CandleData, symbol string) (*BreakoutSignal, error) {
-	if len(candleData) < 50 {
-		return nil, fmt.Errorf("insufficient data for breakout analysis")
+	breakout, err := analysis.AnalyzeSupportResistanceBreakout(toAnalysisCandles(candleData))
+	if err != nil {
+		return nil, err
 	}
 
-	// Get current and previous candles
 	currentCandle := candleData[len(candleData)-1]
 	previousCandle := candleData[len(candleData)-2]
 
-	// Calculate support and resistance levels from recent history
-	supportLevel, resistanceLevel := calculateSupportResistanceLevels(candleData)
-
-	signal := &BreakoutSignal{
+	return &BreakoutSignal{
 		Symbol:          symbol,
 		CurrentPrice:    currentCandle.Close,
-		SupportLevel:    supportLevel,
-		ResistanceLevel: resistanceLevel,
+		SupportLevel:    breakout.SupportLevel,
+		ResistanceLevel: breakout.ResistanceLevel,
 		PreviousCandle:  previousCandle,
 		CurrentCandle:   currentCandle,
-		BreakoutType:    "NONE",
-		Signal:          "NONE",
-		StopLoss:        0,
+		BreakoutType:    breakout.BreakoutType,
+		Signal:          breakout.Signal,
+		StopLoss:        breakout.StopLoss,
 		TakeProfit:      0,
-		Confidence:      0,
-		Analysis:        "",
-	}
-
-	// Check for support breakout (bearish signal)
-	if previousCandle.Close > supportLevel && // Previous candle was above support
-		previousCandle.Close > previousCandle.Open && // Previous candle was green
-		currentCandle.Close < supportLevel { // Current candle broke below support
-
-		signal.BreakoutType = "SUPPORT_BREAK"
-		signal.Signal = "LONG" // Wait for LONG signal as per new logic
-		signal.StopLoss = currentCandle.Low // Stop loss at current candle's low
-		signal.Confidence = 75
-		signal.Analysis = fmt.Sprintf("Support breakout detected: Previous green candle at %.4f above support %.4f, current candle broke below to %.4f",
-			previousCandle.Close, supportLevel, currentCandle.Close)
-	}
-
-	// Check for resistance breakout (bullish signal)
-	if previousCandle.Close < resistanceLevel && // Previous candle was below resistance
-		previousCandle.Close < previousCandle.Open && // Previous candle was red
-		currentCandle.Close > resistanceLevel { // Current candle broke above resistance
-
-		signal.BreakoutType = "RESISTANCE_BREAK"
-		signal.Signal = "SHORT" // Wait for SHORT signal as per new logic
-		signal.StopLoss = currentCandle.High // Stop loss at current candle's high
-		signal.Confidence = 75
-		signal.Analysis = fmt.Sprintf("Resistance breakout detected: Previous red candle at %.4f below resistance %.4f, current candle broke above to %.4f",
-			previousCandle.Close, resistanceLevel, currentCandle.Close)
-	}
-
-	return signal, nil
+		Confidence:      breakout.Confidence,
+		Analysis:        breakout.Analysis,
+	}, nil
 }
 
 // calculateSupportResistanceLevels calculates support and resistance levels from price history
 func calculateSupportResistanceLevels(candleData []*CandleData) (float64, float64) {
-	if len(candleData) < 20 {
-		return 0, 0
-	}
+	return analysis.CalculateSupportResistanceLevels(toAnalysisCandles(candleData))
+}
 
-	// Use last 50 candles for S/R calculation
-	period := 50
-	if len(candleData) < period {
-		period = len(candleData)
+// toAnalysisCandles converts candle data to the analysis package format
+func toAnalysisCandles(candleData []*CandleData) []analysis.CandleData {
+	candles := make([]analysis.CandleData, len(candleData))
+	for i, candle := range candleData {
+		candles[i] = analysis.CandleData(*candle)
 	}
+	return candles
+}
 
-	recentData := candleData[len(candleData)-period:]
-
-	// Find pivot highs and lows
-	var pivotHighs, pivotLows []float64
-
-	for i := 2; i < len(recentData)-2; i++ {
-		// Pivot high: higher than 2 candles on each side
-		if recentData[i].High > recentData[i-1].High && recentData[i].High > recentData[i-2].High &&
-			recentData[i].High > recentData[i+1].High && recentData[i].High > recentData[i+2].High {
-			pivotHighs = append(pivotHighs, recentData[i].High)
-		}
-
-		// Pivot low: lower than 2 candles on each side
-		if recentData[i].Low < recentData[i-1].Low && recentData[i].Low < recentData[i-2].Low &&
-			recentData[i].Low < recentData[i+1].Low && recentData[i].Low < recentData[i+2].Low {
-			pivotLows = append(pivotLows, recentData[i].Low)
-		}
+// executeBreakoutTrade executes a breakout trade with AI confirmation, with a
+// limit entry at the broken level when entries is set
+func executeBreakoutTrade(ctx context.Context, tradingClient trading.Exchange, positions *trading.PositionManager, entries *trading.LimitEntryManager, breakoutSignal *BreakoutSignal) (bool, error) {
+	// Choose the leverage from volatility and the stop, within the bracket for the intended notional
+	sizer := trading.NewPositionSizer(tradingClient, breakoutSizing)
+	sizeRequest := trading.SizeRequest{
+		Symbol:    breakoutSignal.Symbol,
+		OrderType: "MARKET",
+		Entry:     breakoutSignal.CurrentPrice,
+		Stop:      breakoutSignal.StopLoss,
 	}
-
-	// Calculate support (recent significant low)
-	var supportLevel float64
-	if len(pivotLows) > 0 {
-		// Use the most recent significant low
-		supportLevel = pivotLows[len(pivotLows)-1]
-
-		// If multiple lows are close, use the average
-		tolerance := supportLevel * 0.01 // 1% tolerance
-		var closeLows []float64
-		for _, low := range pivotLows {
-			if math.Abs(low-supportLevel) <= tolerance {
-				closeLows = append(closeLows, low)
-			}
-		}
-
-		if len(closeLows) > 1 {
-			sum := 0.0
-			for _, low := range closeLows {
-				sum += low
-			}
-			supportLevel = sum / float64(len(closeLows))
-		}
+	notional, err := sizer.RiskNotional(ctx, sizeRequest)
+	if err != nil {
+		return false, fmt.Errorf("failed to size position: %v", err)
 	}
-
-	// Calculate resistance (recent significant high)
-	var resistanceLevel float64
-	if len(pivotHighs) > 0 {
-		// Use the most recent significant high
-		resistanceLevel = pivotHighs[len(pivotHighs)-1]
-
-		// If multiple highs are close, use the average
-		tolerance := resistanceLevel * 0.01 // 1% tolerance
-		var closeHighs []float64
-		for _, high := range pivotHighs {
-			if math.Abs(high-resistanceLevel) <= tolerance {
-				closeHighs = append(closeHighs, high)
-			}
-		}
-
-		if len(closeHighs) > 1 {
-			sum := 0.0
-			for _, high := range closeHighs {
-				sum += high
-			}
-			resistanceLevel = sum / float64(len(closeHighs))
-		}
+	policy := trading.NewLeveragePolicy(tradingClient, breakoutLeverageConfig)
+	leverage, err := policy.Select(ctx, trading.LeverageRequest{
+		Symbol:   breakoutSignal.Symbol,
+		Entry:    breakoutSignal.CurrentPrice,
+		Stop:     breakoutSignal.StopLoss,
+		Notional: notional,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to select leverage: %v", err)
 	}
 
-	return supportLevel, resistanceLevel
-}
-
-// executeBreakoutTrade executes a breakout trade with AI confirmation
-func executeBreakoutTrade(ctx context.Context, tradingClient *trading.TradingClient, breakoutSignal *BreakoutSignal, balanceUSDT float64) (bool, error) {
-	// Check margin balance first
-	marginAmount := 3.0 // $3 per trade
-	effectiveBalance := balanceUSDT
-
-	if effectiveBalance < marginAmount {
-		return false, fmt.Errorf("insufficient balance: %.2f USDT margin balance, %.2f USDT needed", effectiveBalance, marginAmount)
+	// Size the position so that the stop loss costs the configured share of equity
+	sizeRequest.Leverage = leverage.Leverage
+	size, err := sizer.Size(ctx, sizeRequest)
+	if err != nil {
+		return false, fmt.Errorf("failed to size position: %v", err)
 	}
 
-	// Set conservative leverage for breakout trades
-	err := tradingClient.SetLeverage(breakoutSignal.Symbol, 3)
+	// Keep the stop well inside the liquidation price, lowering the leverage if needed
+	liquidation, err := trading.NewLiquidationGuard(tradingClient, breakoutLiquidation).Check(ctx, trading.LiquidationRequest{
+		Symbol:   breakoutSignal.Symbol,
+		Side:     breakoutSignal.Signal,
+		Entry:    breakoutSignal.CurrentPrice,
+		Stop:     breakoutSignal.StopLoss,
+		Quantity: size.Quantity,
+		Leverage: leverage.Leverage,
+	})
 	if err != nil {
-		return false, fmt.Errorf("failed to set leverage: %v", err)
+		return false, err
+	}
+	if liquidation.Leverage != leverage.Leverage {
+		// Less leverage fits a smaller position in the available margin
+		sizeRequest.Leverage = liquidation.Leverage
+		if size, err = sizer.Size(ctx, sizeRequest); err != nil {
+			return false, fmt.Errorf("failed to size position: %v", err)
+		}
 	}
+	quantity := size.Quantity
 
-	// Calculate position size
-	positionValue := marginAmount * 3 // 3x leverage
-	quantity := positionValue / breakoutSignal.CurrentPrice
+	// Check the portfolio limits against the open positions and resting entries
+	if err := trading.NewExposureGate(tradingClient, breakoutExposure).WithLimitEntries(entries).Check(ctx, breakoutSignal.Symbol, breakoutSignal.Signal, size.Notional); err != nil {
+		return false, err
+	}
 
-	// Format quantity according to symbol precision
-	quantity = formatQuantity(breakoutSignal.Symbol, quantity)
+	// Set the selected leverage, or the lower one the stop needs, when it differs from the current one
+	if err := policy.Apply(breakoutSignal.Symbol, liquidation.Leverage); err != nil {
+		return false, err
+	}
 
-	fmt.Printf("📏 Position Size: %s %s\n", formatQuantityString(breakoutSignal.Symbol, quantity), strings.Replace(breakoutSignal.Symbol, "USDT", "", 1))
-	fmt.Printf("💼 Position Value: $%.2f\n", positionValue)
+	fmt.Printf("📏 Position Size: %g %s (limited by %s)\n", quantity, strings.Replace(breakoutSignal.Symbol, "USDT", "", 1), size.LimitedBy)
+	fmt.Printf("💼 Position Value: $%.2f, risking $%.2f\n", size.Notional, size.Risk)
+	fmt.Printf("⚖️  Leverage: %dx, liquidation at %.4f\n", liquidation.Leverage, liquidation.LiquidationPrice)
 
 	// Place market order with enhanced precision handling
-	var side futures.SideType
+	side := "BUY"
 	if breakoutSignal.Signal == "SHORT" {
-		side = futures.SideTypeSell
-	} else {
-		side = futures.SideTypeBuy
+		side = "SELL"
 	}
 
-	// Try multiple methods to place the order
-	orderResult, err := placeOrderWithRetry(ctx, tradingClient, breakoutSignal.Symbol, side, quantity)
-	if err != nil {
-		return false, fmt.Errorf("failed to place order after all attempts: %v", err)
+	// Wait for a retest of the broken level; the bracket is attached on fill
+	if entries != nil {
+		if placed, err := placeBreakoutLimitEntry(ctx, tradingClient, positions, entries, breakoutSignal, side, quantity); placed || err != nil {
+			return placed, err
+		}
 	}
 
-	fmt.Printf("✅ Order placed successfully! Order ID: %d\n", orderResult.OrderID)
-
-	// Set stop loss and take profit with AI-enhanced levels
-	err = setBreakoutStopLossAndTakeProfit(ctx, tradingClient, breakoutSignal, quantity)
+	// Place the entry with its stop loss and take profit; the position is
+	// flattened if the protective orders cannot be placed
+	bracket, err := placeBreakoutBracket(ctx, tradingClient, breakoutSignal, side, quantity)
 	if err != nil {
-		fmt.Printf("⚠️ Warning: Failed to set stop loss/take profit: %v", err)
+		return false, fmt.Errorf("failed to place bracket: %v", err)
 	}
 
+	fmt.Printf("✅ Bracket placed! Entry: %d, Stop Loss: %d, Take Profit: %v\n", bracket.EntryOrderID, bracket.StopOrderID, bracket.TakeProfitOrderIDs)
+	breakoutJournal.RecordBracket(breakoutTag(breakoutSignal), bracket)
+	positions.ManageBracket(bracket, breakoutSignal.StopLoss)
 	return true, nil
 }
 
-// setBreakoutStopLossAndTakeProfit sets protective orders for breakout trades
-func setBreakoutStopLossAndTakeProfit(ctx context.Context, tradingClient *trading.TradingClient, breakoutSignal *BreakoutSignal, quantity float64) error {
-	// Stop Loss Order
-	var stopSide futures.SideType
-	if breakoutSignal.Signal == "SHORT" {
-		stopSide = futures.SideTypeBuy
-	} else {
-		stopSide = futures.SideTypeSell
-	}
-
-	// Try to place stop loss with enhanced precision
-	quantityStr, err := tradingClient.FormatQuantity(ctx, breakoutSignal.Symbol, quantity)
+// breakoutBracketOrders builds the entry, stop loss and take profit orders of
+// a breakout trade; there is a take profit per target when scaling out
+func breakoutBracketOrders(ctx context.Context, tradingClient trading.Exchange, breakoutSignal *BreakoutSignal, side string, quantity float64) (entry, stopLoss *trading.OrderRequest, takeProfits []*trading.OrderRequest, err error) {
+	quantityStr, err := trading.FormatMarketQuantityFor(ctx, tradingClient, breakoutSignal.Symbol, quantity)
 	if err != nil {
-		quantityStr = fmt.Sprintf("%.3f", quantity) // fallback
+		return nil, nil, nil, fmt.Errorf("failed to format quantity: %v", err)
 	}
 
-	stopPriceStr, err := tradingClient.FormatPrice(ctx, breakoutSignal.Symbol, breakoutSignal.StopLoss)
+	stopPriceStr, err := trading.FormatPriceFor(ctx, tradingClient, breakoutSignal.Symbol, breakoutSignal.StopLoss)
 	if err != nil {
-		stopPriceStr = fmt.Sprintf("%.4f", breakoutSignal.StopLoss) // fallback
-	}
-
-	_, err = tradingClient.BinanceClient.NewCreateOrderService().
-		Symbol(breakoutSignal.Symbol).
-		Side(stopSide).
-		Type("STOP_MARKET").
-		Quantity(quantityStr).
-		StopPrice(stopPriceStr).
-		ReduceOnly(true).
-		Do(ctx)
-
-	if err != nil {
-		return fmt.Errorf("failed to set stop loss: %v", err)
+		return nil, nil, nil, fmt.Errorf("failed to format stop price: %v", err)
 	}
 
 	// Take Profit Order - use AI-enhanced target if available
@@ -566,27 +528,88 @@ func setBreakoutStopLossAndTakeProfit(ctx context.Context, tradingClient *tradin
 		}
 	}
 
-	takeProfitPriceStr, err := tradingClient.FormatPrice(ctx, breakoutSignal.Symbol, takeProfitPrice)
+	takeProfitPriceStr, err := trading.FormatPriceFor(ctx, tradingClient, breakoutSignal.Symbol, takeProfitPrice)
 	if err != nil {
-		takeProfitPriceStr = fmt.Sprintf("%.4f", takeProfitPrice) // fallback
+		return nil, nil, nil, fmt.Errorf("failed to format take profit price: %v", err)
 	}
 
-	_, err = tradingClient.BinanceClient.NewCreateOrderService().
-		Symbol(breakoutSignal.Symbol).
-		Side(stopSide).
-		Type("LIMIT").
-		Quantity(quantityStr).
-		Price(takeProfitPriceStr).
-		TimeInForce("GTC").
-		ReduceOnly(true).
-		Do(ctx)
+	exitSide := "SELL"
+	if side == "SELL" {
+		exitSide = "BUY"
+	}
 
-	if err != nil {
-		return fmt.Errorf("failed to set take profit: %v", err)
+	// Tag each leg so a restart recognises orders already placed for this signal
+	tag := breakoutTag(breakoutSignal)
+	clientOrderID := func(leg trading.OrderLeg) string {
+		tag.Leg = leg
+		return tag.ClientOrderID()
 	}
 
-	fmt.Printf("✅ Stop Loss (%.4f) and Take Profit (%.4f) orders set\n", breakoutSignal.StopLoss, takeProfitPrice)
-	return nil
+	entry = &trading.OrderRequest{
+		Symbol:        breakoutSignal.Symbol,
+		Side:          side,
+		Type:          "MARKET",
+		Quantity:      quantityStr,
+		ClientOrderID: clientOrderID(trading.LegEntry),
+	}
+	stopLoss = &trading.OrderRequest{
+		Symbol:        breakoutSignal.Symbol,
+		Side:          exitSide,
+		Type:          "STOP_MARKET",
+		Quantity:      quantityStr,
+		StopPrice:     stopPriceStr,
+		ReduceOnly:    true,
+		ClientOrderID: clientOrderID(trading.LegStopLoss),
+	}
+
+	// Scale out at Fibonacci extensions of the swing; the quantity stop is
+	// resized to what is left after each target fills
+	if breakoutExits.Enabled && breakoutSignal.SwingHigh > breakoutSignal.SwingLow {
+		targets := breakoutExits.FibonacciTargets(breakoutSignal.SwingHigh, breakoutSignal.SwingLow, breakoutSignal.Signal)
+		scaled, err := trading.ScaledTakeProfits(ctx, tradingClient, breakoutSignal.Symbol, side, quantity, breakoutSignal.CurrentPrice, targets, tag)
+		if err == nil {
+			return entry, stopLoss, scaled, nil
+		}
+		log.Printf("⚠️  Using a single take profit for %s: %v", breakoutSignal.Symbol, err)
+	}
+
+	takeProfit := &trading.OrderRequest{
+		Symbol:        breakoutSignal.Symbol,
+		Side:          exitSide,
+		Type:          "LIMIT",
+		Quantity:      quantityStr,
+		Price:         takeProfitPriceStr,
+		TimeInForce:   "GTC",
+		ReduceOnly:    true,
+		ClientOrderID: clientOrderID(trading.LegTakeProfit),
+	}
+	return entry, stopLoss, []*trading.OrderRequest{takeProfit}, nil
+}
+
+// breakoutTag identifies the orders of a breakout signal by the candle it broke out on
+func breakoutTag(breakoutSignal *BreakoutSignal) trading.ClientOrderTag {
+	return trading.ClientOrderTag{
+		Strategy: breakoutStrategy,
+		Symbol:   breakoutSignal.Symbol,
+		SignalID: trading.SignalID(time.UnixMilli(breakoutSignal.CurrentCandle.Timestamp)),
+	}
+}
+
+// swingRange returns the highest high and lowest low of the last lookback candles
+func swingRange(candleData []*CandleData, lookback int) (high, low float64) {
+	if len(candleData) == 0 {
+		return 0, 0
+	}
+	if lookback > len(candleData) {
+		lookback = len(candleData)
+	}
+	recent := candleData[len(candleData)-lookback:]
+	high, low = recent[0].High, recent[0].Low
+	for _, candle := range recent {
+		high = math.Max(high, candle.High)
+		low = math.Min(low, candle.Low)
+	}
+	return high, low
 }
 
 // callAIForBreakoutAnalysis calls AI to analyze breakout signal and get enhanced targets
@@ -819,48 +842,80 @@ func getCandleColor(candle *CandleData) string {
 	return "Red"
 }
 
-// Helper functions for quantity and price formatting
-func formatQuantity(symbol string, quantity float64) float64 {
-	// Simple rounding for most pairs
-	return math.Round(quantity*1000) / 1000
-}
+// placeBreakoutBracket places a breakout bracket, retrying at 90% size only
+// when the entry was rejected for insufficient margin
+func placeBreakoutBracket(ctx context.Context, tradingClient trading.Exchange, breakoutSignal *BreakoutSignal, side string, quantity float64) (*trading.Bracket, error) {
+	var lastErr error
+
+	for _, size := range []float64{quantity, quantity * 0.9} {
+		entry, stopLoss, takeProfits, err := breakoutBracketOrders(ctx, tradingClient, breakoutSignal, side, size)
+		if err != nil {
+			return nil, err
+		}
 
-func formatQuantityString(symbol string, quantity float64) string {
-	return fmt.Sprintf("%.3f", quantity)
+		bracket, err := trading.PlaceBracket(ctx, tradingClient, entry, stopLoss, takeProfits...)
+		if err == nil {
+			for _, takeProfit := range takeProfits {
+				fmt.Printf("✅ Take Profit set: %s at %s\n", takeProfit.Quantity, takeProfit.Price)
+			}
+			fmt.Printf("✅ Stop Loss set at %s\n", stopLoss.StopPrice)
+			return bracket, nil
+		}
+		lastErr = err
+
+		// A smaller order only helps when the entry was short of margin
+		if bracket != nil || !errors.Is(err, trading.ErrInsufficientMargin) {
+			break
+		}
+	}
+
+	return nil, lastErr
 }
 
-// placeOrderWithRetry tries to place an order with multiple retry attempts
-func placeOrderWithRetry(ctx context.Context, tradingClient *trading.TradingClient, symbol string, side futures.SideType, quantity float64) (*futures.CreateOrderResponse, error) {
-	var lastErr error
-	
-	// Method 1: Direct quantity
-	quantityStr := fmt.Sprintf("%.6f", quantity)
-	order, err := tradingClient.BinanceClient.NewCreateOrderService().
-		Symbol(symbol).
-		Side(side).
-		Type("MARKET").
-		Quantity(quantityStr).
-		Do(ctx)
-	if err == nil {
-		return order, nil
-	}
-	lastErr = err
-	
-	// Method 2: Try with smaller quantity
-	smallerQuantity := quantity * 0.9
-	quantityStr = fmt.Sprintf("%.3f", smallerQuantity)
-	order, err = tradingClient.BinanceClient.NewCreateOrderService().
-		Symbol(symbol).
-		Side(side).
-		Type("MARKET").
-		Quantity(quantityStr).
-		Do(ctx)
-	if err == nil {
-		return order, nil
-	}
-	lastErr = err
-	
-	return nil, fmt.Errorf("all order placement methods failed, last error: %w", lastErr)
+// placeBreakoutLimitEntry rests a LIMIT entry at the broken resistance (LONG)
+// or support (SHORT), cancelled if the price reaches the stop first or it is
+// not filled in time. It reports false when the level is not on the entry
+// side of the price, leaving the trade to a market entry.
+func placeBreakoutLimitEntry(ctx context.Context, tradingClient trading.Exchange, positions *trading.PositionManager, entries *trading.LimitEntryManager, breakoutSignal *BreakoutSignal, side string, quantity float64) (bool, error) {
+	level := breakoutSignal.ResistanceLevel
+	if breakoutSignal.Signal == "SHORT" {
+		level = breakoutSignal.SupportLevel
+	}
+	if level <= 0 || (side == "BUY" && level >= breakoutSignal.CurrentPrice) || (side == "SELL" && level <= breakoutSignal.CurrentPrice) {
+		fmt.Printf("⚠️  No retest level for %s, entering at market\n", breakoutSignal.Symbol)
+		return false, nil
+	}
+
+	entry, _, _, err := breakoutBracketOrders(ctx, tradingClient, breakoutSignal, side, quantity)
+	if err != nil {
+		return false, err
+	}
+	entry.Type = "LIMIT"
+	if entry.Price, err = trading.FormatPriceFor(ctx, tradingClient, breakoutSignal.Symbol, level); err != nil {
+		return false, fmt.Errorf("failed to format entry price: %v", err)
+	}
+	if entry.Quantity, err = trading.FormatQuantityFor(ctx, tradingClient, breakoutSignal.Symbol, quantity); err != nil {
+		return false, fmt.Errorf("failed to format quantity: %v", err)
+	}
+
+	err = entries.Place(ctx, trading.PendingEntry{
+		Entry:        entry,
+		Invalidation: breakoutSignal.StopLoss,
+		Protection: func(filled float64) (*trading.OrderRequest, []*trading.OrderRequest, error) {
+			_, stopLoss, takeProfits, err := breakoutBracketOrders(ctx, tradingClient, breakoutSignal, side, filled)
+			return stopLoss, takeProfits, err
+		},
+		OnFilled: func(bracket *trading.Bracket) {
+			breakoutJournal.RecordBracket(breakoutTag(breakoutSignal), bracket)
+			positions.ManageBracket(bracket, breakoutSignal.StopLoss)
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to place limit entry: %v", err)
+	}
+
+	fmt.Printf("⏳ Limit entry placed at %s, waiting for the retest\n", entry.Price)
+	return true, nil
 }
 
 // StartTrading starts the breakout trading system
@@ -877,6 +932,72 @@ func StartTrading() {
 		log.Fatalf("Failed to create trading client: %v", err)
 	}
 
+	// Simulate fills on a paper exchange instead of sending real orders
+	var exchange trading.Exchange = tradingClient
+	var account *trading.UserDataStream
+	if trading.PaperTradingEnabled() {
+		paperConfig := trading.PaperConfigFromEnv()
+		exchange = trading.NewPaperExchange(tradingClient, paperConfig)
+		log.Printf("📝 Paper trading enabled (balance: %.2f USDT)", paperConfig.InitialBalance)
+	} else {
+		// Follow fills and positions in real time
+		account = trading.NewUserDataStream(tradingClient)
+		if err := account.Start(context.Background()); err != nil {
+			log.Printf("⚠️  Failed to start user data stream: %v", err)
+			account = nil
+		}
+	}
+
+	// Serve klines from the local candle store when configured
+	if dir := trading.KlineStoreDir(); dir != "" {
+		store, err := trading.NewKlineStore(dir, tradingClient)
+		if err != nil {
+			log.Fatalf("Failed to create kline store: %v", err)
+		}
+		exchange = trading.WithKlineSource(exchange, store)
+		log.Printf("💾 Kline store enabled (%s)", dir)
+	}
+
+	// Reject orders that would fail the symbol filters, margin or bracket checks before sending them
+	exchange = trading.WithOrderValidation(exchange)
+
+	// Record every order, fill and trade outcome when JOURNAL_DIR is set
+	breakoutJournal, err = trading.JournalFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to open journal: %v", err)
+	}
+	if breakoutJournal != nil {
+		exchange = trading.WithJournal(exchange, breakoutJournal)
+		if account != nil {
+			breakoutJournal.Attach(account)
+		}
+		log.Printf("📕 Journal enabled (%s)", os.Getenv("JOURNAL_DIR"))
+	}
+
+	// Load symbol filters for order rounding and keep them refreshed
+	if err := exchange.Symbols().Start(context.Background()); err != nil {
+		log.Printf("⚠️  Failed to load symbol filters: %v", err)
+	}
+
+	// Report orders a previous run left open; their entries are not resent
+	if tagged, err := trading.TaggedOpenOrders(context.Background(), exchange, breakoutStrategy); err != nil {
+		log.Printf("⚠️  Failed to look up open orders: %v", err)
+	} else if len(tagged) > 0 {
+		log.Printf("♻️  Found %d open orders from a previous run", len(tagged))
+		for tag, order := range tagged {
+			log.Printf("   %s %s (signal %s): %s order %d", tag.Symbol, tag.Leg, tag.SignalID, order.Type, order.OrderID)
+		}
+	}
+
+	// Cancel the sibling SL/TP once one closes the position and resize the
+	// stop after a partial take profit, polling when there is no stream
+	oco := trading.NewOCOSupervisor(exchange)
+	if account != nil {
+		oco.Attach(account)
+	} else {
+		go oco.Run(context.Background())
+	}
+
 	// Get symbols for trading - using predefined list for now
 	symbols := []string{
 		"BTCUSDT", "ETHUSDT", "ADAUSDT", "XRPUSDT", "DOTUSDT",
@@ -885,6 +1006,42 @@ func StartTrading() {
 		"1INCHUSDT", "COMPUSDT", "MKRUSDT", "RENUSDT", "KNCUSDT",
 	}
 
+	// Move stops to break-even and trail them as configured by BREAKEVEN_R and TRAIL_MODE
+	positions := trading.NewPositionManager(exchange, trading.PositionManagerConfigFromEnv())
+	go positions.Run(context.Background())
+
+	// Scale out over several take profits when SCALED_TP is set
+	breakoutExits = trading.ScaledExitConfigFromEnv()
+
+	// Risk RISK_PERCENT of equity per trade, within MAX_NOTIONAL and the available margin
+	breakoutSizing = trading.SizingConfigFromEnv()
+
+	// Limit concurrent positions as configured by MAX_OPEN_POSITIONS and the notional limits
+	breakoutExposure = trading.ExposureConfigFromEnv()
+
+	// Keep stops LIQUIDATION_BUFFER_PERCENT inside the liquidation price
+	breakoutLiquidation = trading.LiquidationConfigFromEnv()
+
+	// Follow volatility with the leverage, never above the conservative breakout leverage
+	breakoutLeverageConfig = capBreakoutLeverage(trading.LeverageConfigFromEnv())
+
+	// Rest entries at the broken level when LIMIT_ENTRY is set, attaching the
+	// bracket once they fill
+	var entries *trading.LimitEntryManager
+	if entryConfig := trading.LimitEntryConfigFromEnv(); entryConfig.Enabled {
+		entries = trading.NewLimitEntryManager(exchange, entryConfig)
+		if account != nil {
+			entries.Attach(account)
+		}
+		go entries.Run(context.Background())
+	}
+
+	// Halt new entries once the daily loss or drawdown limit is crossed
+	breaker, err := trading.NewCircuitBreaker(exchange, trading.CircuitBreakerConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Failed to create circuit breaker: %v", err)
+	}
+
 	// Start breakout trading
-	startBreakoutTrading(tradingClient, symbols)
+	startBreakoutTrading(exchange, positions, entries, breaker, symbols)
 }